@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/opsee/hugs/consumer"
+	"github.com/opsee/hugs/notifier"
+	"github.com/opsee/hugs/store"
+	log "github.com/opsee/logrus"
+)
+
+const usage = `usage: deadletters <command> [arguments]
+
+commands:
+  list [-limit n]  list the most recent dead letters for all customers
+  show <id>        print a dead letter and its CheckResult
+  replay [-force] <id>
+                   send a dead letter's CheckResult through the notification pipeline,
+                   -force sends it even if the check's state has changed since
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := store.NewPostgres()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch flag.Arg(0) {
+	case "list":
+		listFlags := flag.NewFlagSet("list", flag.ExitOnError)
+		limit := listFlags.Int("limit", store.DefaultDeadLettersLimit, "maximum number of dead letters to list")
+		listFlags.Parse(flag.Args()[1:])
+
+		deadLetters, err := db.UnsafeGetDeadLetters(*limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printJSON(deadLetters)
+
+	case "show":
+		deadLetter, err := db.UnsafeGetDeadLetter(idArg(flag.Args()[1:]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		printJSON(deadLetter)
+
+	case "replay":
+		replayFlags := flag.NewFlagSet("replay", flag.ExitOnError)
+		force := replayFlags.Bool("force", false, "send the result even if the check's state has changed since")
+		replayFlags.Parse(flag.Args()[1:])
+
+		deadLetter, err := db.UnsafeGetDeadLetter(idArg(replayFlags.Args()))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		deps, err := notifier.NewDependencies(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		n, errMap := notifier.NewNotifier(deps)
+		for k, v := range errMap {
+			if v != nil {
+				log.WithError(v).Fatal("Couldn't initialize notifier: ", k)
+			}
+		}
+
+		pipeline := consumer.NewPipeline(db, n)
+		// the dead letter is left as it is unless something was delivered
+		if err := pipeline.Replay(deadLetter.CheckResult, *force); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := db.MarkDeadLetterReplayed(deadLetter); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Replayed dead letter %d.\n", deadLetter.Id)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func idArg(args []string) int {
+	if len(args) < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		log.Fatal("id must be an integer")
+	}
+	return id
+}
+
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(out))
+}
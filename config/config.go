@@ -1,8 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -15,7 +20,53 @@ import (
 	log "github.com/opsee/logrus"
 )
 
-const DefaultLogLevel = "debug"
+const (
+	DefaultLogLevel              = "debug"
+	DefaultMaxAttempts           = 10
+	DefaultNotificaptionTimeout  = 15 * time.Second
+	DefaultFlapWindow            = 30 * time.Minute
+	DefaultFlapThreshold         = 5
+	DefaultSlackAPIURL           = "https://slack.com/api"
+	DefaultPagerDutyEventsURL    = "https://events.pagerduty.com"
+	DefaultOpsgenieAPIURL        = "https://api.opsgenie.com"
+	DefaultTwilioAPIURL          = "https://api.twilio.com"
+	DefaultSMSRateLimit          = 10
+	DefaultSMSRateWindow         = time.Hour
+	DefaultMandrillAPIURL        = "https://mandrillapp.com/api/1.0/"
+	DefaultMandrillFrom          = "Opsee <alerts@opsee.com>"
+	DefaultCatsAddress           = "cats.in.opsee.com:443"
+	DefaultCredentialCacheTTL    = 30 * time.Second
+	DefaultWebhookConnectTimeout = 5 * time.Second
+	DefaultWebhookTimeout        = 10 * time.Second
+	DefaultWebhookMaxRedirects   = 3
+	DefaultWebhookMaxResponse    = 64 * 1024
+
+	// Email transports, selected with HUGS_EMAIL_TRANSPORT.
+	EmailTransportMandrill = "mandrill"
+	EmailTransportSMTP     = "smtp"
+)
+
+// DefaultWebhookDeniedNetworks are the loopback, private, link-local (which
+// includes the EC2 metadata service), multicast and otherwise reserved
+// networks webhooks can't be delivered to.
+var DefaultWebhookDeniedNetworks = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
 
 // TODO(dan) consider splitting this into configs and testconfigs for each module
 type Config struct {
@@ -32,8 +83,23 @@ type Config struct {
 	// OpseeHost is the public API endpoint for Opsee. This is used in notification
 	// templates.
 	OpseeHost string `required:"true"`
-	// MandrillApiKey is the Mandrill API Key used for sending e-mail.
-	MandrillApiKey string `required:"true"`
+	// MandrillApiKey is the Mandrill API Key used for sending e-mail. It is
+	// only required when EmailTransport is mandrill.
+	MandrillApiKey string
+	// EmailTransport selects how e-mail is sent: through Mandrill's hosted
+	// templates (the default) or rendered locally and sent over SMTP.
+	EmailTransport string
+	// SMTPAddress is the host:port of the SMTP server e-mail is sent through.
+	SMTPAddress string
+	// SMTPUsername and SMTPPassword authenticate with the SMTP server. Auth
+	// is skipped without a username.
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPFrom is the sender address of e-mail sent over SMTP.
+	SMTPFrom string
+	// SMTPRequireTLS refuses to send e-mail to SMTP servers that don't
+	// offer STARTTLS. STARTTLS is always used when it is offered.
+	SMTPRequireTLS bool
 	// These two may not even be used.
 	VapeEndpoint string `required:"true"`
 	VapeKey      string `required:"true"`
@@ -53,12 +119,73 @@ type Config struct {
 	SlackTestClientId string
 	// AWSSession is the shared `aws.Session` object used by all of the hugs components.
 	AWSSession *session.Session
+	// SNSEndpoint overrides the SNS endpoint SNS notifications are published
+	// to, e.g. for a local SNS compatible service.
+	SNSEndpoint string
 	// NotificaptionEndpoint is the URL of the notificaption service.
 	NotificaptionEndpoint string
+	// NotificaptionTimeout bounds calls to notificaption. Notifications are
+	// sent without screenshots when it is exceeded.
+	NotificaptionTimeout time.Duration
 	// BartnetEndpoint is the URL of bartnet
 	BartnetEndpoint string
 	// YellerAPIKey is the API key used to report errors to the Yeller app
 	YellerAPIKey string
+	// MaxAttempts is the number of times workers try to process a CheckResult
+	// before parking it in the dead letter store.
+	MaxAttempts int
+	// FlapThreshold is the number of state changes within FlapWindow that
+	// makes a check flapping. Zero disables flap detection.
+	FlapThreshold int
+	// FlapWindow is how far back state changes are counted, and how long a
+	// flapping check must keep its state to be considered stable again.
+	FlapWindow time.Duration
+	// SlackAPIURL is the base URL of the Slack Web API.
+	SlackAPIURL string
+	// PagerDutyEventsURL is the base URL of the PagerDuty Events APIs.
+	PagerDutyEventsURL string
+	// OpsgenieAPIURL is the base URL of the Opsgenie REST API.
+	OpsgenieAPIURL string
+	// TwilioAccountSid and TwilioAuthToken authenticate with the Twilio
+	// compatible API SMS notifications are sent through. SMS notifications
+	// are disabled without them.
+	TwilioAccountSid string
+	TwilioAuthToken  string
+	// TwilioFromNumber is the E.164 number SMS notifications are sent from.
+	TwilioFromNumber string
+	// TwilioAPIURL is the base URL of the Twilio REST API.
+	TwilioAPIURL string
+	// SMSRateLimit is the number of SMS notifications a phone number gets
+	// within SMSRateWindow, further ones are dropped. Zero disables the limit.
+	// It is counted per process, so with N workers a number can get up to N
+	// times as many.
+	SMSRateLimit  int
+	SMSRateWindow time.Duration
+	// MandrillAPIURL is the base URL of the Mandrill API, including the
+	// version and trailing slash.
+	MandrillAPIURL string
+	// MandrillFrom is the sender address of the e-mail Mandrill has no
+	// hosted templates for, which is rendered by hugs.
+	MandrillFrom string
+	// CatsAddress is the host:port of the cats gRPC service.
+	CatsAddress string
+	// CredentialCacheTTL is how long senders reuse a customer's Slack,
+	// PagerDuty and Opsgenie credentials before reading them from the
+	// database again. Workers can't see the API invalidating its cache, so
+	// it bounds how long they keep using a disconnected integration.
+	CredentialCacheTTL time.Duration
+	// WebhookConnectTimeout bounds connecting to a webhook's host, and
+	// WebhookTimeout the whole request including reading the response.
+	WebhookConnectTimeout time.Duration
+	WebhookTimeout        time.Duration
+	// WebhookDeniedNetworks are the CIDRs webhooks can't connect to, checked
+	// against the addresses webhook hosts resolve to.
+	WebhookDeniedNetworks []string
+	// WebhookMaxRedirects is the number of redirects a webhook follows.
+	WebhookMaxRedirects int
+	// WebhookMaxResponse is the number of bytes of a webhook's response hugs
+	// reads.
+	WebhookMaxResponse int
 
 	// global database connection
 	DBConnection *sqlx.DB
@@ -69,6 +196,26 @@ func (this *Config) Validate() error {
 	if err := validator.Validate(this); err != nil {
 		return err
 	}
+
+	switch this.EmailTransport {
+	case EmailTransportMandrill:
+		if this.MandrillApiKey == "" {
+			return fmt.Errorf("MandrillApiKey is required for the %s email transport", this.EmailTransport)
+		}
+	case EmailTransportSMTP:
+		if this.SMTPAddress == "" || this.SMTPFrom == "" {
+			return fmt.Errorf("SMTPAddress and SMTPFrom are required for the %s email transport", this.EmailTransport)
+		}
+	default:
+		return fmt.Errorf("Unknown email transport %s", this.EmailTransport)
+	}
+
+	for _, cidr := range this.WebhookDeniedNetworks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("Invalid webhook denied network %s", cidr)
+		}
+	}
+
 	return nil
 }
 
@@ -119,6 +266,80 @@ func (this *Config) setLogLevel() {
 	log.WithError(err).Error("Invalid log level %s.  Using logrus default.", this.LogLevel)
 }
 
+// getenvString returns the value of an environment variable, or def if it is
+// unset.
+func getenvString(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getenvInt returns the integer value of an environment variable, or def if it
+// is unset or invalid.
+func getenvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.WithError(err).Warnf("Invalid value for %s.  Using default %d.", key, def)
+		return def
+	}
+	return i
+}
+
+// getenvBool returns the boolean value (e.g. "true", "0") of an environment
+// variable, or def if it is unset or invalid.
+func getenvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.WithError(err).Warnf("Invalid value for %s.  Using default %t.", key, def)
+		return def
+	}
+	return b
+}
+
+// getenvList returns the comma separated values of an environment variable,
+// or def if it is unset.
+func getenvList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getenvDuration returns the duration value (e.g. "5s") of an environment
+// variable, or def if it is unset or invalid.
+func getenvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.WithError(err).Warnf("Invalid value for %s.  Using default %s.", key, def)
+		return def
+	}
+	return d
+}
+
 func GetConfig() *Config {
 	once.Do(func() {
 		c := &Config{
@@ -137,8 +358,37 @@ func GetConfig() *Config {
 			SlackTestClientId:     os.Getenv("HUGS_TEST_SLACK_CLIENT_ID"),
 			SlackTestClientSecret: os.Getenv("HUGS_TEST_SLACK_CLIENT_SECRET"),
 			NotificaptionEndpoint: os.Getenv("HUGS_NOTIFICAPTION_ENDPOINT"),
+			NotificaptionTimeout:  getenvDuration("HUGS_NOTIFICAPTION_TIMEOUT", DefaultNotificaptionTimeout),
 			BartnetEndpoint:       os.Getenv("HUGS_BARTNET_ENDPOINT"),
 			YellerAPIKey:          os.Getenv("HUGS_YELLER_API_KEY"),
+			MaxAttempts:           getenvInt("HUGS_MAX_ATTEMPTS", DefaultMaxAttempts),
+			FlapThreshold:         getenvInt("HUGS_FLAP_THRESHOLD", DefaultFlapThreshold),
+			FlapWindow:            getenvDuration("HUGS_FLAP_WINDOW", DefaultFlapWindow),
+			SlackAPIURL:           strings.TrimSuffix(getenvString("HUGS_SLACK_API_URL", DefaultSlackAPIURL), "/"),
+			PagerDutyEventsURL:    strings.TrimSuffix(getenvString("HUGS_PAGERDUTY_EVENTS_URL", DefaultPagerDutyEventsURL), "/"),
+			OpsgenieAPIURL:        strings.TrimSuffix(getenvString("HUGS_OPSGENIE_API_URL", DefaultOpsgenieAPIURL), "/"),
+			TwilioAccountSid:      os.Getenv("HUGS_TWILIO_ACCOUNT_SID"),
+			TwilioAuthToken:       os.Getenv("HUGS_TWILIO_AUTH_TOKEN"),
+			TwilioFromNumber:      os.Getenv("HUGS_TWILIO_FROM_NUMBER"),
+			TwilioAPIURL:          strings.TrimSuffix(getenvString("HUGS_TWILIO_API_URL", DefaultTwilioAPIURL), "/"),
+			SMSRateLimit:          getenvInt("HUGS_SMS_RATE_LIMIT", DefaultSMSRateLimit),
+			SMSRateWindow:         getenvDuration("HUGS_SMS_RATE_WINDOW", DefaultSMSRateWindow),
+			SNSEndpoint:           os.Getenv("HUGS_SNS_ENDPOINT"),
+			EmailTransport:        getenvString("HUGS_EMAIL_TRANSPORT", EmailTransportMandrill),
+			SMTPAddress:           os.Getenv("HUGS_SMTP_ADDRESS"),
+			SMTPUsername:          os.Getenv("HUGS_SMTP_USERNAME"),
+			SMTPPassword:          os.Getenv("HUGS_SMTP_PASSWORD"),
+			SMTPFrom:              os.Getenv("HUGS_SMTP_FROM"),
+			SMTPRequireTLS:        getenvBool("HUGS_SMTP_REQUIRE_TLS", true),
+			MandrillAPIURL:        strings.TrimSuffix(getenvString("HUGS_MANDRILL_API_URL", DefaultMandrillAPIURL), "/") + "/",
+			MandrillFrom:          getenvString("HUGS_MANDRILL_FROM", DefaultMandrillFrom),
+			CatsAddress:           getenvString("HUGS_CATS_ADDRESS", DefaultCatsAddress),
+			CredentialCacheTTL:    getenvDuration("HUGS_CREDENTIAL_CACHE_TTL", DefaultCredentialCacheTTL),
+			WebhookConnectTimeout: getenvDuration("HUGS_WEBHOOK_CONNECT_TIMEOUT", DefaultWebhookConnectTimeout),
+			WebhookTimeout:        getenvDuration("HUGS_WEBHOOK_TIMEOUT", DefaultWebhookTimeout),
+			WebhookDeniedNetworks: getenvList("HUGS_WEBHOOK_DENIED_NETWORKS", DefaultWebhookDeniedNetworks),
+			WebhookMaxRedirects:   getenvInt("HUGS_WEBHOOK_MAX_REDIRECTS", DefaultWebhookMaxRedirects),
+			WebhookMaxResponse:    getenvInt("HUGS_WEBHOOK_MAX_RESPONSE", DefaultWebhookMaxResponse),
 		}
 		if err := c.Validate(); err == nil {
 			c.setLogLevel()
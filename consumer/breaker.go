@@ -0,0 +1,83 @@
+package consumer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// Breaker is a circuit breaker for calls to a remote service. After Threshold
+// consecutive failures it opens and rejects calls for Cooldown, then lets a
+// single trial call through. The trial's outcome closes or re-opens it.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a call may be made. Every allowed call must be
+// followed by Success or Failure.
+func (b *Breaker) Allow() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.Cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// a trial call is already in flight
+		return false
+	}
+	return true
+}
+
+func (b *Breaker) Success() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+func (b *Breaker) Failure() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.Threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Open reports whether the breaker is currently rejecting calls.
+func (b *Breaker) Open() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.state != breakerClosed
+}
@@ -0,0 +1,46 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	now := time.Now()
+	breaker := NewBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	assert.True(t, breaker.Allow())
+	breaker.Failure()
+	assert.True(t, breaker.Allow())
+	breaker.Failure()
+
+	assert.True(t, breaker.Open())
+	assert.False(t, breaker.Allow())
+}
+
+func TestBreakerHalfOpenTrial(t *testing.T) {
+	now := time.Now()
+	breaker := NewBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	assert.False(t, breaker.Allow())
+
+	// after the cooldown only a single trial call is let through
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	assert.False(t, breaker.Allow())
+
+	// a failed trial re-opens the breaker for another cooldown
+	breaker.Failure()
+	assert.False(t, breaker.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	breaker.Success()
+	assert.False(t, breaker.Open())
+	assert.True(t, breaker.Allow())
+}
@@ -0,0 +1,310 @@
+package consumer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// Sender is implemented by anything that can deliver a single notification,
+// e.g. notifier.Notifier.
+type Sender interface {
+	Send(n *obj.Notification, e *obj.Event) error
+}
+
+// DeliveryLog records every delivery attempt made by a Dispatcher, e.g.
+// store.Store.
+type DeliveryLog interface {
+	PutDelivery(*obj.Delivery) error
+}
+
+// Backoff is an exponential retry schedule for a single notification.
+type Backoff struct {
+	// Initial is the wait before the first retry. It doubles for every
+	// subsequent retry.
+	Initial time.Duration
+	// Max caps the wait between two retries.
+	Max time.Duration
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+}
+
+var DefaultBackoff = &Backoff{
+	Initial:     5 * time.Second,
+	Max:         5 * time.Minute,
+	MaxAttempts: 8,
+}
+
+// Duration returns the wait before the given retry, starting at 1.
+func (b *Backoff) Duration(retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+	d := b.Initial << uint(retry-1)
+	if d > b.Max || d <= 0 {
+		return b.Max
+	}
+	return d
+}
+
+// Dispatcher delivers every notification for an event independently. A failed
+// delivery is retried on its own schedule and never causes a notification that
+// was already delivered to be sent again.
+type Dispatcher struct {
+	Sender  Sender
+	Backoff *Backoff
+	// Log is optional. When set, every attempt is recorded in it.
+	Log DeliveryLog
+	// Undeliverable is optional. It is called once every notification for an
+	// event has failed its last attempt, with the error for each of them.
+	Undeliverable func(event *obj.Event, attempts int, reasons []string)
+	retries       sync.WaitGroup
+	mu            sync.Mutex
+	scheduled     map[*retry]bool
+	stopped       bool
+}
+
+// dispatch tracks the outstanding deliveries for a single event.
+type dispatch struct {
+	sync.Mutex
+	event       *obj.Event
+	pending     int
+	attempts    int
+	delivered   bool
+	interrupted bool
+	reasons     []string
+}
+
+// retry is a delivery attempt waiting for its turn.
+type retry struct {
+	timer        *time.Timer
+	tracker      *dispatch
+	notification *obj.Notification
+	attempt      int
+	err          error
+}
+
+func NewDispatcher(sender Sender, backoff *Backoff, deliveryLog DeliveryLog) *Dispatcher {
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	return &Dispatcher{
+		Sender:    sender,
+		Backoff:   backoff,
+		Log:       deliveryLog,
+		scheduled: map[*retry]bool{},
+	}
+}
+
+// Dispatch makes the first delivery attempt for each notification and
+// schedules retries for the ones that failed. It returns the number of
+// notifications that were delivered on the first attempt.
+func (d *Dispatcher) Dispatch(notifications []*obj.Notification, event *obj.Event) int {
+	tracker := &dispatch{
+		event:   event,
+		pending: len(notifications),
+	}
+
+	delivered := 0
+	for _, notification := range notifications {
+		if d.deliver(tracker, notification, 1) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Attempt makes a single delivery attempt for each notification, without
+// retrying failures or calling Undeliverable. It returns an error for each
+// notification that wasn't delivered.
+func (d *Dispatcher) Attempt(notifications []*obj.Notification, event *obj.Event) []error {
+	errs := []error{}
+	for _, notification := range notifications {
+		logger := log.WithFields(log.Fields{
+			"customer_id":     event.Result.CustomerId,
+			"check_id":        event.Result.CheckId,
+			"notification_id": notification.Id,
+			"type":            notification.Type,
+		})
+
+		start := time.Now()
+		err := d.Sender.Send(notification, event)
+		latency := time.Since(start)
+
+		status := obj.DeliveryStatusSent
+		if suppressed, ok := err.(*obj.SuppressedError); ok {
+			status = suppressed.Status
+		} else if err != nil {
+			status = obj.DeliveryStatusFailed
+		}
+		d.record(notification, event, status, err, 1, latency)
+
+		if err != nil {
+			logger.WithError(err).Error("Error emitting notification.")
+			errs = append(errs, fmt.Errorf("%s notification %d: %s", notification.Type, notification.Id, err))
+			continue
+		}
+		logger.Infof("Sent %s notification to customer.", notification.Type)
+	}
+	return errs
+}
+
+// Suppress records a delivery with the given status for each notification
+// without sending anything.
+func (d *Dispatcher) Suppress(notifications []*obj.Notification, event *obj.Event, status string, reason error) {
+	for _, notification := range notifications {
+		d.record(notification, event, status, reason, 0, 0)
+	}
+}
+
+// Wait blocks until all scheduled retries have finished.
+func (d *Dispatcher) Wait() {
+	d.retries.Wait()
+}
+
+// Stop gives up on every scheduled retry, waiting for the ones already under
+// way. Retries only live in this process, so events that still had some are
+// handed to Undeliverable, even if other notifications for them went out, to
+// be parked rather than lost. Deliveries that fail afterwards aren't retried.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	scheduled := d.scheduled
+	d.scheduled = map[*retry]bool{}
+	d.mu.Unlock()
+
+	for r := range scheduled {
+		r.timer.Stop()
+		d.abandon(r)
+		d.retries.Done()
+	}
+
+	d.retries.Wait()
+}
+
+func (d *Dispatcher) deliver(tracker *dispatch, notification *obj.Notification, attempt int) bool {
+	event := tracker.event
+	logger := log.WithFields(log.Fields{
+		"customer_id":     event.Result.CustomerId,
+		"check_id":        event.Result.CheckId,
+		"notification_id": notification.Id,
+		"type":            notification.Type,
+		"attempt":         attempt,
+	})
+
+	tracker.Lock()
+	if attempt > tracker.attempts {
+		tracker.attempts = attempt
+	}
+	tracker.Unlock()
+
+	start := time.Now()
+	err := d.Sender.Send(notification, event)
+	latency := time.Since(start)
+
+	if err == nil {
+		logger.Infof("Sent %s notification to customer.", notification.Type)
+		d.record(notification, event, obj.DeliveryStatusSent, nil, attempt, latency)
+		d.finish(tracker, nil)
+		return true
+	}
+
+	// the sender chose not to send it, retrying would only send it later
+	if suppressed, ok := err.(*obj.SuppressedError); ok {
+		logger.WithField("status", suppressed.Status).Warnf("Suppressed %s notification: %s", notification.Type, suppressed.Reason)
+		d.record(notification, event, suppressed.Status, err, attempt, latency)
+		d.finish(tracker, nil)
+		return false
+	}
+
+	if attempt >= d.Backoff.MaxAttempts {
+		logger.WithError(err).Error("Error emitting notification, giving up.")
+		d.record(notification, event, obj.DeliveryStatusFailed, err, attempt, latency)
+		d.finish(tracker, fmt.Errorf("%s notification %d: %s", notification.Type, notification.Id, err))
+		return false
+	}
+
+	wait := d.Backoff.Duration(attempt)
+	logger.WithError(err).WithField("retry_in", wait.String()).Warn("Error emitting notification, scheduling retry.")
+	d.record(notification, event, obj.DeliveryStatusRetrying, err, attempt, latency)
+
+	r := &retry{tracker: tracker, notification: notification, attempt: attempt, err: err}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		d.abandon(r)
+		return false
+	}
+
+	d.retries.Add(1)
+	d.scheduled[r] = true
+	r.timer = time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		ok := d.scheduled[r]
+		delete(d.scheduled, r)
+		d.mu.Unlock()
+
+		// Stop got to it first
+		if !ok {
+			return
+		}
+
+		defer d.retries.Done()
+		d.deliver(tracker, notification, attempt+1)
+	})
+
+	return false
+}
+
+// abandon gives up on a retry because the dispatcher is stopping.
+func (d *Dispatcher) abandon(r *retry) {
+	log.WithFields(log.Fields{
+		"customer_id":     r.tracker.event.Result.CustomerId,
+		"check_id":        r.tracker.event.Result.CheckId,
+		"notification_id": r.notification.Id,
+		"type":            r.notification.Type,
+		"attempt":         r.attempt,
+	}).Warn("Stopping before retrying notification.")
+
+	r.tracker.Lock()
+	r.tracker.interrupted = true
+	r.tracker.Unlock()
+
+	d.finish(r.tracker, fmt.Errorf("%s notification %d: stopped before retrying: %s", r.notification.Type, r.notification.Id, r.err))
+}
+
+// finish marks one of the event's deliveries as done, with the error it
+// finally failed with if any.
+func (d *Dispatcher) finish(tracker *dispatch, err error) {
+	tracker.Lock()
+	tracker.pending--
+	if err == nil {
+		tracker.delivered = true
+	} else {
+		tracker.reasons = append(tracker.reasons, err.Error())
+	}
+	undeliverable := tracker.pending == 0 && (!tracker.delivered || tracker.interrupted)
+	tracker.Unlock()
+
+	if undeliverable && d.Undeliverable != nil {
+		d.Undeliverable(tracker.event, tracker.attempts, tracker.reasons)
+	}
+}
+
+func (d *Dispatcher) record(notification *obj.Notification, event *obj.Event, status string, err error, attempt int, latency time.Duration) {
+	if d.Log == nil {
+		return
+	}
+
+	delivery := obj.NewDelivery(notification, event, status, err)
+	delivery.Attempt = attempt
+	delivery.LatencyMs = int64(latency / time.Millisecond)
+
+	if err := d.Log.PutDelivery(delivery); err != nil {
+		log.WithError(err).WithFields(log.Fields{"check_id": delivery.CheckId, "notification_id": delivery.NotificationId}).Error("Couldn't record delivery.")
+	}
+}
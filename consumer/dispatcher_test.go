@@ -0,0 +1,210 @@
+package consumer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+// fails each notification a set number of times before succeeding
+type flakySender struct {
+	sync.Mutex
+	failures map[int]int
+	attempts map[int]int
+}
+
+func newFlakySender(failures map[int]int) *flakySender {
+	return &flakySender{
+		failures: failures,
+		attempts: map[int]int{},
+	}
+}
+
+func (s *flakySender) Send(n *obj.Notification, e *obj.Event) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.attempts[n.Id]++
+	if s.attempts[n.Id] <= s.failures[n.Id] {
+		return errors.New("remote unavailable")
+	}
+	return nil
+}
+
+func (s *flakySender) Attempts(id int) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.attempts[id]
+}
+
+type memoryDeliveryLog struct {
+	sync.Mutex
+	deliveries []*obj.Delivery
+}
+
+func (l *memoryDeliveryLog) PutDelivery(delivery *obj.Delivery) error {
+	l.Lock()
+	defer l.Unlock()
+	l.deliveries = append(l.deliveries, delivery)
+	return nil
+}
+
+func (l *memoryDeliveryLog) Statuses(notificationId int) []string {
+	l.Lock()
+	defer l.Unlock()
+
+	statuses := []string{}
+	for _, delivery := range l.deliveries {
+		if delivery.NotificationId == notificationId {
+			statuses = append(statuses, delivery.Status)
+		}
+	}
+	return statuses
+}
+
+var testBackoff = &Backoff{
+	Initial:     time.Millisecond,
+	Max:         4 * time.Millisecond,
+	MaxAttempts: 4,
+}
+
+func TestBackoffDuration(t *testing.T) {
+	assert.Equal(t, time.Millisecond, testBackoff.Duration(1))
+	assert.Equal(t, 2*time.Millisecond, testBackoff.Duration(2))
+	assert.Equal(t, 4*time.Millisecond, testBackoff.Duration(3))
+	assert.Equal(t, 4*time.Millisecond, testBackoff.Duration(10))
+	assert.Equal(t, 4*time.Millisecond, testBackoff.Duration(100))
+}
+
+func TestDispatchRetriesFailedNotificationsOnly(t *testing.T) {
+	sender := newFlakySender(map[int]int{2: 2})
+	deliveryLog := &memoryDeliveryLog{}
+	dispatcher := NewDispatcher(sender, testBackoff, deliveryLog)
+
+	notifications := []*obj.Notification{
+		&obj.Notification{Id: 1, Type: "slack_bot"},
+		&obj.Notification{Id: 2, Type: "webhook"},
+		&obj.Notification{Id: 3, Type: "email"},
+	}
+
+	delivered := dispatcher.Dispatch(notifications, obj.GenerateFailingTestEvent())
+	assert.Equal(t, 2, delivered)
+
+	dispatcher.Wait()
+	assert.Equal(t, 1, sender.Attempts(1))
+	assert.Equal(t, 3, sender.Attempts(2))
+	assert.Equal(t, 1, sender.Attempts(3))
+
+	statuses := deliveryLog.Statuses(2)
+	assert.Equal(t, []string{obj.DeliveryStatusRetrying, obj.DeliveryStatusRetrying, obj.DeliveryStatusSent}, statuses)
+}
+
+func TestDispatchGivesUpAfterMaxAttempts(t *testing.T) {
+	sender := newFlakySender(map[int]int{1: 100})
+	deliveryLog := &memoryDeliveryLog{}
+	dispatcher := NewDispatcher(sender, testBackoff, deliveryLog)
+
+	delivered := dispatcher.Dispatch([]*obj.Notification{&obj.Notification{Id: 1, Type: "webhook"}}, obj.GenerateFailingTestEvent())
+	assert.Equal(t, 0, delivered)
+
+	dispatcher.Wait()
+	assert.Equal(t, testBackoff.MaxAttempts, sender.Attempts(1))
+
+	statuses := deliveryLog.Statuses(1)
+	assert.Equal(t, obj.DeliveryStatusFailed, statuses[len(statuses)-1])
+}
+
+func TestDispatchReportsUndeliverableEvents(t *testing.T) {
+	var (
+		undeliverable []string
+		calls         int
+	)
+
+	// only the event whose notifications all fail is undeliverable
+	sender := newFlakySender(map[int]int{1: 100, 2: 100, 3: 100})
+	dispatcher := NewDispatcher(sender, testBackoff, nil)
+	dispatcher.Undeliverable = func(event *obj.Event, attempts int, reasons []string) {
+		calls++
+		undeliverable = reasons
+		assert.Equal(t, testBackoff.MaxAttempts, attempts)
+	}
+
+	dispatcher.Dispatch([]*obj.Notification{
+		&obj.Notification{Id: 1, Type: "webhook"},
+		&obj.Notification{Id: 2, Type: "email"},
+	}, obj.GenerateFailingTestEvent())
+
+	dispatcher.Dispatch([]*obj.Notification{
+		&obj.Notification{Id: 3, Type: "webhook"},
+		&obj.Notification{Id: 4, Type: "slack_bot"},
+	}, obj.GenerateFailingTestEvent())
+
+	dispatcher.Wait()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 2, len(undeliverable))
+}
+
+type suppressingSender struct {
+	attempts int
+}
+
+func (s *suppressingSender) Send(n *obj.Notification, e *obj.Event) error {
+	s.attempts++
+	return &obj.SuppressedError{Status: obj.DeliveryStatusRateLimited, Reason: "rate limited"}
+}
+
+func TestDispatchRecordsSuppressedDeliveries(t *testing.T) {
+	sender := &suppressingSender{}
+	deliveryLog := &memoryDeliveryLog{}
+	dispatcher := NewDispatcher(sender, testBackoff, deliveryLog)
+	dispatcher.Undeliverable = func(event *obj.Event, attempts int, reasons []string) {
+		t.Error("suppressed notifications aren't undeliverable")
+	}
+
+	delivered := dispatcher.Dispatch([]*obj.Notification{&obj.Notification{Id: 1, Type: "sms"}}, obj.GenerateFailingTestEvent())
+	assert.Equal(t, 0, delivered)
+
+	dispatcher.Wait()
+	assert.Equal(t, 1, sender.attempts)
+	assert.Equal(t, []string{obj.DeliveryStatusRateLimited}, deliveryLog.Statuses(1))
+}
+
+func TestDispatchStopParksPendingRetries(t *testing.T) {
+	var (
+		undeliverable []string
+		calls         int
+	)
+
+	// notification 2 is delivered, 1 is waiting an hour for its retry
+	sender := newFlakySender(map[int]int{1: 100})
+	deliveryLog := &memoryDeliveryLog{}
+	dispatcher := NewDispatcher(sender, &Backoff{Initial: time.Hour, Max: time.Hour, MaxAttempts: 3}, deliveryLog)
+	dispatcher.Undeliverable = func(event *obj.Event, attempts int, reasons []string) {
+		calls++
+		undeliverable = reasons
+		assert.Equal(t, 1, attempts)
+	}
+
+	delivered := dispatcher.Dispatch([]*obj.Notification{
+		&obj.Notification{Id: 1, Type: "webhook"},
+		&obj.Notification{Id: 2, Type: "email"},
+	}, obj.GenerateFailingTestEvent())
+	assert.Equal(t, 1, delivered)
+
+	dispatcher.Stop()
+	assert.Equal(t, 1, calls)
+	if assert.Len(t, undeliverable, 1) {
+		assert.Contains(t, undeliverable[0], "stopped before retrying")
+	}
+	assert.Equal(t, 1, sender.Attempts(1))
+
+	// failures after stopping aren't retried either
+	dispatcher.Dispatch([]*obj.Notification{&obj.Notification{Id: 1, Type: "webhook"}}, obj.GenerateFailingTestEvent())
+	dispatcher.Wait()
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, sender.Attempts(1))
+}
@@ -0,0 +1,149 @@
+package consumer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// Escalator walks failing checks through their escalation policies. Pending
+// steps only live in the worker's memory, so they are lost if it restarts.
+type Escalator struct {
+	Dispatcher *Dispatcher
+	// Active is optional. It is consulted before each step fires so that a
+	// check which recovered in the meantime isn't escalated any further, even
+	// if its passing result was handled by another worker.
+	Active func(result *schema.CheckResult) bool
+	sync.Mutex
+	pending map[string]*escalation
+}
+
+// escalation holds the scheduled steps for a single failing check target.
+type escalation struct {
+	timers []*time.Timer
+}
+
+func NewEscalator(dispatcher *Dispatcher) *Escalator {
+	return &Escalator{
+		Dispatcher: dispatcher,
+		pending:    map[string]*escalation{},
+	}
+}
+
+// Escalate schedules the policy's steps for a failing result. A check target
+// that is already being escalated keeps its original schedule.
+func (e *Escalator) Escalate(policy *obj.EscalationPolicy, event *obj.Event) {
+	key := escalationKey(event.Result)
+
+	e.Lock()
+	defer e.Unlock()
+
+	if _, ok := e.pending[key]; ok {
+		return
+	}
+
+	esc := &escalation{}
+	for i, step := range policy.Steps {
+		notifications := stepNotifications(step, event.Result)
+		last := i == len(policy.Steps)-1
+		esc.timers = append(esc.timers, time.AfterFunc(step.DelayDuration(), func() {
+			e.fire(key, esc, notifications, event, last)
+		}))
+	}
+	e.pending[key] = esc
+
+	log.WithFields(log.Fields{
+		"customer_id": event.Result.CustomerId,
+		"check_id":    event.Result.CheckId,
+		"steps":       len(policy.Steps),
+	}).Info("Escalating failing check.")
+}
+
+// Cancel stops the pending escalation for a result's check target. It returns
+// true if there was one.
+func (e *Escalator) Cancel(result *schema.CheckResult) bool {
+	key := escalationKey(result)
+
+	e.Lock()
+	defer e.Unlock()
+
+	esc, ok := e.pending[key]
+	if !ok {
+		return false
+	}
+
+	esc.stop()
+	delete(e.pending, key)
+
+	log.WithFields(log.Fields{
+		"customer_id": result.CustomerId,
+		"check_id":    result.CheckId,
+	}).Info("Cancelled escalation.")
+	return true
+}
+
+// Stop cancels every pending escalation.
+func (e *Escalator) Stop() {
+	e.Lock()
+	defer e.Unlock()
+
+	for key, esc := range e.pending {
+		esc.stop()
+		delete(e.pending, key)
+	}
+}
+
+// Pending returns the number of check targets being escalated.
+func (e *Escalator) Pending() int {
+	e.Lock()
+	defer e.Unlock()
+	return len(e.pending)
+}
+
+func (e *Escalator) fire(key string, esc *escalation, notifications []*obj.Notification, event *obj.Event, last bool) {
+	e.Lock()
+	if e.pending[key] != esc {
+		// cancelled while the timer was firing
+		e.Unlock()
+		return
+	}
+	if last {
+		delete(e.pending, key)
+	}
+	e.Unlock()
+
+	if e.Active != nil && !e.Active(event.Result) {
+		e.Cancel(event.Result)
+		return
+	}
+
+	e.Dispatcher.Dispatch(notifications, event)
+}
+
+func (esc *escalation) stop() {
+	for _, timer := range esc.timers {
+		timer.Stop()
+	}
+}
+
+func escalationKey(result *schema.CheckResult) string {
+	state := obj.NewCheckState(result)
+	return fmt.Sprintf("%s/%s/%s", state.CustomerId, state.CheckId, state.TargetId)
+}
+
+// stepNotifications returns copies of a step's notifications that belong to
+// the result's customer and check, which is what the senders expect.
+func stepNotifications(step *obj.EscalationStep, result *schema.CheckResult) []*obj.Notification {
+	notifications := make([]*obj.Notification, 0, len(step.Notifications))
+	for _, n := range step.Notifications {
+		notification := *n
+		notification.CustomerId = result.CustomerId
+		notification.CheckId = result.CheckId
+		notifications = append(notifications, &notification)
+	}
+	return notifications
+}
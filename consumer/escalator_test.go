@@ -0,0 +1,67 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEscalationPolicy() *obj.EscalationPolicy {
+	return &obj.EscalationPolicy{
+		CheckId: "check",
+		Steps: obj.EscalationSteps{
+			{Delay: 0, Notifications: []*obj.Notification{{Id: 1, Type: "slack_bot", Value: "#ops"}}},
+			{Delay: 1, Notifications: []*obj.Notification{{Id: 2, Type: "email", Value: "ops@example.com"}}},
+		},
+	}
+}
+
+func testFailingEvent() *obj.Event {
+	return &obj.Event{Result: &schema.CheckResult{CustomerId: "customer", CheckId: "check", Passing: false}}
+}
+
+func TestEscalatorRunsSteps(t *testing.T) {
+	sender := newFlakySender(map[int]int{})
+	escalator := NewEscalator(NewDispatcher(sender, testBackoff, nil))
+
+	escalator.Escalate(testEscalationPolicy(), testFailingEvent())
+	assert.Equal(t, 1, escalator.Pending())
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, 1, sender.Attempts(1))
+	assert.Equal(t, 1, sender.Attempts(2))
+	assert.Equal(t, 0, escalator.Pending())
+}
+
+func TestEscalatorCancel(t *testing.T) {
+	sender := newFlakySender(map[int]int{})
+	escalator := NewEscalator(NewDispatcher(sender, testBackoff, nil))
+
+	event := testFailingEvent()
+	escalator.Escalate(testEscalationPolicy(), event)
+	time.Sleep(100 * time.Millisecond)
+
+	assert.True(t, escalator.Cancel(&schema.CheckResult{CustomerId: "customer", CheckId: "check", Passing: true}))
+	assert.False(t, escalator.Cancel(event.Result))
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, 1, sender.Attempts(1))
+	assert.Equal(t, 0, sender.Attempts(2))
+}
+
+func TestEscalatorSkipsInactiveChecks(t *testing.T) {
+	sender := newFlakySender(map[int]int{})
+	escalator := NewEscalator(NewDispatcher(sender, testBackoff, nil))
+	escalator.Active = func(result *schema.CheckResult) bool {
+		return false
+	}
+
+	escalator.Escalate(testEscalationPolicy(), testFailingEvent())
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, 0, sender.Attempts(1))
+	assert.Equal(t, 0, escalator.Pending())
+}
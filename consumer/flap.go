@@ -0,0 +1,88 @@
+package consumer
+
+import (
+	"time"
+
+	"github.com/opsee/hugs/obj"
+)
+
+// FlapDetector decides when a check changes state too often for us to notify
+// about every change. It keeps its bookkeeping on the CheckState so that it
+// works across workers.
+type FlapDetector struct {
+	// Threshold is the number of state changes within Window that makes a
+	// check flapping. Zero disables flap detection.
+	Threshold int
+	// Window is how far back state changes are counted, and how long a
+	// flapping check must keep its state before it is stable again.
+	Window time.Duration
+}
+
+func NewFlapDetector(threshold int, window time.Duration) *FlapDetector {
+	return &FlapDetector{
+		Threshold: threshold,
+		Window:    window,
+	}
+}
+
+// Transition records a state change on the state, and returns a Flapping if
+// it made the check start flapping.
+func (f *FlapDetector) Transition(state *obj.CheckState, now time.Time) *obj.Flapping {
+	if f == nil || f.Threshold < 1 {
+		return nil
+	}
+
+	transitions := obj.Timestamps{}
+	for _, t := range state.Transitions {
+		if now.Sub(t) < f.Window {
+			transitions = append(transitions, t)
+		}
+	}
+	state.Transitions = append(transitions, now)
+
+	if state.Flapping {
+		state.FlapCount++
+		return nil
+	}
+
+	if len(state.Transitions) < f.Threshold {
+		return nil
+	}
+
+	since := state.Transitions[0]
+	state.Flapping = true
+	state.FlapCount = len(state.Transitions)
+	state.FlappingSince = &since
+
+	return &obj.Flapping{
+		Transitions: state.FlapCount,
+		Since:       since,
+	}
+}
+
+// Settle ends flapping once the check has kept its state for a whole window,
+// and returns the Flapping summary to send if it did.
+func (f *FlapDetector) Settle(state *obj.CheckState, now time.Time) *obj.Flapping {
+	if !state.Flapping || len(state.Transitions) == 0 {
+		return nil
+	}
+
+	if f != nil && now.Sub(state.Transitions[len(state.Transitions)-1]) < f.Window {
+		return nil
+	}
+
+	flapping := &obj.Flapping{
+		Stable:      true,
+		Transitions: state.FlapCount,
+	}
+	if state.FlappingSince != nil {
+		flapping.Since = *state.FlappingSince
+	}
+
+	state.Flapping = false
+	state.FlapCount = 0
+	state.FlappingSince = nil
+	state.Transitions = obj.Timestamps{}
+
+	return flapping
+}
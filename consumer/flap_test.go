@@ -0,0 +1,65 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlapDetector(t *testing.T) {
+	detector := NewFlapDetector(3, 10*time.Minute)
+	state := &obj.CheckState{}
+	start := time.Now()
+
+	assert.Nil(t, detector.Transition(state, start))
+	assert.Nil(t, detector.Transition(state, start.Add(time.Minute)))
+
+	flapping := detector.Transition(state, start.Add(2*time.Minute))
+	if assert.NotNil(t, flapping) {
+		assert.False(t, flapping.Stable)
+		assert.Equal(t, 3, flapping.Transitions)
+		assert.Equal(t, start, flapping.Since)
+	}
+	assert.True(t, state.Flapping)
+
+	// further changes are counted, but don't start flapping again
+	assert.Nil(t, detector.Transition(state, start.Add(3*time.Minute)))
+	assert.Equal(t, 4, state.FlapCount)
+
+	// not stable until the state has held for a whole window
+	assert.Nil(t, detector.Settle(state, start.Add(12*time.Minute)))
+
+	summary := detector.Settle(state, start.Add(13*time.Minute))
+	if assert.NotNil(t, summary) {
+		assert.True(t, summary.Stable)
+		assert.Equal(t, 4, summary.Transitions)
+	}
+	assert.False(t, state.Flapping)
+	assert.Nil(t, detector.Settle(state, start.Add(14*time.Minute)))
+}
+
+func TestFlapDetectorWindow(t *testing.T) {
+	detector := NewFlapDetector(3, 10*time.Minute)
+	state := &obj.CheckState{}
+	start := time.Now()
+
+	// changes older than the window don't count
+	assert.Nil(t, detector.Transition(state, start))
+	assert.Nil(t, detector.Transition(state, start.Add(6*time.Minute)))
+	assert.Nil(t, detector.Transition(state, start.Add(12*time.Minute)))
+	assert.False(t, state.Flapping)
+	assert.Equal(t, 2, len(state.Transitions))
+}
+
+func TestFlapDetectorDisabled(t *testing.T) {
+	detector := NewFlapDetector(0, 10*time.Minute)
+	state := &obj.CheckState{}
+	start := time.Now()
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, detector.Transition(state, start.Add(time.Duration(i)*time.Second)))
+	}
+	assert.False(t, state.Flapping)
+}
@@ -4,51 +4,90 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/opsee/basic/schema"
-	"github.com/opsee/hugs/config"
 	"github.com/opsee/hugs/obj"
 	log "github.com/opsee/logrus"
 )
 
-var (
-	httpClient = &http.Client{
-		Timeout: 15 * time.Second,
-	}
+const (
+	// DefaultNocapTimeout bounds a single call to notificaption.
+	DefaultNocapTimeout = 15 * time.Second
+	// nocapBreakerThreshold is the number of consecutive notificaption
+	// failures after which we stop calling it for nocapBreakerCooldown.
+	nocapBreakerThreshold = 3
+	nocapBreakerCooldown  = time.Minute
 )
 
-func BuildEvent(n *obj.Notification, result *schema.CheckResult) (*obj.Event, error) {
+// Nocap is a client for the notificaption service, which renders screenshots
+// and JSON for CheckResults.
+type Nocap struct {
+	Endpoint string
+	Breaker  *Breaker
+	client   *http.Client
+}
+
+func NewNocap(endpoint string, timeout time.Duration) *Nocap {
+	if timeout <= 0 {
+		timeout = DefaultNocapTimeout
+	}
+	return &Nocap{
+		Endpoint: endpoint,
+		Breaker:  NewBreaker(nocapBreakerThreshold, nocapBreakerCooldown),
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// BuildEvent returns the event senders need for a CheckResult. Notificaption
+// data is best effort: every sender handles a nil Event.Nocap, so if
+// notificaption is unconfigured, failing or tripped we still return an event.
+func BuildEvent(nocap *Nocap, n *obj.Notification, result *schema.CheckResult) *obj.Event {
 	log.WithFields(log.Fields{"notification": n}).Info("Building event.")
 
 	event := &obj.Event{
 		Result: result,
 	}
 
-	// If we can't finish the notificaption stuff, then we still want to return
-	// an event with the CheckResult, because workers should be able to handle
-	// not having the Notificaption data.
-	if notifEndpoint := config.GetConfig().NotificaptionEndpoint; notifEndpoint != "" {
-		resp, err := getNocapResponse(notifEndpoint, result)
-		if err != nil {
-			log.WithFields(log.Fields{"err": err}).Error("Error getting Notificaption data")
-			return nil, err
-		}
-		event.Nocap = resp
-		log.WithFields(log.Fields{"nocap": resp}).Debug("Got nocap response")
-	} else {
+	if nocap == nil || nocap.Endpoint == "" {
 		log.Info("No notificaption endpoint configured.")
-		return nil, fmt.Errorf("No notificaption endpoint configured.")
+		return event
+	}
+
+	resp, err := nocap.Get(result)
+	if err != nil {
+		log.WithFields(log.Fields{"err": err, "check_id": result.CheckId}).Warn("Error getting Notificaption data, building event without it.")
+		return event
+	}
+
+	event.Nocap = resp
+	log.WithFields(log.Fields{"nocap": resp}).Debug("Got nocap response")
+	return event
+}
+
+// Get fetches notificaption data for a CheckResult, unless the circuit breaker
+// is open.
+func (c *Nocap) Get(result *schema.CheckResult) (*obj.NocapResponse, error) {
+	if !c.Breaker.Allow() {
+		return nil, ErrBreakerOpen
+	}
+
+	resp, err := c.getNocapResponse(result)
+	if err != nil {
+		c.Breaker.Failure()
+		return nil, err
 	}
 
-	return event, nil
+	c.Breaker.Success()
+	return resp, nil
 }
 
-func getNocapResponse(nocapEndpoint string, result *schema.CheckResult) (*obj.NocapResponse, error) {
+func (c *Nocap) getNocapResponse(result *schema.CheckResult) (*obj.NocapResponse, error) {
 	checkBytes, err := json.Marshal(result)
 	if err != nil {
 		return nil, err
@@ -59,7 +98,7 @@ func getNocapResponse(nocapEndpoint string, result *schema.CheckResult) (*obj.No
 	req, err := http.NewRequest(
 		"POST",
 		strings.Join([]string{
-			nocapEndpoint,
+			c.Endpoint,
 			"screenshot",
 		}, "/"),
 		body)
@@ -68,7 +107,7 @@ func getNocapResponse(nocapEndpoint string, result *schema.CheckResult) (*obj.No
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
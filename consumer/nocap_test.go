@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildEventWithNocap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/screenshot", r.URL.Path)
+		fmt.Fprint(w, `{"image_urls": {"default": "http://img"}, "json_url": "http://json"}`)
+	}))
+	defer server.Close()
+
+	result := obj.GenerateFailingTestEvent().Result
+	event := BuildEvent(NewNocap(server.URL, time.Second), &obj.Notification{}, result)
+
+	assert.Equal(t, result, event.Result)
+	if assert.NotNil(t, event.Nocap) {
+		assert.Equal(t, "http://json", event.Nocap.JSONUrl)
+	}
+}
+
+func TestBuildEventWithoutNocapEndpoint(t *testing.T) {
+	result := obj.GenerateFailingTestEvent().Result
+
+	event := BuildEvent(NewNocap("", time.Second), &obj.Notification{}, result)
+	assert.Equal(t, result, event.Result)
+	assert.Nil(t, event.Nocap)
+
+	event = BuildEvent(nil, &obj.Notification{}, result)
+	assert.Nil(t, event.Nocap)
+}
+
+func TestBuildEventFallsBackWhenNocapFails(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	nocap := NewNocap(server.URL, time.Second)
+	result := obj.GenerateFailingTestEvent().Result
+
+	for i := 0; i < nocapBreakerThreshold+2; i++ {
+		event := BuildEvent(nocap, &obj.Notification{}, result)
+		assert.Equal(t, result, event.Result)
+		assert.Nil(t, event.Nocap)
+	}
+
+	// the breaker stops us from calling notificaption once it has tripped
+	assert.Equal(t, int32(nocapBreakerThreshold), atomic.LoadInt32(&calls))
+	assert.True(t, nocap.Breaker.Open())
+}
+
+func TestBuildEventFallsBackOnTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	event := BuildEvent(NewNocap(server.URL, 10*time.Millisecond), &obj.Notification{}, obj.GenerateFailingTestEvent().Result)
+	assert.Nil(t, event.Nocap)
+}
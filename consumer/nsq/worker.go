@@ -1,11 +1,10 @@
 package nsq
 
 import (
-	"fmt"
-
 	"github.com/gogo/protobuf/proto"
 	"github.com/nsqio/go-nsq"
 	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/config"
 	hugsconsumer "github.com/opsee/hugs/consumer"
 	"github.com/opsee/hugs/notifier"
 	"github.com/opsee/hugs/store"
@@ -15,9 +14,12 @@ import (
 var nsqTopic = "alerts"
 
 type Worker struct {
-	Id       string
-	Store    *store.Postgres
-	Notifier *notifier.Notifier
+	Id          string
+	Store       store.Store
+	Notifier    *notifier.Notifier
+	Pipeline    *hugsconsumer.Pipeline
+	MaxAttempts int
+	consumer    *nsq.Consumer
 }
 
 func NewWorker(Id string) (*Worker, error) {
@@ -26,8 +28,13 @@ func NewWorker(Id string) (*Worker, error) {
 		return nil, err
 	}
 
+	deps, err := notifier.NewDependencies(s)
+	if err != nil {
+		return nil, err
+	}
+
 	// create new notifier and warn on errors
-	notifier, errMap := notifier.NewNotifier()
+	notifier, errMap := notifier.NewNotifier(deps)
 	for k, v := range errMap {
 		if v != nil {
 			log.WithFields(log.Fields{"worker": Id, "error": v}).Info("Couldn't initialize notifier: ", k)
@@ -36,9 +43,11 @@ func NewWorker(Id string) (*Worker, error) {
 	}
 
 	return &Worker{
-		Id:       Id,
-		Store:    s,
-		Notifier: notifier,
+		Id:          Id,
+		Store:       s,
+		Notifier:    notifier,
+		Pipeline:    hugsconsumer.NewPipeline(s, notifier),
+		MaxAttempts: config.GetConfig().MaxAttempts,
 	}, nil
 }
 
@@ -48,6 +57,9 @@ func (w *Worker) Start() error {
 
 	config := nsq.NewConfig()
 	config.MaxInFlight = 4
+	// HandleMessage gives up on messages itself so that they can be parked
+	// in the dead letter store instead of being dropped.
+	config.MaxAttempts = 0
 	consumer, err := nsq.NewConsumer(nsqTopic, nsqTopic, config)
 	if err != nil {
 		log.WithError(err).Error("couldn't create nsq consumer")
@@ -59,59 +71,56 @@ func (w *Worker) Start() error {
 		return err
 	}
 
+	w.consumer = consumer
 	return nil
 }
 
+// Stop stops taking messages, waits for the ones being handled and then parks
+// the results of notifications still waiting to be retried, which would
+// otherwise be lost with the process.
+func (w *Worker) Stop() {
+	logger := log.WithFields(log.Fields{"worker": w.Id})
+	logger.Info("Shutting down.")
+
+	if w.consumer != nil {
+		w.consumer.Stop()
+		<-w.consumer.StopChan
+	}
+	w.Pipeline.Stop()
+
+	logger.Info("Stopped.")
+}
+
 func (w *Worker) HandleMessage(message *nsq.Message) error {
-	log.WithFields(log.Fields{"worker": w.Id}).Info("Doing work...")
+	logger := log.WithFields(log.Fields{"worker": w.Id, "attempts": message.Attempts})
+	logger.Info("Doing work...")
 
 	result := &schema.CheckResult{}
 	err := proto.Unmarshal(message.Body, result)
 	if err != nil {
-		log.WithError(err).Error("couldn't unmarshal checkresult")
-		return err
-	}
-
-	notifications, err := w.Store.UnsafeGetNotificationsByCheckId(result.CheckId)
-	if err != nil {
-		log.WithError(err).Error("couldn't get notifications from the db")
+		logger.WithError(err).Error("couldn't unmarshal checkresult")
+		if w.exhausted(message) {
+			logger.Error("Giving up on message that isn't a CheckResult.")
+			return nil
+		}
 		return err
 	}
 
-	if len(notifications) < 1 {
-		log.Infof("no notifications found, skipping check id: %s", result.CheckId)
+	err = w.Pipeline.Process(result)
+	if err == nil {
 		return nil
 	}
 
-	event, err := hugsconsumer.BuildEvent(notifications[0], result)
-	if err != nil {
+	if !w.exhausted(message) {
 		return err
 	}
 
-	var msg string
-	if event.Result.Passing {
-		msg = "Sending passing notifications to customer."
-	} else {
-		msg = "Sending failing notifications to customer."
+	if parkErr := w.Pipeline.Park(result, int(message.Attempts), []string{err.Error()}); parkErr != nil {
+		return parkErr
 	}
-
-	log.WithFields(log.Fields{
-		"customer_id": event.Result.CustomerId,
-		"check_id":    event.Result.CheckId,
-	}).Info(msg)
-
-	for _, notification := range notifications {
-		// Send notification with Notifier
-		sendErr := w.Notifier.Send(notification, event)
-		if sendErr != nil {
-			log.WithFields(log.Fields{"worker": w.Id, "err": sendErr}).Error("Error emitting notification")
-			return sendErr
-		}
-		log.WithFields(log.Fields{
-			"customer_id": event.Result.CustomerId,
-			"check_id":    event.Result.CheckId,
-		}).Info(fmt.Sprintf("Sent %s notification to customer.", notification.Type))
-	}
-
 	return nil
 }
+
+func (w *Worker) exhausted(message *nsq.Message) bool {
+	return int(message.Attempts) >= w.MaxAttempts
+}
@@ -0,0 +1,484 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	log "github.com/opsee/logrus"
+)
+
+// Pipeline turns CheckResults into notifications. It is shared by the queue
+// workers and by dead letter replays.
+type Pipeline struct {
+	Store      store.Store
+	Dispatcher *Dispatcher
+	Escalator  *Escalator
+	Flaps      *FlapDetector
+	Nocap      *Nocap
+}
+
+func NewPipeline(s store.Store, sender Sender) *Pipeline {
+	cfg := config.GetConfig()
+	p := &Pipeline{
+		Store: s,
+		Flaps: NewFlapDetector(cfg.FlapThreshold, cfg.FlapWindow),
+		Nocap: NewNocap(cfg.NotificaptionEndpoint, cfg.NotificaptionTimeout),
+	}
+	p.Dispatcher = NewDispatcher(sender, DefaultBackoff, s)
+	p.Dispatcher.Undeliverable = p.undeliverable
+	p.Escalator = NewEscalator(p.Dispatcher)
+	p.Escalator.Active = p.failing
+	return p
+}
+
+// Process notifies the customer if the result changes the state of its check
+// and the new state has met the check's threshold. Checks that flap get a
+// single notification when they start flapping and a summary once they are
+// stable again. An error means nobody has been notified yet and the result
+// should be retried.
+func (p *Pipeline) Process(result *schema.CheckResult) error {
+	var err error
+	// Results for the same check can be processed concurrently. Only the one
+	// that records its state first acts on it, the others start over from the
+	// state it recorded.
+	for attempt := 0; attempt < maxStateAttempts; attempt++ {
+		err = p.process(result)
+		if err != store.ErrCheckStateChanged {
+			return err
+		}
+	}
+	return err
+}
+
+// maxStateAttempts is how many times Process reads and records a check's state
+// before leaving the result to be retried.
+const maxStateAttempts = 3
+
+func (p *Pipeline) process(result *schema.CheckResult) error {
+	if result.Passing {
+		p.Escalator.Cancel(result)
+	}
+
+	current := obj.NewCheckState(result)
+	previous, err := p.Store.GetCheckState(current.CustomerId, current.CheckId, current.TargetId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get check state from the db")
+		return err
+	}
+
+	threshold, err := p.Store.UnsafeGetThreshold(result.CustomerId, result.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get threshold from the db")
+		return err
+	}
+
+	now := time.Now().UTC()
+	state, transition := NextCheckState(previous, result, threshold, now)
+	if !transition {
+		if flapping := p.Flaps.Settle(state, now); flapping != nil {
+			log.WithFields(log.Fields{
+				"customer_id": result.CustomerId,
+				"check_id":    result.CheckId,
+				"transitions": flapping.Transitions,
+			}).Info("Check stopped flapping, sending summary.")
+			return p.dispatch(result, previous, state, flapping)
+		}
+
+		logger := log.WithFields(log.Fields{
+			"customer_id": result.CustomerId,
+			"check_id":    result.CheckId,
+			"target_id":   state.TargetId,
+			"passing":     result.Passing,
+		})
+		if IsTransition(previous, result) {
+			logger.WithField("consecutive", state.Consecutive).Info("Check threshold not met, suppressing notifications.")
+		} else {
+			logger.Info("Check state unchanged, suppressing notifications.")
+		}
+		return p.putCheckState(previous, state)
+	}
+
+	flapping := p.Flaps.Transition(state, now)
+
+	incident, err := p.incident(result)
+	if err != nil {
+		return err
+	}
+	if incident != nil && incident.Acknowledged() {
+		return p.suppress(result, previous, state, obj.DeliveryStatusAcknowledged, fmt.Errorf("incident %d is acknowledged", incident.Id))
+	}
+
+	if flapping != nil {
+		log.WithFields(log.Fields{
+			"customer_id": result.CustomerId,
+			"check_id":    result.CheckId,
+			"transitions": flapping.Transitions,
+		}).Info("Check is flapping, suppressing notifications until it is stable.")
+		return p.dispatch(result, previous, state, flapping)
+	}
+
+	if state.Flapping {
+		return p.suppress(result, previous, state, obj.DeliveryStatusFlapping, errors.New("check is flapping"))
+	}
+
+	return p.dispatch(result, previous, state, nil)
+}
+
+// ReplaySkippedError is returned by Replay when the result shouldn't be sent,
+// because there's no one to notify, the check is silenced or it is stale.
+type ReplaySkippedError struct {
+	Reason string
+}
+
+func (e *ReplaySkippedError) Error() string {
+	return "replay skipped: " + e.Reason
+}
+
+// UndeliveredError is returned by Replay when none of the notifications could
+// be delivered.
+type UndeliveredError struct {
+	Reasons []string
+}
+
+func (e *UndeliveredError) Error() string {
+	return fmt.Sprintf("couldn't deliver any notifications: %s", strings.Join(e.Reasons, "; "))
+}
+
+// Replay notifies the customer about a result and leaves the recorded state
+// alone. Each notification gets a single attempt and nothing is parked again,
+// so an error, returned unless at least one notification was delivered, means
+// the result can be replayed later. A result that disagrees with the state we
+// last notified about is stale and skipped unless force is set, so that a
+// recovered check doesn't page anyone about its old failure.
+func (p *Pipeline) Replay(result *schema.CheckResult, force bool) error {
+	notifications, err := p.Store.UnsafeGetNotificationsByCheckId(result.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get notifications from the db")
+		return err
+	}
+
+	if len(notifications) < 1 {
+		return &ReplaySkippedError{Reason: fmt.Sprintf("no notifications found for check id: %s", result.CheckId)}
+	}
+
+	silence, err := p.silence(result)
+	if err != nil {
+		return err
+	}
+	if silence != nil {
+		return &ReplaySkippedError{Reason: fmt.Sprintf("check is silenced by silence %d", silence.Id)}
+	}
+
+	if !force {
+		ids := obj.NewCheckState(result)
+		state, err := p.Store.GetCheckState(ids.CustomerId, ids.CheckId, ids.TargetId)
+		if err != nil {
+			log.WithError(err).Error("couldn't get check state from the db")
+			return err
+		}
+		if state != nil && state.Passing != result.Passing {
+			return &ReplaySkippedError{Reason: fmt.Sprintf("check is %s now, force the replay to send it anyway", passingName(state.Passing))}
+		}
+	}
+
+	event := BuildEvent(p.Nocap, notifications[0], result)
+
+	log.WithFields(log.Fields{
+		"customer_id": result.CustomerId,
+		"check_id":    result.CheckId,
+		"force":       force,
+	}).Info("Replaying notifications to customer.")
+
+	errs := p.Dispatcher.Attempt(notifications, event)
+	if len(errs) == len(notifications) {
+		reasons := []string{}
+		for _, err := range errs {
+			reasons = append(reasons, err.Error())
+		}
+		return &UndeliveredError{Reasons: reasons}
+	}
+
+	return nil
+}
+
+func passingName(passing bool) string {
+	if passing {
+		return "passing"
+	}
+	return "failing"
+}
+
+// Acknowledge tells the services that support it, PagerDuty for now, that
+// someone is handling the check's incident. That includes the targets of the
+// check's escalation policy, which may have been escalated to.
+func (p *Pipeline) Acknowledge(incident *obj.Incident) error {
+	notifications, err := p.Store.UnsafeGetNotificationsByCheckId(incident.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get notifications from the db")
+		return err
+	}
+
+	policy, err := p.Store.UnsafeGetEscalationPolicy(incident.CustomerId, incident.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get escalation policy from the db")
+		return err
+	}
+	if policy != nil {
+		result := &schema.CheckResult{CustomerId: incident.CustomerId, CheckId: incident.CheckId}
+		for _, step := range policy.Steps {
+			notifications = append(notifications, stepNotifications(step, result)...)
+		}
+	}
+
+	// each service key is acknowledged once, however many times it appears
+	acknowledgeable := []*obj.Notification{}
+	seen := map[string]bool{}
+	for _, n := range notifications {
+		if n.Type == "pagerduty" && !seen[n.Value] {
+			seen[n.Value] = true
+			acknowledgeable = append(acknowledgeable, n)
+		}
+	}
+
+	event := &obj.Event{
+		Result: &schema.CheckResult{
+			CustomerId: incident.CustomerId,
+			CheckId:    incident.CheckId,
+		},
+		Acknowledged: true,
+	}
+	p.Dispatcher.Dispatch(acknowledgeable, event)
+	return nil
+}
+
+// Stop gives up on pending notification retries, parking their results in
+// the dead letter store. Call it once no more results will be processed.
+func (p *Pipeline) Stop() {
+	p.Dispatcher.Stop()
+}
+
+// Park moves a result we could not deliver notifications for into the dead
+// letter store.
+func (p *Pipeline) Park(result *schema.CheckResult, attempts int, reasons []string) error {
+	deadLetter, err := obj.NewDeadLetter(result, attempts, reasons)
+	if err != nil {
+		return err
+	}
+
+	if err := p.Store.PutDeadLetter(deadLetter); err != nil {
+		log.WithError(err).WithFields(log.Fields{"customer_id": result.CustomerId, "check_id": result.CheckId}).Error("Couldn't park CheckResult in dead letter store.")
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"customer_id": result.CustomerId,
+		"check_id":    result.CheckId,
+		"dead_letter": deadLetter.Id,
+		"attempts":    attempts,
+		"reasons":     reasons,
+	}).Warn("Parked CheckResult in dead letter store.")
+	return nil
+}
+
+// dispatch notifies the customer about a result, or about the check flapping
+// when flapping is set.
+func (p *Pipeline) dispatch(result *schema.CheckResult, previous, state *obj.CheckState, flapping *obj.Flapping) error {
+	notifications, err := p.Store.UnsafeGetNotificationsByCheckId(result.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get notifications from the db")
+		return err
+	}
+
+	var policy *obj.EscalationPolicy
+	if flapping == nil {
+		policy, err = p.escalationPolicy(result, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(notifications) < 1 && policy == nil {
+		log.Infof("no notifications found, skipping check id: %s", result.CheckId)
+		return p.putCheckState(previous, state)
+	}
+
+	silence, err := p.silence(result)
+	if err != nil {
+		return err
+	}
+
+	// Silenced results don't touch the check state, so a check that is still
+	// failing when the silence ends notifies as usual.
+	if silence != nil {
+		log.WithFields(log.Fields{
+			"customer_id": result.CustomerId,
+			"check_id":    result.CheckId,
+			"silence_id":  silence.Id,
+		}).Info("Check is silenced, suppressing notifications.")
+		p.Dispatcher.Suppress(notifications, &obj.Event{Result: result}, obj.DeliveryStatusSilenced, fmt.Errorf("silenced by silence %d", silence.Id))
+		return nil
+	}
+
+	var first *obj.Notification
+	if len(notifications) > 0 {
+		first = notifications[0]
+	}
+	event := BuildEvent(p.Nocap, first, result)
+	event.Flapping = flapping
+
+	var msg string
+	if flapping != nil {
+		msg = "Sending flapping notifications to customer."
+	} else if event.Result.Passing {
+		msg = "Sending passing notifications to customer."
+	} else {
+		msg = "Sending failing notifications to customer."
+	}
+
+	log.WithFields(log.Fields{
+		"customer_id": event.Result.CustomerId,
+		"check_id":    event.Result.CheckId,
+	}).Info(msg)
+
+	// Record the new state before sending anything so that a failure here
+	// means the result is retried without having notified anyone yet.
+	if err := p.putCheckState(previous, state); err != nil {
+		return err
+	}
+
+	// Each notification is delivered and retried on its own, so retrying the
+	// result never re-sends notifications that already went out.
+	p.Dispatcher.Dispatch(notifications, event)
+
+	if policy != nil {
+		p.Escalator.Escalate(policy, event)
+	}
+
+	return nil
+}
+
+// escalationPolicy returns the policy to escalate a newly failing result
+// with, if its check has one. Replayed results are never escalated.
+func (p *Pipeline) escalationPolicy(result *schema.CheckResult, state *obj.CheckState) (*obj.EscalationPolicy, error) {
+	if result.Passing || state == nil {
+		return nil, nil
+	}
+
+	policy, err := p.Store.UnsafeGetEscalationPolicy(result.CustomerId, result.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get escalation policy from the db")
+		return nil, err
+	}
+	return policy, nil
+}
+
+// incident opens the check's incident for a failing result, or resolves it for
+// a passing one. It returns the open incident, if any.
+func (p *Pipeline) incident(result *schema.CheckResult) (*obj.Incident, error) {
+	if result.Passing {
+		if err := p.Store.ResolveIncident(result.CustomerId, result.CheckId); err != nil {
+			log.WithError(err).Error("couldn't resolve incident")
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	incident, err := p.Store.OpenIncident(result.CustomerId, result.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't open incident")
+		return nil, err
+	}
+	return incident, nil
+}
+
+// failing reports whether a check target is still failing, not silenced and
+// not acknowledged, erring on the side of escalating when we can't tell.
+func (p *Pipeline) failing(result *schema.CheckResult) bool {
+	state := obj.NewCheckState(result)
+	previous, err := p.Store.GetCheckState(state.CustomerId, state.CheckId, state.TargetId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get check state from the db")
+		return true
+	}
+	if previous != nil && previous.Passing {
+		return false
+	}
+
+	incident, err := p.Store.GetOpenIncident(result.CustomerId, result.CheckId)
+	if err == nil && incident != nil && incident.Acknowledged() {
+		return false
+	}
+
+	silence, err := p.silence(result)
+	return err != nil || silence == nil
+}
+
+// silence returns the first active silence matching the result, if any.
+func (p *Pipeline) silence(result *schema.CheckResult) (*obj.Silence, error) {
+	silences, err := p.Store.GetActiveSilences(result.CustomerId, time.Now())
+	if err != nil {
+		log.WithError(err).Error("couldn't get silences from the db")
+		return nil, err
+	}
+
+	for _, silence := range silences {
+		if silence.Matches(result) {
+			return silence, nil
+		}
+	}
+	return nil, nil
+}
+
+// suppress records the state, and a delivery with the given status for each of
+// the check's notifications, without sending anything.
+func (p *Pipeline) suppress(result *schema.CheckResult, previous, state *obj.CheckState, status string, reason error) error {
+	notifications, err := p.Store.UnsafeGetNotificationsByCheckId(result.CheckId)
+	if err != nil {
+		log.WithError(err).Error("couldn't get notifications from the db")
+		return err
+	}
+
+	if err := p.putCheckState(previous, state); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"customer_id": result.CustomerId,
+		"check_id":    result.CheckId,
+		"status":      status,
+	}).Info("Suppressing notifications.")
+	p.Dispatcher.Suppress(notifications, &obj.Event{Result: result}, status, reason)
+	return nil
+}
+
+func (p *Pipeline) putCheckState(previous, state *obj.CheckState) error {
+	if state == nil {
+		return nil
+	}
+
+	err := p.Store.PutCheckState(previous, state)
+	if err == store.ErrCheckStateChanged {
+		log.WithFields(log.Fields{"check_id": state.CheckId, "target_id": state.TargetId}).Info("Check state changed concurrently.")
+		return err
+	}
+	if err != nil {
+		log.WithError(err).Error("couldn't record check state")
+		return err
+	}
+	return nil
+}
+
+func (p *Pipeline) undeliverable(event *obj.Event, attempts int, reasons []string) {
+	// there is no CheckResult worth replaying behind an acknowledgement
+	if event.Acknowledged {
+		log.WithFields(log.Fields{"check_id": event.Result.CheckId, "reasons": reasons}).Error("Couldn't deliver acknowledgement.")
+		return
+	}
+	p.Park(event.Result, attempts, reasons)
+}
@@ -0,0 +1,268 @@
+package consumer
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var pipelineTestUser = &schema.User{
+	Id:         13,
+	CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+}
+
+// newTestPipeline builds a pipeline the way NewPipeline does, minus config.
+func newTestPipeline(s store.Store, sender Sender) *Pipeline {
+	p := &Pipeline{
+		Store: s,
+		Flaps: NewFlapDetector(5, time.Hour),
+		Nocap: NewNocap("", 0),
+	}
+	p.Dispatcher = NewDispatcher(sender, testBackoff, s)
+	p.Dispatcher.Undeliverable = p.undeliverable
+	p.Escalator = NewEscalator(p.Dispatcher)
+	p.Escalator.Active = p.failing
+	return p
+}
+
+func newPipelineTestStore(t *testing.T) (*store.Memory, *obj.Notification) {
+	s := store.NewMemory()
+	err := s.PutNotifications(pipelineTestUser, []*obj.Notification{
+		&obj.Notification{
+			CustomerId: pipelineTestUser.CustomerId,
+			UserId:     13,
+			CheckId:    "00002",
+			Value:      "http://localhost/hook",
+			Type:       "webhook",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifications, err := s.UnsafeGetNotificationsByCheckId("00002")
+	if err != nil || len(notifications) != 1 {
+		t.Fatal("expected a notification for the check", err)
+	}
+	return s, notifications[0]
+}
+
+func pipelineTestResult(passing bool) *schema.CheckResult {
+	event := obj.GenerateFailingTestEvent()
+	if passing {
+		event = obj.GenerateTestEvent()
+		event.Result.CheckId = "00002"
+	}
+	event.Result.CustomerId = pipelineTestUser.CustomerId
+	return event.Result
+}
+
+func TestPipelineNotifiesOnTransitions(t *testing.T) {
+	s, notification := newPipelineTestStore(t)
+	sender := newFlakySender(nil)
+	p := newTestPipeline(s, sender)
+
+	assert.Nil(t, p.Process(pipelineTestResult(false)))
+	assert.Nil(t, p.Process(pipelineTestResult(false)))
+	p.Dispatcher.Wait()
+	assert.Equal(t, 1, sender.Attempts(notification.Id))
+
+	incident, err := s.GetOpenIncident(pipelineTestUser.CustomerId, "00002")
+	assert.Nil(t, err)
+	assert.NotNil(t, incident)
+
+	assert.Nil(t, p.Process(pipelineTestResult(true)))
+	p.Dispatcher.Wait()
+	assert.Equal(t, 2, sender.Attempts(notification.Id))
+
+	incident, err = s.GetOpenIncident(pipelineTestUser.CustomerId, "00002")
+	assert.Nil(t, err)
+	assert.Nil(t, incident)
+
+	deliveries, err := s.GetDeliveriesByCheckId(pipelineTestUser, "00002", 0)
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.True(t, deliveries[0].Passing)
+		assert.Equal(t, obj.DeliveryStatusSent, deliveries[0].Status)
+	}
+}
+
+func TestPipelineSuppressesSilencedChecks(t *testing.T) {
+	s, notification := newPipelineTestStore(t)
+	sender := newFlakySender(nil)
+	p := newTestPipeline(s, sender)
+
+	err := s.PutSilence(pipelineTestUser, &obj.Silence{
+		CheckId:  "00002",
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, p.Process(pipelineTestResult(false)))
+	p.Dispatcher.Wait()
+	assert.Equal(t, 0, sender.Attempts(notification.Id))
+
+	deliveries, err := s.GetDeliveriesByCheckId(pipelineTestUser, "00002", 0)
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, obj.DeliveryStatusSilenced, deliveries[0].Status)
+	}
+}
+
+func TestPipelineParksUndeliverableResults(t *testing.T) {
+	s, notification := newPipelineTestStore(t)
+	sender := newFlakySender(map[int]int{notification.Id: 100})
+	p := newTestPipeline(s, sender)
+
+	assert.Nil(t, p.Process(pipelineTestResult(false)))
+	p.Dispatcher.Wait()
+
+	deadLetters, err := s.GetDeadLetters(pipelineTestUser, 0)
+	assert.Nil(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, "00002", deadLetters[0].CheckId)
+		assert.Equal(t, testBackoff.MaxAttempts, deadLetters[0].Attempts)
+		assert.NotNil(t, deadLetters[0].CheckResult)
+	}
+}
+
+func TestPipelineReplay(t *testing.T) {
+	s, notification := newPipelineTestStore(t)
+	sender := newFlakySender(map[int]int{notification.Id: 1})
+	p := newTestPipeline(s, sender)
+
+	// a failed replay isn't retried or parked again
+	assert.IsType(t, &UndeliveredError{}, p.Replay(pipelineTestResult(false), false))
+	p.Dispatcher.Wait()
+	assert.Equal(t, 1, sender.Attempts(notification.Id))
+
+	deadLetters, err := s.GetDeadLetters(pipelineTestUser, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, deadLetters)
+
+	assert.Nil(t, p.Replay(pipelineTestResult(false), false))
+	assert.Equal(t, 2, sender.Attempts(notification.Id))
+
+	deliveries, err := s.GetDeliveriesByCheckId(pipelineTestUser, "00002", 0)
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 2) {
+		assert.Equal(t, obj.DeliveryStatusSent, deliveries[0].Status)
+		assert.Equal(t, obj.DeliveryStatusFailed, deliveries[1].Status)
+	}
+}
+
+func TestPipelineReplaySkipsStaleResults(t *testing.T) {
+	s, notification := newPipelineTestStore(t)
+	sender := newFlakySender(map[int]int{})
+	p := newTestPipeline(s, sender)
+
+	// the check recovered after the failing result was parked
+	recovered := obj.NewCheckState(pipelineTestResult(false))
+	recovered.Passing = true
+	recovered.Observed = true
+	if err := s.PutCheckState(nil, recovered); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IsType(t, &ReplaySkippedError{}, p.Replay(pipelineTestResult(false), false))
+	assert.Equal(t, 0, sender.Attempts(notification.Id))
+
+	assert.Nil(t, p.Replay(pipelineTestResult(false), true))
+	assert.Equal(t, 1, sender.Attempts(notification.Id))
+
+	state, err := s.GetCheckState(recovered.CustomerId, recovered.CheckId, recovered.TargetId)
+	assert.Nil(t, err)
+	if assert.NotNil(t, state) {
+		assert.True(t, state.Passing)
+	}
+}
+
+// records the values of the notifications it is asked to send
+type valueSender struct {
+	sync.Mutex
+	values []string
+}
+
+func (s *valueSender) Send(n *obj.Notification, e *obj.Event) error {
+	s.Lock()
+	defer s.Unlock()
+	s.values = append(s.values, n.Type+":"+n.Value)
+	return nil
+}
+
+func TestPipelineAcknowledgesEscalationTargets(t *testing.T) {
+	s, _ := newPipelineTestStore(t)
+	sender := &valueSender{}
+	p := newTestPipeline(s, sender)
+
+	err := s.PutNotifications(pipelineTestUser, []*obj.Notification{
+		&obj.Notification{CustomerId: pipelineTestUser.CustomerId, CheckId: "00002", Type: "pagerduty", Value: "service-key"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.PutEscalationPolicy(pipelineTestUser, &obj.EscalationPolicy{
+		CheckId: "00002",
+		Steps: obj.EscalationSteps{
+			&obj.EscalationStep{Delay: 60, Notifications: []*obj.Notification{
+				&obj.Notification{Type: "pagerduty", Value: "service-key"},
+				&obj.Notification{Type: "pagerduty", Value: "escalation-key"},
+				&obj.Notification{Type: "email", Value: "oncall@opsee.com"},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	incident := &obj.Incident{CustomerId: pipelineTestUser.CustomerId, CheckId: "00002"}
+	assert.Nil(t, p.Acknowledge(incident))
+	p.Dispatcher.Wait()
+
+	sort.Strings(sender.values)
+	assert.Equal(t, []string{"pagerduty:escalation-key", "pagerduty:service-key"}, sender.values)
+}
+
+// racingStore lets another worker process a result between the pipeline
+// reading a check's state and recording the next one.
+type racingStore struct {
+	*store.Memory
+	race func()
+}
+
+func (s *racingStore) GetCheckState(customerId, checkId, targetId string) (*obj.CheckState, error) {
+	state, err := s.Memory.GetCheckState(customerId, checkId, targetId)
+	if s.race != nil {
+		race := s.race
+		s.race = nil
+		race()
+	}
+	return state, err
+}
+
+func TestPipelineNotifiesOnceForConcurrentTransitions(t *testing.T) {
+	memory, notification := newPipelineTestStore(t)
+	s := &racingStore{Memory: memory}
+	sender := newFlakySender(nil)
+	p := newTestPipeline(s, sender)
+	other := newTestPipeline(memory, sender)
+
+	s.race = func() {
+		assert.Nil(t, other.Process(pipelineTestResult(false)))
+	}
+	assert.Nil(t, p.Process(pipelineTestResult(false)))
+
+	p.Dispatcher.Wait()
+	other.Dispatcher.Wait()
+	assert.Equal(t, 1, sender.Attempts(notification.Id))
+}
@@ -4,8 +4,8 @@ import (
 	//"encoding/json"
 	"encoding/base64"
 	"errors"
-	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -20,6 +20,10 @@ import (
 	"github.com/yeller/yeller-golang"
 )
 
+// approximateReceiveCount is the SQS message attribute holding the number of
+// times a message has been received.
+const approximateReceiveCount = "ApproximateReceiveCount"
+
 var (
 	httpClient = &http.Client{
 		Timeout: 15 * time.Second,
@@ -30,8 +34,10 @@ type Worker struct {
 	Id                string
 	SQS               *sqs.SQS
 	SQSUrl            string
-	Store             *store.Postgres
+	Store             store.Store
 	Notifier          *notifier.Notifier
+	Pipeline          *consumer.Pipeline
+	MaxAttempts       int
 	errCount          int
 	errCountThreshold int
 }
@@ -42,8 +48,13 @@ func NewWorker(Id string, maxErr int, sqsUrl string) (*Worker, error) {
 		return nil, err
 	}
 
+	deps, err := notifier.NewDependencies(s)
+	if err != nil {
+		return nil, err
+	}
+
 	// create new notifier and warn on errors
-	notifier, errMap := notifier.NewNotifier()
+	notifier, errMap := notifier.NewNotifier(deps)
 	for k, v := range errMap {
 		if v != nil {
 			log.WithFields(log.Fields{"worker": Id, "error": v}).Info("Couldn't initialize notifier: ", k)
@@ -57,6 +68,8 @@ func NewWorker(Id string, maxErr int, sqsUrl string) (*Worker, error) {
 		SQSUrl:            sqsUrl,
 		Store:             s,
 		Notifier:          notifier,
+		Pipeline:          consumer.NewPipeline(s, notifier),
+		MaxAttempts:       config.GetConfig().MaxAttempts,
 		errCount:          0,
 		errCountThreshold: maxErr,
 	}, nil
@@ -100,6 +113,7 @@ func (w *Worker) Work() {
 		QueueUrl:            aws.String(w.SQSUrl),
 		MaxNumberOfMessages: aws.Int64(10),
 		WaitTimeSeconds:     aws.Int64(20),
+		AttributeNames:      []*string{aws.String(approximateReceiveCount)},
 	}
 	message, err := w.SQS.ReceiveMessage(input)
 
@@ -135,6 +149,7 @@ func (w *Worker) Work() {
 			if err := w.deleteMessage(message.ReceiptHandle); err != nil {
 				log.WithError(err).WithFields(log.Fields{"worker": w.Id, "message": *message.Body}).Error("Cannot delete message from SQS.")
 			}
+			continue
 		}
 
 		result := &schema.CheckResult{}
@@ -147,60 +162,40 @@ func (w *Worker) Work() {
 			if err := w.deleteMessage(message.ReceiptHandle); err != nil {
 				log.WithError(err).WithFields(log.Fields{"worker": w.Id, "message": *message.Body}).Error("Cannot delete message from SQS.")
 			}
+			continue
 		}
 		log.WithFields(log.Fields{"worker": w.Id, "CheckResult": result.String()}).Info("Unmarshalled CheckResult.")
 
-		notifications, err := w.Store.UnsafeGetNotificationsByCheckId(result.CheckId)
+		err = w.Pipeline.Process(result)
 		if err != nil {
-			//TODO(dan) send message back to sqs if you can't get notifications
-			// OR send notification to seperate SQS queue for redelivery
-			log.WithFields(log.Fields{"worker": w.Id}).Warn("Worker: Couldn't get notifications for event.")
-			info := make(map[string]interface{})
-			info["message"] = string(bodyBytes)
-			yeller.NotifyInfo(err, info)
-			continue
-		}
-
-		if len(notifications) < 1 {
-			log.WithFields(log.Fields{"worker": w.Id, "check": result.CheckId}).Info("Deleting check with no notifications.")
-			if err := w.deleteMessage(message.ReceiptHandle); err != nil {
-				log.WithError(err).WithFields(log.Fields{"worker": w.Id, "message": *message.Body}).Error("Cannot delete message from SQS.")
-			}
-		} else {
-			event, err := consumer.BuildEvent(notifications[0], result)
-			if err != nil {
-				return
+			receiveCount := receiveCount(message)
+			if receiveCount < w.MaxAttempts {
+				// leave the message on the queue, SQS will redeliver it once
+				// its visibility timeout expires.
+				log.WithError(err).WithFields(log.Fields{"worker": w.Id, "check": result.CheckId, "receive_count": receiveCount}).Warn("Worker: Couldn't process CheckResult, leaving it for redelivery.")
+				info := make(map[string]interface{})
+				info["message"] = string(bodyBytes)
+				yeller.NotifyInfo(err, info)
+				continue
 			}
 
-			var msg string
-			if event.Result.Passing {
-				msg = "Sending passing notifications to customer."
-			} else {
-				msg = "Sending failing notifications to customer."
+			if err := w.Pipeline.Park(result, receiveCount, []string{err.Error()}); err != nil {
+				continue
 			}
+		}
 
-			log.WithFields(log.Fields{
-				"customer_id": event.Result.CustomerId,
-				"check_id":    event.Result.CheckId,
-			}).Info(msg)
-
-			for _, notification := range notifications {
-				// Send notification with Notifier
-				sendErr := w.Notifier.Send(notification, event)
-				if sendErr != nil {
-					log.WithFields(log.Fields{"worker": w.Id, "err": sendErr}).Error("Error emitting notification")
-				} else {
-					log.WithFields(log.Fields{
-						"customer_id": event.Result.CustomerId,
-						"check_id":    event.Result.CheckId,
-					}).Info(fmt.Sprintf("Sent %s notification to customer.", notification.Type))
-					// If we successfully send one notification, then we're going to delete the SQS Message.
-					// TODO(greg): Separate queues per notification type.
-					if err := w.deleteMessage(message.ReceiptHandle); err != nil {
-						log.WithError(err).WithFields(log.Fields{"worker": w.Id, "message": *message.Body}).Error("Cannot delete message from SQS.")
-					}
-				}
-			}
+		if err := w.deleteMessage(message.ReceiptHandle); err != nil {
+			log.WithError(err).WithFields(log.Fields{"worker": w.Id, "message": *message.Body}).Error("Cannot delete message from SQS.")
+		}
+	}
+}
+
+// receiveCount returns the number of times SQS has handed out a message.
+func receiveCount(message *sqs.Message) int {
+	if count, ok := message.Attributes[approximateReceiveCount]; ok && count != nil {
+		if i, err := strconv.Atoi(*count); err == nil {
+			return i
 		}
 	}
+	return 1
 }
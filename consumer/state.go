@@ -0,0 +1,52 @@
+package consumer
+
+import (
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+// IsTransition reports whether a CheckResult changes the state we last
+// notified the customer about. A check we have no state for is assumed to be
+// passing, so only a failing first result is a transition.
+func IsTransition(previous *obj.CheckState, result *schema.CheckResult) bool {
+	if previous == nil {
+		return !result.Passing
+	}
+	return previous.Passing != result.Passing
+}
+
+// NextCheckState returns the state to record for a CheckResult, and whether the
+// result completes a transition the customer should be notified about. A
+// transition only completes once the new state has held long enough to meet
+// the threshold, which is optional.
+func NextCheckState(previous *obj.CheckState, result *schema.CheckResult, threshold *obj.Threshold, now time.Time) (*obj.CheckState, bool) {
+	next := obj.NewCheckState(result)
+	next.UpdatedAt = now
+	next.ObservedSince = now
+	next.Passing = previous == nil || previous.Passing
+
+	if previous != nil {
+		next.Transitions = previous.Transitions
+		next.Flapping = previous.Flapping
+		next.FlapCount = previous.FlapCount
+		next.FlappingSince = previous.FlappingSince
+	}
+
+	if previous != nil && previous.Observed == result.Passing {
+		next.Consecutive = previous.Consecutive + 1
+		next.ObservedSince = previous.ObservedSince
+	}
+
+	if !IsTransition(previous, result) {
+		return next, false
+	}
+
+	if threshold != nil && !threshold.Met(next.Consecutive, now.Sub(next.ObservedSince)) {
+		return next, false
+	}
+
+	next.Passing = result.Passing
+	return next, true
+}
@@ -0,0 +1,100 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransition(t *testing.T) {
+	passing := &obj.CheckState{Passing: true}
+	failing := &obj.CheckState{Passing: false}
+
+	cases := []struct {
+		name     string
+		previous *obj.CheckState
+		passing  bool
+		expected bool
+	}{
+		{"first result passing", nil, true, false},
+		{"first result failing", nil, false, true},
+		{"still passing", passing, true, false},
+		{"still failing", failing, false, false},
+		{"pass to fail", passing, false, true},
+		{"fail to pass", failing, true, true},
+	}
+
+	for _, c := range cases {
+		result := &schema.CheckResult{CheckId: "check", Passing: c.passing}
+		assert.Equal(t, c.expected, IsTransition(c.previous, result), c.name)
+	}
+}
+
+func TestNextCheckStateWithoutThreshold(t *testing.T) {
+	now := time.Now()
+	failing := &schema.CheckResult{CustomerId: "customer", CheckId: "check", Passing: false}
+
+	state, transition := NextCheckState(nil, failing, nil, now)
+	assert.True(t, transition)
+	assert.False(t, state.Passing)
+	assert.Equal(t, 1, state.Consecutive)
+
+	state, transition = NextCheckState(state, failing, nil, now)
+	assert.False(t, transition)
+	assert.Equal(t, 2, state.Consecutive)
+}
+
+func TestNextCheckStateConsecutiveThreshold(t *testing.T) {
+	now := time.Now()
+	threshold := &obj.Threshold{Consecutive: 3}
+	failing := &schema.CheckResult{CustomerId: "customer", CheckId: "check", Passing: false}
+	passing := &schema.CheckResult{CustomerId: "customer", CheckId: "check", Passing: true}
+
+	var state *obj.CheckState
+	var transition bool
+
+	// the third failure in a row alerts
+	for i := 0; i < 2; i++ {
+		state, transition = NextCheckState(state, failing, threshold, now)
+		assert.False(t, transition)
+		assert.True(t, state.Passing)
+	}
+	state, transition = NextCheckState(state, failing, threshold, now)
+	assert.True(t, transition)
+	assert.False(t, state.Passing)
+
+	// a passing result in between resets the count for recovery
+	state, _ = NextCheckState(state, passing, threshold, now)
+	state, _ = NextCheckState(state, failing, threshold, now)
+	assert.Equal(t, 1, state.Consecutive)
+	assert.False(t, state.Passing)
+
+	// the third pass in a row recovers
+	for i := 0; i < 2; i++ {
+		state, transition = NextCheckState(state, passing, threshold, now)
+		assert.False(t, transition)
+		assert.False(t, state.Passing)
+	}
+	state, transition = NextCheckState(state, passing, threshold, now)
+	assert.True(t, transition)
+	assert.True(t, state.Passing)
+}
+
+func TestNextCheckStateDurationThreshold(t *testing.T) {
+	start := time.Now()
+	threshold := &obj.Threshold{Duration: 300}
+	failing := &schema.CheckResult{CustomerId: "customer", CheckId: "check", Passing: false}
+
+	state, transition := NextCheckState(nil, failing, threshold, start)
+	assert.False(t, transition)
+
+	state, transition = NextCheckState(state, failing, threshold, start.Add(4*time.Minute))
+	assert.False(t, transition)
+
+	state, transition = NextCheckState(state, failing, threshold, start.Add(5*time.Minute))
+	assert.True(t, transition)
+	assert.False(t, state.Passing)
+}
@@ -0,0 +1,94 @@
+// Package hugstest provides in-process fakes of the third party APIs hugs
+// talks to, so that senders and handlers can be tested end to end without
+// the network. Every fake is an httptest.Server that records the requests it
+// receives; point the matching config URL (or sender constructor) at its URL.
+package hugstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Request is a request received by one of the fakes.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Form   url.Values
+	Body   []byte
+}
+
+// Decode unmarshals the JSON body of the request into v.
+func (r *Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+type recorder struct {
+	sync.Mutex
+	requests []*Request
+}
+
+// record reads the request body, parses form values and keeps a copy of the
+// request around. The body is restored so handlers can read it again.
+func (rec *recorder) record(r *http.Request) *Request {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	req := &Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header,
+		Form:   url.Values{},
+		Body:   body,
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			req.Form = form
+		}
+	}
+	for k, v := range r.URL.Query() {
+		req.Form[k] = append(req.Form[k], v...)
+	}
+
+	rec.Lock()
+	rec.requests = append(rec.requests, req)
+	rec.Unlock()
+
+	return req
+}
+
+// Requests returns the requests received for path, or every request if path
+// is empty.
+func (rec *recorder) Requests(path string) []*Request {
+	rec.Lock()
+	defer rec.Unlock()
+
+	requests := []*Request{}
+	for _, r := range rec.requests {
+		if path == "" || r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+
+	return requests
+}
+
+// Reset forgets every recorded request.
+func (rec *recorder) Reset() {
+	rec.Lock()
+	rec.requests = nil
+	rec.Unlock()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,148 @@
+package hugstest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/keighl/mandrill"
+)
+
+// MandrillSendTemplatePath is where the mandrill client posts templated messages.
+const MandrillSendTemplatePath = "/messages/send-template.json"
+
+// MandrillSendPath is where the mandrill client posts messages it rendered.
+const MandrillSendPath = "/messages/send.json"
+
+// MandrillMessage is a messages/send call received by the fake Mandrill.
+type MandrillMessage struct {
+	Key     string            `json:"key"`
+	Message *mandrill.Message `json:"message"`
+}
+
+// MandrillTemplateMessage is a messages/send-template call received by the
+// fake Mandrill.
+type MandrillTemplateMessage struct {
+	Key             string               `json:"key"`
+	TemplateName    string               `json:"template_name"`
+	TemplateContent []*mandrill.Variable `json:"template_content"`
+	Message         *mandrill.Message    `json:"message"`
+}
+
+// Mandrill emulates Mandrill's messages/send-template and messages/send
+// endpoints. The mandrill
+// client appends paths to its base URL directly, so use URL + "/" as the
+// MandrillAPIURL.
+type Mandrill struct {
+	*httptest.Server
+	recorder
+
+	// Key is the only API key accepted by the fake. If empty, any non-empty
+	// key is accepted.
+	Key string
+}
+
+func NewMandrill() *Mandrill {
+	m := &Mandrill{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(MandrillSendTemplatePath, m.sendTemplate)
+	mux.HandleFunc(MandrillSendPath, m.send)
+	m.Server = httptest.NewServer(mux)
+
+	return m
+}
+
+// Messages returns the templated messages received so far.
+func (m *Mandrill) Messages() []*MandrillTemplateMessage {
+	messages := []*MandrillTemplateMessage{}
+	for _, r := range m.Requests(MandrillSendTemplatePath) {
+		msg := &MandrillTemplateMessage{}
+		if err := r.Decode(msg); err == nil {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// Sent returns the rendered messages received so far.
+func (m *Mandrill) Sent() []*MandrillMessage {
+	messages := []*MandrillMessage{}
+	for _, r := range m.Requests(MandrillSendPath) {
+		msg := &MandrillMessage{}
+		if err := r.Decode(msg); err == nil {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+func (m *Mandrill) mandrillError(w http.ResponseWriter, status int, name, msg string) {
+	writeJSON(w, status, &mandrill.Error{
+		Status:  "error",
+		Code:    -1,
+		Name:    name,
+		Message: msg,
+	})
+}
+
+func (m *Mandrill) sendTemplate(w http.ResponseWriter, r *http.Request) {
+	msg := &MandrillTemplateMessage{}
+	if err := m.record(r).Decode(msg); err != nil {
+		m.mandrillError(w, http.StatusInternalServerError, "ValidationError", err.Error())
+		return
+	}
+
+	if msg.Key == "" || (m.Key != "" && msg.Key != m.Key) {
+		m.mandrillError(w, http.StatusInternalServerError, "Invalid_Key", "Invalid API key")
+		return
+	}
+
+	if msg.TemplateName == "" {
+		m.mandrillError(w, http.StatusInternalServerError, "Unknown_Template", "No such template \"\"")
+		return
+	}
+
+	if msg.Message == nil || len(msg.Message.To) == 0 {
+		m.mandrillError(w, http.StatusInternalServerError, "ValidationError", "You must specify a recipient")
+		return
+	}
+
+	m.respond(w, msg.Message, len(m.Requests(MandrillSendTemplatePath)))
+}
+
+func (m *Mandrill) send(w http.ResponseWriter, r *http.Request) {
+	msg := &MandrillMessage{}
+	if err := m.record(r).Decode(msg); err != nil {
+		m.mandrillError(w, http.StatusInternalServerError, "ValidationError", err.Error())
+		return
+	}
+
+	if msg.Key == "" || (m.Key != "" && msg.Key != m.Key) {
+		m.mandrillError(w, http.StatusInternalServerError, "Invalid_Key", "Invalid API key")
+		return
+	}
+
+	if msg.Message == nil || len(msg.Message.To) == 0 {
+		m.mandrillError(w, http.StatusInternalServerError, "ValidationError", "You must specify a recipient")
+		return
+	}
+
+	m.respond(w, msg.Message, len(m.Requests(MandrillSendPath)))
+}
+
+// respond reports every recipient of a message as sent.
+func (m *Mandrill) respond(w http.ResponseWriter, message *mandrill.Message, n int) {
+	responses := []*mandrill.Response{}
+	for i, to := range message.To {
+		responses = append(responses, &mandrill.Response{
+			Email:  to.Email,
+			Status: "sent",
+			Id:     fmt.Sprintf("%032d", n*100+i),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
@@ -0,0 +1,129 @@
+package hugstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/opsee/hugs/obj"
+)
+
+// Opsgenie emulates the Opsgenie alert API: creating alerts and closing or
+// acknowledging them by alias. Use its URL as the OpsgenieAPIURL and Key as
+// the integration's API key.
+type Opsgenie struct {
+	*httptest.Server
+	recorder
+
+	Key string
+}
+
+func NewOpsgenie() *Opsgenie {
+	o := &Opsgenie{Key: "test"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(obj.OpsgenieAlertsPath, o.createAlert)
+	mux.HandleFunc(obj.OpsgenieAlertsPath+"/", o.alertAction)
+	o.Server = httptest.NewServer(mux)
+
+	return o
+}
+
+// Alerts returns the alerts created so far.
+func (o *Opsgenie) Alerts() []*obj.OpsgenieAlertRequest {
+	alerts := []*obj.OpsgenieAlertRequest{}
+	for _, r := range o.Requests(obj.OpsgenieAlertsPath) {
+		alert := &obj.OpsgenieAlertRequest{}
+		if err := r.Decode(alert); err == nil {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts
+}
+
+// Actions returns the aliases of the alerts action (close or acknowledge) was
+// taken on so far.
+func (o *Opsgenie) Actions(action string) []string {
+	aliases := []string{}
+	for _, r := range o.Requests("") {
+		alias, a, ok := o.parseActionPath(r.Path)
+		if ok && a == action {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
+
+func (o *Opsgenie) parseActionPath(path string) (alias, action string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(path, obj.OpsgenieAlertsPath+"/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func (o *Opsgenie) respond(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &obj.OpsgenieResponse{
+		Result:    msg,
+		Message:   msg,
+		Took:      0.01,
+		RequestId: "hugstest",
+	})
+}
+
+func (o *Opsgenie) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "GenieKey "+o.Key {
+		o.respond(w, http.StatusUnauthorized, "Could not authenticate")
+		return false
+	}
+
+	return true
+}
+
+func (o *Opsgenie) createAlert(w http.ResponseWriter, r *http.Request) {
+	req := o.record(r)
+	if !o.authorized(w, r) {
+		return
+	}
+
+	alert := &obj.OpsgenieAlertRequest{}
+	if err := req.Decode(alert); err != nil {
+		o.respond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if alert.Message == "" {
+		o.respond(w, http.StatusUnprocessableEntity, "Message can not be empty.")
+		return
+	}
+
+	if len(alert.Message) > obj.OpsgenieMessageLimit {
+		o.respond(w, http.StatusUnprocessableEntity, "Message can not be longer than 130 characters.")
+		return
+	}
+
+	o.respond(w, http.StatusAccepted, "Request will be processed")
+}
+
+func (o *Opsgenie) alertAction(w http.ResponseWriter, r *http.Request) {
+	req := o.record(r)
+	if !o.authorized(w, r) {
+		return
+	}
+
+	_, action, ok := o.parseActionPath(req.Path)
+	if !ok || (action != "close" && action != "acknowledge") {
+		o.respond(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	if req.Form.Get("identifierType") != "alias" {
+		o.respond(w, http.StatusNotFound, "Alert not found")
+		return
+	}
+
+	o.respond(w, http.StatusAccepted, "Request will be processed")
+}
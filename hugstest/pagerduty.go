@@ -0,0 +1,126 @@
+package hugstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/opsee/hugs/obj"
+)
+
+// PagerDuty emulates the PagerDuty Events API, both the v1 generic endpoint
+// and v2 enqueue. Use its URL as the PagerDutyEventsURL.
+type PagerDuty struct {
+	*httptest.Server
+	recorder
+}
+
+func NewPagerDuty() *PagerDuty {
+	p := &PagerDuty{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(obj.PagerDutyEventsV1Path, p.createEvent)
+	mux.HandleFunc(obj.PagerDutyEventsV2Path, p.enqueue)
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// Events returns the v1 events received so far.
+func (p *PagerDuty) Events() []*obj.PagerDutyRequest {
+	events := []*obj.PagerDutyRequest{}
+	for _, r := range p.Requests(obj.PagerDutyEventsV1Path) {
+		event := &obj.PagerDutyRequest{}
+		if err := r.Decode(event); err == nil {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// V2Events returns the v2 events received so far.
+func (p *PagerDuty) V2Events() []*obj.PagerDutyV2Request {
+	events := []*obj.PagerDutyV2Request{}
+	for _, r := range p.Requests(obj.PagerDutyEventsV2Path) {
+		event := &obj.PagerDutyV2Request{}
+		if err := r.Decode(event); err == nil {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func (p *PagerDuty) invalid(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"status":  "invalid event",
+		"message": "Event object is invalid",
+		"errors":  []string{msg},
+	})
+}
+
+func (p *PagerDuty) createEvent(w http.ResponseWriter, r *http.Request) {
+	event := &obj.PagerDutyRequest{}
+	if err := p.record(r).Decode(event); err != nil {
+		p.invalid(w, err.Error())
+		return
+	}
+
+	if event.ServiceKey == "" {
+		p.invalid(w, "Service key is the wrong length (should be 32 characters)")
+		return
+	}
+
+	switch event.EventType {
+	case "trigger", "acknowledge", "resolve":
+	default:
+		p.invalid(w, "Event type is invalid")
+		return
+	}
+
+	if event.EventType != "trigger" && event.IncidentKey == "" {
+		p.invalid(w, "Incident key is required")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":       "success",
+		"message":      "Event processed",
+		"incident_key": event.IncidentKey,
+	})
+}
+
+func (p *PagerDuty) enqueue(w http.ResponseWriter, r *http.Request) {
+	event := &obj.PagerDutyV2Request{}
+	if err := p.record(r).Decode(event); err != nil {
+		p.invalid(w, err.Error())
+		return
+	}
+
+	if event.RoutingKey == "" {
+		p.invalid(w, "'routing_key' is missing or blank")
+		return
+	}
+
+	switch event.EventAction {
+	case "trigger":
+		if event.Payload == nil {
+			p.invalid(w, "'payload' is missing or blank")
+			return
+		}
+	case "acknowledge", "resolve":
+		if event.DedupKey == "" {
+			p.invalid(w, "'dedup_key' is missing or blank")
+			return
+		}
+	default:
+		p.invalid(w, "'event_action' is invalid")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, &obj.PagerDutyV2Response{
+		Status:   "success",
+		Message:  "Event processed",
+		DedupKey: event.DedupKey,
+	})
+}
@@ -0,0 +1,185 @@
+package hugstest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+)
+
+// SlackInvalidCode is an oauth code the fake Slack rejects.
+const SlackInvalidCode = "invalid"
+
+// Slack emulates the Slack web API endpoints used by hugs: oauth.access,
+// chat.postMessage, chat.update, channels.list and team.info. Use its URL as
+// the SlackAPIURL.
+type Slack struct {
+	*httptest.Server
+	recorder
+
+	// Token is the only token accepted by the fake. If empty, any non-empty
+	// token is accepted.
+	Token         string
+	OAuthResponse *obj.SlackOAuthResponse
+	Channels      []*obj.SlackChannel
+	TeamDomain    string
+
+	// messages counts posted messages to give each a unique ts
+	messages int
+	posted   map[string]bool
+}
+
+func NewSlack() *Slack {
+	s := &Slack{
+		Token: "test",
+		OAuthResponse: &obj.SlackOAuthResponse{
+			AccessToken: "test",
+			Scope:       "bot",
+			TeamName:    "test",
+			TeamId:      "test",
+			IncomingWebhook: &obj.SlackIncomingWebhook{
+				URL:              "test",
+				Channel:          "test",
+				ConfigurationURL: "test",
+			},
+			Bot: &obj.SlackBotCreds{
+				BotUserId:      "test",
+				BotAccessToken: "test",
+			},
+		},
+		Channels: []*obj.SlackChannel{
+			&obj.SlackChannel{Id: "C00000001", Name: "general"},
+		},
+		TeamDomain: "test",
+		posted:     map[string]bool{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth.access", s.oauthAccess)
+	mux.HandleFunc("/chat.postMessage", s.authorized(s.chatPostMessage))
+	mux.HandleFunc("/chat.update", s.authorized(s.chatUpdate))
+	mux.HandleFunc("/channels.list", s.authorized(s.channelsList))
+	mux.HandleFunc("/team.info", s.authorized(s.teamInfo))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Messages returns the chat.postMessage requests received so far.
+func (s *Slack) Messages() []*Request {
+	return s.Requests("/chat.postMessage")
+}
+
+// Updates returns the chat.update requests received so far.
+func (s *Slack) Updates() []*Request {
+	return s.Requests("/chat.update")
+}
+
+func (s *Slack) slackError(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusOK, &obj.SlackResponse{OK: false, Error: msg})
+}
+
+func (s *Slack) authorized(h func(http.ResponseWriter, *Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := s.record(r)
+
+		token := req.Form.Get("token")
+		if token == "" {
+			s.slackError(w, "not_authed")
+			return
+		}
+		if s.Token != "" && token != s.Token {
+			s.slackError(w, "invalid_auth")
+			return
+		}
+
+		h(w, req)
+	}
+}
+
+func (s *Slack) oauthAccess(w http.ResponseWriter, r *http.Request) {
+	req := s.record(r)
+
+	code := req.Form.Get("code")
+	if code == "" || code == SlackInvalidCode {
+		s.slackError(w, "invalid_code")
+		return
+	}
+
+	response := *s.OAuthResponse
+	response.OK = true
+	writeJSON(w, http.StatusOK, &response)
+}
+
+func (s *Slack) chatPostMessage(w http.ResponseWriter, req *Request) {
+	channel := req.Form.Get("channel")
+	if channel == "" {
+		s.slackError(w, "channel_not_found")
+		return
+	}
+
+	// replies are only accepted in threads of messages posted to the fake
+	s.Lock()
+	threadTs := req.Form.Get("thread_ts")
+	if threadTs != "" && !s.posted[threadTs] {
+		s.Unlock()
+		s.slackError(w, "thread_not_found")
+		return
+	}
+	s.messages++
+	ts := fmt.Sprintf("%d.%06d", time.Now().Unix(), s.messages)
+	s.posted[ts] = true
+	s.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok":      true,
+		"channel": channel,
+		"ts":      ts,
+	})
+}
+
+func (s *Slack) chatUpdate(w http.ResponseWriter, req *Request) {
+	channel, ts := req.Form.Get("channel"), req.Form.Get("ts")
+	if channel == "" {
+		s.slackError(w, "channel_not_found")
+		return
+	}
+	if ts == "" {
+		s.slackError(w, "message_not_found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok":      true,
+		"channel": channel,
+		"ts":      ts,
+	})
+}
+
+func (s *Slack) channelsList(w http.ResponseWriter, req *Request) {
+	channels := []map[string]interface{}{}
+	for _, c := range s.Channels {
+		channels = append(channels, map[string]interface{}{
+			"id":   c.Id,
+			"name": c.Name,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok":       true,
+		"channels": channels,
+	})
+}
+
+func (s *Slack) teamInfo(w http.ResponseWriter, req *Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok": true,
+		"team": map[string]interface{}{
+			"id":     s.OAuthResponse.TeamId,
+			"name":   s.OAuthResponse.TeamName,
+			"domain": s.TeamDomain,
+		},
+	})
+}
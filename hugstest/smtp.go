@@ -0,0 +1,278 @@
+package hugstest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPMessage is a message delivered to the fake SMTP server.
+type SMTPMessage struct {
+	From string
+	To   []string
+	Data []byte
+	// TLS is whether the message was sent after STARTTLS.
+	TLS bool
+	// Username is who authenticated, if anyone.
+	Username string
+}
+
+// Parse parses the message's headers and body.
+func (m *SMTPMessage) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+// SMTP is a fake SMTP server that offers STARTTLS with a self-signed
+// certificate and, if Username is set, requires AUTH PLAIN over TLS. Use Addr
+// as the SMTP address and ClientTLSConfig to trust its certificate.
+type SMTP struct {
+	Addr string
+
+	Username string
+	Password string
+	// DisableTLS stops the server offering STARTTLS.
+	DisableTLS bool
+
+	sync.Mutex
+	listener net.Listener
+	cert     tls.Certificate
+	pool     *x509.CertPool
+	messages []*SMTPMessage
+}
+
+func NewSMTP() *SMTP {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("hugstest: failed to listen: " + err.Error())
+	}
+
+	s := &SMTP{Addr: l.Addr().String(), listener: l}
+	s.cert, s.pool = selfSignedCert()
+
+	go s.serve()
+	return s
+}
+
+// ClientTLSConfig returns a TLS config that trusts the server's certificate.
+func (s *SMTP) ClientTLSConfig() *tls.Config {
+	return &tls.Config{RootCAs: s.pool, ServerName: "127.0.0.1"}
+}
+
+// Messages returns the messages delivered so far.
+func (s *SMTP) Messages() []*SMTPMessage {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]*SMTPMessage{}, s.messages...)
+}
+
+func (s *SMTP) Close() {
+	s.listener.Close()
+}
+
+func (s *SMTP) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+type smtpSession struct {
+	text     *textproto.Conn
+	tls      bool
+	username string
+	message  *SMTPMessage
+}
+
+func (s *SMTP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	session := &smtpSession{text: textproto.NewConn(conn)}
+	session.text.PrintfLine("220 hugstest ESMTP")
+
+	for {
+		line, err := session.text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			s.ehlo(session)
+		case "STARTTLS":
+			if s.DisableTLS || session.tls {
+				session.text.PrintfLine("502 STARTTLS not available")
+				continue
+			}
+			session.text.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			session = &smtpSession{text: textproto.NewConn(tlsConn), tls: true}
+		case "AUTH":
+			s.auth(session, arg)
+		case "MAIL":
+			if s.Username != "" && session.username == "" {
+				session.text.PrintfLine("530 Authentication required")
+				continue
+			}
+			from, ok := smtpPath(arg, "FROM:")
+			if !ok {
+				session.text.PrintfLine("501 Syntax error")
+				continue
+			}
+			session.message = &SMTPMessage{
+				From:     from,
+				TLS:      session.tls,
+				Username: session.username,
+			}
+			session.text.PrintfLine("250 OK")
+		case "RCPT":
+			if session.message == nil {
+				session.text.PrintfLine("503 Need MAIL first")
+				continue
+			}
+			to, ok := smtpPath(arg, "TO:")
+			if !ok || to == "" {
+				session.text.PrintfLine("501 Syntax error")
+				continue
+			}
+			session.message.To = append(session.message.To, to)
+			session.text.PrintfLine("250 OK")
+		case "DATA":
+			if session.message == nil || len(session.message.To) == 0 {
+				session.text.PrintfLine("503 Need RCPT first")
+				continue
+			}
+			session.text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := session.text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			session.message.Data = data
+
+			s.Lock()
+			s.messages = append(s.messages, session.message)
+			s.Unlock()
+
+			session.message = nil
+			session.text.PrintfLine("250 OK queued")
+		case "RSET":
+			session.message = nil
+			session.text.PrintfLine("250 OK")
+		case "NOOP":
+			session.text.PrintfLine("250 OK")
+		case "QUIT":
+			session.text.PrintfLine("221 Bye")
+			return
+		default:
+			session.text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *SMTP) ehlo(session *smtpSession) {
+	extensions := []string{"hugstest"}
+	if !s.DisableTLS && !session.tls {
+		extensions = append(extensions, "STARTTLS")
+	}
+	if s.Username != "" && session.tls {
+		extensions = append(extensions, "AUTH PLAIN")
+	}
+
+	for i, ext := range extensions {
+		sep := "-"
+		if i == len(extensions)-1 {
+			sep = " "
+		}
+		session.text.PrintfLine("250%s%s", sep, ext)
+	}
+}
+
+func (s *SMTP) auth(session *smtpSession, arg string) {
+	fields := strings.Fields(arg)
+	if !session.tls || len(fields) != 2 || strings.ToUpper(fields[0]) != "PLAIN" {
+		session.text.PrintfLine("504 Unrecognized authentication type")
+		return
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(fields[1])
+	parts := strings.Split(string(decoded), "\x00")
+	if err != nil || len(parts) != 3 || parts[1] != s.Username || parts[2] != s.Password {
+		session.text.PrintfLine("535 Authentication failed")
+		return
+	}
+
+	session.username = parts[1]
+	session.text.PrintfLine("235 Authentication succeeded")
+}
+
+// smtpPath returns the address in a MAIL FROM or RCPT TO argument, without
+// angle brackets or parameters.
+func smtpPath(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || strings.ToUpper(arg[:len(prefix)]) != prefix {
+		return "", false
+	}
+
+	path := strings.TrimSpace(arg[len(prefix):])
+	if i := strings.Index(path, ">"); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimPrefix(path, "<"), true
+}
+
+func selfSignedCert() (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic("hugstest: failed to generate key: " + err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"hugstest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic("hugstest: failed to create certificate: " + err.Error())
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic("hugstest: failed to parse certificate: " + err.Error())
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
@@ -0,0 +1,112 @@
+package hugstest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// SNSMessage is a message published to the fake SNS.
+type SNSMessage struct {
+	TopicArn   string
+	Message    string
+	Attributes map[string]string
+}
+
+// SNS emulates the SNS Publish action of the query API. Use its URL as the
+// SNS endpoint, with any static AWS credentials.
+type SNS struct {
+	*httptest.Server
+	recorder
+}
+
+func NewSNS() *SNS {
+	s := &SNS{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Messages returns the messages published so far.
+func (s *SNS) Messages() []*SNSMessage {
+	messages := []*SNSMessage{}
+	for _, r := range s.Requests("") {
+		if r.Form.Get("Action") == "Publish" {
+			messages = append(messages, snsMessage(r.Form))
+		}
+	}
+
+	return messages
+}
+
+// snsMessage reads a published message, with attributes encoded as
+// MessageAttributes.entry.N.Name and MessageAttributes.entry.N.Value.StringValue.
+func snsMessage(form url.Values) *SNSMessage {
+	message := &SNSMessage{
+		TopicArn:   form.Get("TopicArn"),
+		Message:    form.Get("Message"),
+		Attributes: map[string]string{},
+	}
+
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("MessageAttributes.entry.%d.", i)
+		name := form.Get(prefix + "Name")
+		if name == "" {
+			break
+		}
+		message.Attributes[name] = form.Get(prefix + "Value.StringValue")
+	}
+
+	return message
+}
+
+type snsError struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestId string   `xml:"RequestId"`
+}
+
+type snsPublishResponse struct {
+	XMLName   xml.Name `xml:"PublishResponse"`
+	MessageId string   `xml:"PublishResult>MessageId"`
+	RequestId string   `xml:"ResponseMetadata>RequestId"`
+}
+
+func (s *SNS) writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(v)
+}
+
+func (s *SNS) invalid(w http.ResponseWriter, status int, code, msg string) {
+	s.writeXML(w, status, &snsError{Type: "Sender", Code: code, Message: msg, RequestId: "hugstest"})
+}
+
+func (s *SNS) handle(w http.ResponseWriter, r *http.Request) {
+	req := s.record(r)
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		s.invalid(w, http.StatusForbidden, "MissingAuthenticationToken", "Request is missing Authentication Token")
+		return
+	}
+
+	if req.Form.Get("Action") != "Publish" {
+		s.invalid(w, http.StatusBadRequest, "InvalidAction", "Could not find operation "+req.Form.Get("Action"))
+		return
+	}
+
+	if req.Form.Get("TopicArn") == "" || req.Form.Get("Message") == "" {
+		s.invalid(w, http.StatusBadRequest, "InvalidParameter", "Invalid parameter: TopicArn or Message")
+		return
+	}
+
+	s.writeXML(w, http.StatusOK, &snsPublishResponse{
+		MessageId: fmt.Sprintf("hugstest-%d", len(s.Requests(""))),
+		RequestId: "hugstest",
+	})
+}
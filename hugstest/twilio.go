@@ -0,0 +1,83 @@
+package hugstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/opsee/hugs/obj"
+)
+
+var e164Number = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// Twilio emulates the Twilio Messages API. Use its URL as the TwilioAPIURL,
+// with AccountSid and AuthToken as credentials.
+type Twilio struct {
+	*httptest.Server
+	recorder
+
+	AccountSid string
+	AuthToken  string
+}
+
+func NewTwilio() *Twilio {
+	t := &Twilio{AccountSid: "ACtest", AuthToken: "test"}
+	t.Server = httptest.NewServer(http.HandlerFunc(t.createMessage))
+
+	return t
+}
+
+// Messages returns the text messages sent so far.
+func (t *Twilio) Messages() []*obj.TwilioMessageRequest {
+	messages := []*obj.TwilioMessageRequest{}
+	for _, r := range t.Requests(obj.TwilioMessagesPath(t.AccountSid)) {
+		messages = append(messages, &obj.TwilioMessageRequest{
+			To:   r.Form.Get("To"),
+			From: r.Form.Get("From"),
+			Body: r.Form.Get("Body"),
+		})
+	}
+
+	return messages
+}
+
+func (t *Twilio) invalid(w http.ResponseWriter, status, code int, msg string) {
+	writeJSON(w, status, &obj.TwilioMessage{
+		Code:    code,
+		Message: msg,
+	})
+}
+
+func (t *Twilio) createMessage(w http.ResponseWriter, r *http.Request) {
+	req := t.record(r)
+
+	sid, token, ok := r.BasicAuth()
+	if !ok || sid != t.AccountSid || token != t.AuthToken {
+		t.invalid(w, http.StatusUnauthorized, 20003, "Authenticate")
+		return
+	}
+
+	if r.Method != "POST" || req.Path != obj.TwilioMessagesPath(t.AccountSid) {
+		t.invalid(w, http.StatusNotFound, 20404, "The requested resource was not found")
+		return
+	}
+
+	to := req.Form.Get("To")
+	if !e164Number.MatchString(to) {
+		t.invalid(w, http.StatusBadRequest, 21211, "The 'To' number "+to+" is not a valid phone number.")
+		return
+	}
+
+	if req.Form.Get("Body") == "" {
+		t.invalid(w, http.StatusBadRequest, 21602, "Message body is required.")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, &obj.TwilioMessage{
+		Sid:    "SMtest",
+		Status: "queued",
+		To:     to,
+		From:   req.Form.Get("From"),
+		Body:   req.Form.Get("Body"),
+	})
+}
@@ -0,0 +1,26 @@
+package hugstest
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// Webhook is a sink for webhook notifications. It accepts anything posted to
+// it; use URL + any path as a webhook notification's value.
+type Webhook struct {
+	*httptest.Server
+	recorder
+
+	// Status is the response code returned for every request, 200 by default.
+	Status int
+}
+
+func NewWebhook() *Webhook {
+	h := &Webhook{Status: http.StatusOK}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.record(r)
+		w.WriteHeader(h.Status)
+	}))
+
+	return h
+}
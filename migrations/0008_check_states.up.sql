@@ -0,0 +1,8 @@
+create table check_states (
+  customer_id UUID not null,
+  check_id varchar(255) not null,
+  target_id varchar(255) not null default '',
+  passing boolean not null,
+  updated_at timestamp with time zone not null default now(),
+  primary key (customer_id, check_id, target_id)
+);
@@ -0,0 +1,15 @@
+create table notification_deliveries (
+  id serial primary key,
+  customer_id UUID not null,
+  notification_id int not null,
+  check_id varchar(255) not null,
+  type varchar(255) not null,
+  passing boolean not null,
+  status varchar(32) not null,
+  error text not null default '',
+  attempt int not null default 1,
+  latency_ms int not null default 0,
+  created_at timestamp with time zone not null default now()
+);
+
+create index idx_notification_deliveries_check on notification_deliveries(customer_id, check_id, created_at);
@@ -0,0 +1,12 @@
+create table dead_letters (
+  id serial primary key,
+  customer_id UUID not null,
+  check_id varchar(255) not null,
+  result bytea not null,
+  reasons jsonb not null,
+  attempts int not null,
+  created_at timestamp with time zone not null default now(),
+  replayed_at timestamp with time zone
+);
+
+create index idx_dead_letters_customer on dead_letters(customer_id, created_at);
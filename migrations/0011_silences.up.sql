@@ -0,0 +1,13 @@
+create table silences (
+  id serial primary key,
+  customer_id UUID not null,
+  user_id int not null,
+  check_id varchar(255) not null default '',
+  target_id varchar(255) not null default '',
+  reason text not null default '',
+  starts_at timestamp with time zone not null,
+  ends_at timestamp with time zone not null,
+  created_at timestamp with time zone not null default now()
+);
+
+create index idx_silences_customer on silences(customer_id, ends_at);
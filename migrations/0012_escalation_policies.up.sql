@@ -0,0 +1,8 @@
+create table escalation_policies (
+  customer_id UUID not null,
+  check_id varchar(255) not null,
+  steps jsonb not null default '[]',
+  created_at timestamp with time zone not null default now(),
+  updated_at timestamp with time zone not null default now(),
+  primary key (customer_id, check_id)
+);
@@ -0,0 +1,13 @@
+create table notification_thresholds (
+  customer_id UUID not null,
+  check_id varchar(255) not null default '',
+  consecutive int not null default 0,
+  duration int not null default 0,
+  primary key (customer_id, check_id)
+);
+
+alter table check_states add column observed boolean;
+update check_states set observed = passing;
+alter table check_states alter column observed set not null;
+alter table check_states add column consecutive int not null default 1;
+alter table check_states add column observed_since timestamp with time zone not null default now();
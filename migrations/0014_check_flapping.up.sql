@@ -0,0 +1,4 @@
+alter table check_states add column transitions jsonb not null default '[]';
+alter table check_states add column flapping boolean not null default false;
+alter table check_states add column flap_count int not null default 0;
+alter table check_states add column flapping_since timestamp with time zone;
@@ -0,0 +1,11 @@
+create table incidents (
+  id serial primary key,
+  customer_id UUID not null,
+  check_id varchar(255) not null,
+  opened_at timestamp with time zone not null default now(),
+  acknowledged_at timestamp with time zone,
+  acknowledged_by int,
+  resolved_at timestamp with time zone
+);
+
+create unique index idx_incidents_open on incidents(customer_id, check_id) where resolved_at is null;
@@ -0,0 +1,21 @@
+-- add_notification_type rebuilds notification_type with one more value.
+-- alter type ... add value can't run inside the transaction migrate wraps
+-- each migration in, so new notification types are added through this.
+-- Statements are executed dynamically so that no plan holds on to the
+-- dropped type.
+create function add_notification_type(value text) returns void as $$
+declare
+    labels text;
+begin
+    select string_agg(quote_literal(enumlabel), ', ' order by enumsortorder) into labels
+        from pg_enum where enumtypid = 'notification_type'::regtype;
+
+    execute format('create type notification_type_new as enum (%s, %L)', labels, value);
+    execute 'alter table notifications alter column type set data type notification_type_new using type::text::notification_type_new';
+    execute 'alter table default_notifications alter column type set data type notification_type_new using type::text::notification_type_new';
+    execute 'drop type notification_type';
+    execute 'alter type notification_type_new rename to notification_type';
+end;
+$$ language plpgsql;
+
+select add_notification_type('msteams');
@@ -0,0 +1,9 @@
+create table opsgenie_integrations (
+    id serial primary key,
+    customer_id UUID not null,
+    data jsonb not null
+);
+
+create index idx_opsgenie_integrations_customer on opsgenie_integrations(customer_id);
+
+select add_notification_type('opsgenie');
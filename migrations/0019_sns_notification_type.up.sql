@@ -0,0 +1 @@
+select add_notification_type('sns');
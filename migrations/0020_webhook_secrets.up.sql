@@ -0,0 +1,5 @@
+create table webhook_secrets (
+    customer_id UUID primary key,
+    secret text not null,
+    created_at timestamp with time zone not null default now()
+);
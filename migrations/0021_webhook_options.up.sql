@@ -0,0 +1,2 @@
+alter table notifications add column webhook jsonb;
+alter table default_notifications add column webhook jsonb;
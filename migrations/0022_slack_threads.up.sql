@@ -0,0 +1,9 @@
+create table slack_threads (
+  customer_id UUID not null,
+  check_id varchar(255) not null,
+  channel varchar(255) not null,
+  channel_id varchar(255) not null,
+  ts varchar(255) not null,
+  created_at timestamp with time zone not null default now(),
+  primary key (customer_id, check_id, channel)
+);
@@ -0,0 +1,5 @@
+delete from opsgenie_integrations a using opsgenie_integrations b
+  where a.customer_id = b.customer_id and a.id < b.id;
+
+drop index idx_opsgenie_integrations_customer;
+create unique index idx_opsgenie_integrations_customer on opsgenie_integrations(customer_id);
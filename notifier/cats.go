@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	opsee "github.com/opsee/basic/service"
+)
+
+// NewCatsClient dials cats. The connection is established lazily and is meant
+// to be shared by every sender.
+func NewCatsClient(address string) (opsee.CatsClient, error) {
+	catsConn, err := grpc.Dial(
+		address,
+		grpc.WithTransportCredentials(
+			credentials.NewTLS(&tls.Config{
+				InsecureSkipVerify: true,
+			}),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return opsee.NewCatsClient(catsConn), nil
+}
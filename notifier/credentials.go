@@ -0,0 +1,211 @@
+package notifier
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	log "github.com/opsee/logrus"
+)
+
+// Credentials looks up the integration credentials senders need for a
+// customer.
+type Credentials interface {
+	SlackBotToken(customerId string) (string, error)
+	PagerDutyIntegration(customerId string) (*obj.PagerDutyOAuthResponse, error)
+	OpsgenieIntegration(customerId string) (*obj.OpsgenieIntegration, error)
+	// WebhookSecret returns an empty secret, not an error, if the customer
+	// hasn't generated one, so their webhooks are sent unsigned.
+	WebhookSecret(customerId string) (string, error)
+}
+
+// StoreCredentials reads credentials from the store on every lookup.
+type StoreCredentials struct {
+	Store store.Store
+}
+
+func (c *StoreCredentials) SlackBotToken(customerId string) (string, error) {
+	oaResponse, err := c.Store.GetSlackOAuthResponse(&schema.User{CustomerId: customerId})
+	if err != nil {
+		return "", err
+	}
+
+	// if for whatever reason we don't have a bot
+	if oaResponse == nil || oaResponse.Bot == nil {
+		log.WithFields(log.Fields{"credentials": "SlackBotToken", "customer_id": customerId}).Error("User does not have a bot token associated with this slack integration.")
+		return "", fmt.Errorf("integration_inactive")
+	}
+
+	return oaResponse.Bot.BotAccessToken, nil
+}
+
+func (c *StoreCredentials) PagerDutyIntegration(customerId string) (*obj.PagerDutyOAuthResponse, error) {
+	oaResponse, err := c.Store.GetPagerDutyOAuthResponse(&schema.User{CustomerId: customerId})
+	if err != nil {
+		return nil, err
+	}
+	if oaResponse == nil {
+		return nil, fmt.Errorf("integration_inactive")
+	}
+	if oaResponse.Enabled == false {
+		return nil, fmt.Errorf("integration_disabled")
+	}
+
+	return oaResponse, nil
+}
+
+func (c *StoreCredentials) OpsgenieIntegration(customerId string) (*obj.OpsgenieIntegration, error) {
+	integration, err := c.Store.GetOpsgenieIntegration(&schema.User{CustomerId: customerId})
+	if err != nil {
+		return nil, err
+	}
+	if integration == nil {
+		return nil, fmt.Errorf("integration_inactive")
+	}
+	if integration.Enabled == false {
+		return nil, fmt.Errorf("integration_disabled")
+	}
+
+	return integration, nil
+}
+
+func (c *StoreCredentials) WebhookSecret(customerId string) (string, error) {
+	secret, err := c.Store.GetWebhookSecret(&schema.User{CustomerId: customerId})
+	if err != nil {
+		return "", err
+	}
+	if secret == nil {
+		return "", nil
+	}
+
+	return secret.Secret, nil
+}
+
+type cachedSlackToken struct {
+	token   string
+	expires time.Time
+}
+
+type cachedPagerDutyIntegration struct {
+	integration obj.PagerDutyOAuthResponse
+	expires     time.Time
+}
+
+type cachedOpsgenieIntegration struct {
+	integration obj.OpsgenieIntegration
+	expires     time.Time
+}
+
+// CredentialCache remembers successful lookups of another Credentials for TTL,
+// so a burst of alerts for a customer reads their credentials once. Failed
+// lookups are never cached, so a newly connected integration is picked up
+// right away. Call Invalidate when a customer's credentials change. That only
+// reaches this process's cache, other processes keep using the old
+// credentials until they expire, so keep TTL short.
+type CredentialCache struct {
+	Credentials Credentials
+	TTL         time.Duration
+
+	sync.Mutex
+	slack     map[string]*cachedSlackToken
+	pagerDuty map[string]*cachedPagerDutyIntegration
+	opsgenie  map[string]*cachedOpsgenieIntegration
+	now       func() time.Time
+}
+
+func NewCredentialCache(credentials Credentials, ttl time.Duration) *CredentialCache {
+	return &CredentialCache{
+		Credentials: credentials,
+		TTL:         ttl,
+		slack:       map[string]*cachedSlackToken{},
+		pagerDuty:   map[string]*cachedPagerDutyIntegration{},
+		opsgenie:    map[string]*cachedOpsgenieIntegration{},
+		now:         time.Now,
+	}
+}
+
+func (c *CredentialCache) SlackBotToken(customerId string) (string, error) {
+	c.Lock()
+	cached, ok := c.slack[customerId]
+	c.Unlock()
+	if ok && c.now().Before(cached.expires) {
+		return cached.token, nil
+	}
+
+	token, err := c.Credentials.SlackBotToken(customerId)
+	if err != nil {
+		return "", err
+	}
+
+	c.Lock()
+	c.slack[customerId] = &cachedSlackToken{token: token, expires: c.now().Add(c.TTL)}
+	c.Unlock()
+
+	return token, nil
+}
+
+// PagerDutyIntegration returns a copy of the cached integration so callers
+// can't modify the cache.
+func (c *CredentialCache) PagerDutyIntegration(customerId string) (*obj.PagerDutyOAuthResponse, error) {
+	c.Lock()
+	cached, ok := c.pagerDuty[customerId]
+	c.Unlock()
+	if ok && c.now().Before(cached.expires) {
+		integration := cached.integration
+		return &integration, nil
+	}
+
+	integration, err := c.Credentials.PagerDutyIntegration(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.pagerDuty[customerId] = &cachedPagerDutyIntegration{integration: *integration, expires: c.now().Add(c.TTL)}
+	c.Unlock()
+
+	return integration, nil
+}
+
+// OpsgenieIntegration returns a copy of the cached integration, like
+// PagerDutyIntegration.
+func (c *CredentialCache) OpsgenieIntegration(customerId string) (*obj.OpsgenieIntegration, error) {
+	c.Lock()
+	cached, ok := c.opsgenie[customerId]
+	c.Unlock()
+	if ok && c.now().Before(cached.expires) {
+		integration := cached.integration
+		return &integration, nil
+	}
+
+	integration, err := c.Credentials.OpsgenieIntegration(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.opsgenie[customerId] = &cachedOpsgenieIntegration{integration: *integration, expires: c.now().Add(c.TTL)}
+	c.Unlock()
+
+	return integration, nil
+}
+
+// WebhookSecret isn't cached. Secrets are rotated by the API, which can't
+// invalidate the caches of worker processes, and a receiver switching to the
+// new secret would reject webhooks still signed with a cached old one.
+func (c *CredentialCache) WebhookSecret(customerId string) (string, error) {
+	return c.Credentials.WebhookSecret(customerId)
+}
+
+// Invalidate forgets every cached credential for a customer.
+func (c *CredentialCache) Invalidate(customerId string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.slack, customerId)
+	delete(c.pagerDuty, customerId)
+	delete(c.opsgenie, customerId)
+}
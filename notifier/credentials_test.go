@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// counts lookups that reach the underlying credentials
+type countingCredentials struct {
+	Credentials
+	lookups int
+}
+
+func (c *countingCredentials) SlackBotToken(customerId string) (string, error) {
+	c.lookups++
+	return c.Credentials.SlackBotToken(customerId)
+}
+
+func (c *countingCredentials) PagerDutyIntegration(customerId string) (*obj.PagerDutyOAuthResponse, error) {
+	c.lookups++
+	return c.Credentials.PagerDutyIntegration(customerId)
+}
+
+func (c *countingCredentials) OpsgenieIntegration(customerId string) (*obj.OpsgenieIntegration, error) {
+	c.lookups++
+	return c.Credentials.OpsgenieIntegration(customerId)
+}
+
+func (c *countingCredentials) WebhookSecret(customerId string) (string, error) {
+	c.lookups++
+	return c.Credentials.WebhookSecret(customerId)
+}
+
+func TestStoreCredentials(t *testing.T) {
+	db := store.NewMemory()
+	credentials := &StoreCredentials{Store: db}
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+
+	_, err := credentials.SlackBotToken(user.CustomerId)
+	assert.EqualError(t, err, "integration_inactive")
+
+	db.PutSlackOAuthResponse(user, &obj.SlackOAuthResponse{AccessToken: "token"})
+	_, err = credentials.SlackBotToken(user.CustomerId)
+	assert.EqualError(t, err, "integration_inactive")
+
+	db.PutPagerDutyOAuthResponse(user, &obj.PagerDutyOAuthResponse{ServiceKey: "key"})
+	_, err = credentials.PagerDutyIntegration(user.CustomerId)
+	assert.EqualError(t, err, "integration_disabled")
+
+	_, err = credentials.OpsgenieIntegration(user.CustomerId)
+	assert.EqualError(t, err, "integration_inactive")
+
+	db.PutOpsgenieIntegration(user, &obj.OpsgenieIntegration{APIKey: "key"})
+	_, err = credentials.OpsgenieIntegration(user.CustomerId)
+	assert.EqualError(t, err, "integration_disabled")
+}
+
+func TestCredentialCache(t *testing.T) {
+	db := store.NewMemory()
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+	counting := &countingCredentials{Credentials: &StoreCredentials{Store: db}}
+
+	now := time.Now()
+	cache := NewCredentialCache(counting, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	// failed lookups aren't cached
+	_, err := cache.SlackBotToken(user.CustomerId)
+	assert.NotNil(t, err)
+
+	db.PutSlackOAuthResponse(user, &obj.SlackOAuthResponse{
+		AccessToken: "token",
+		Bot:         &obj.SlackBotCreds{BotAccessToken: "bot-token"},
+	})
+	for i := 0; i < 3; i++ {
+		token, err := cache.SlackBotToken(user.CustomerId)
+		assert.Nil(t, err)
+		assert.Equal(t, "bot-token", token)
+	}
+	assert.Equal(t, 2, counting.lookups)
+
+	db.PutSlackOAuthResponse(user, &obj.SlackOAuthResponse{
+		AccessToken: "token",
+		Bot:         &obj.SlackBotCreds{BotAccessToken: "new-bot-token"},
+	})
+	token, _ := cache.SlackBotToken(user.CustomerId)
+	assert.Equal(t, "bot-token", token)
+
+	cache.Invalidate(user.CustomerId)
+	token, _ = cache.SlackBotToken(user.CustomerId)
+	assert.Equal(t, "new-bot-token", token)
+	assert.Equal(t, 3, counting.lookups)
+
+	db.PutPagerDutyOAuthResponse(user, &obj.PagerDutyOAuthResponse{ServiceKey: "key", Enabled: true})
+	integration, err := cache.PagerDutyIntegration(user.CustomerId)
+	assert.Nil(t, err)
+	integration.ServiceKey = "modified"
+
+	integration, _ = cache.PagerDutyIntegration(user.CustomerId)
+	assert.Equal(t, "key", integration.ServiceKey)
+	assert.Equal(t, 4, counting.lookups)
+
+	db.PutOpsgenieIntegration(user, &obj.OpsgenieIntegration{APIKey: "key", Enabled: true})
+	opsgenie, err := cache.OpsgenieIntegration(user.CustomerId)
+	assert.Nil(t, err)
+	opsgenie.APIKey = "modified"
+
+	opsgenie, _ = cache.OpsgenieIntegration(user.CustomerId)
+	assert.Equal(t, "key", opsgenie.APIKey)
+	assert.Equal(t, 5, counting.lookups)
+
+	// everything expires after the ttl
+	now = now.Add(time.Minute)
+	cache.SlackBotToken(user.CustomerId)
+	cache.PagerDutyIntegration(user.CustomerId)
+	cache.OpsgenieIntegration(user.CustomerId)
+	assert.Equal(t, 8, counting.lookups)
+}
+
+func TestCredentialCacheWebhookSecret(t *testing.T) {
+	db := store.NewMemory()
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+	counting := &countingCredentials{Credentials: &StoreCredentials{Store: db}}
+	cache := NewCredentialCache(counting, time.Minute)
+
+	// no secret means unsigned webhooks
+	secret, err := cache.WebhookSecret(user.CustomerId)
+	assert.Nil(t, err)
+	assert.Equal(t, "", secret)
+
+	// rotations are picked up without invalidating the cache, as they are by
+	// other processes
+	for i := 0; i < 2; i++ {
+		generated := obj.NewWebhookSecret(user.CustomerId)
+		db.PutWebhookSecret(generated)
+
+		secret, err := cache.WebhookSecret(user.CustomerId)
+		assert.Nil(t, err)
+		assert.Equal(t, generated.Secret, secret)
+	}
+	assert.Equal(t, 3, counting.lookups)
+}
+
+func TestCredentialCacheErrors(t *testing.T) {
+	failing := &countingCredentials{Credentials: &StoreCredentials{Store: store.NewMemory()}}
+	cache := NewCredentialCache(failing, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.PagerDutyIntegration("customer")
+		assert.EqualError(t, err, "integration_inactive")
+	}
+	assert.Equal(t, 2, failing.lookups)
+}
@@ -1,13 +1,11 @@
 package notifier
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"net/mail"
+	"strings"
 
 	"golang.org/x/net/context"
 
@@ -19,9 +17,96 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// Mailer delivers a check email, rendering the named template with the
+// template content.
+type Mailer interface {
+	SendTemplate(to string, templateName string, templateContent map[string]interface{}) error
+}
+
+// MandrillMailer renders templates hosted by Mandrill. The flapping emails
+// have no hosted versions, they are rendered locally and sent as is.
+type MandrillMailer struct {
+	mailClient *mandrill.Client
+	from       *mail.Address
+	local      map[string]*emailTemplate
+}
+
+// mandrillLocalTemplates are the email templates Mandrill doesn't host.
+var mandrillLocalTemplates = []string{"check-flapping", "check-stable"}
+
+func (m *MandrillMailer) SendTemplate(to string, templateName string, templateContent map[string]interface{}) error {
+	if tmpl, ok := m.local[templateName]; ok {
+		return m.send(to, tmpl, templateContent)
+	}
+
+	message := &mandrill.Message{}
+	message.AddRecipient(to, to, "to")
+	message.Merge = true
+	message.MergeLanguage = "handlebars"
+	message.MergeVars = []*mandrill.RcptMergeVars{mandrill.MapToRecipientVars(to, templateContent)}
+
+	log.Debug(message)
+
+	_, err := m.mailClient.MessagesSendTemplate(message, templateName, templateContent)
+	return err
+}
+
+// send renders a local template and sends the result through Mandrill.
+func (m *MandrillMailer) send(to string, tmpl *emailTemplate, templateContent map[string]interface{}) error {
+	message := &mandrill.Message{
+		FromEmail: m.from.Address,
+		FromName:  m.from.Name,
+		Subject:   tmpl.subject.Render(templateContent),
+		HTML:      tmpl.html.Render(templateContent),
+		Text:      tmpl.text.Render(templateContent),
+	}
+	message.AddRecipient(to, to, "to")
+
+	log.Debug(message)
+
+	_, err := m.mailClient.MessagesSend(message)
+	return err
+}
+
+func NewMandrillMailer(mandrillKey, mandrillURL, from string) (*MandrillMailer, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Mandrill from address: %s", err)
+	}
+
+	sources := map[string]emailTemplateSource{}
+	for _, name := range mandrillLocalTemplates {
+		sources[name] = emailTemplates[name]
+	}
+	local, err := parseEmailTemplates(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	mailClient := mandrill.ClientWithKey(mandrillKey)
+	mailClient.BaseURL = mandrillURL
+
+	return &MandrillMailer{
+		mailClient: mailClient,
+		from:       address,
+		local:      local,
+	}, nil
+}
+
+// NewMailer returns the Mailer selected by the EmailTransport config.
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.EmailTransport {
+	case config.EmailTransportMandrill:
+		return NewMandrillMailer(cfg.MandrillApiKey, cfg.MandrillAPIURL, cfg.MandrillFrom)
+	case config.EmailTransportSMTP:
+		return NewSMTPMailer(cfg.SMTPAddress, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom, cfg.SMTPRequireTLS)
+	}
+	return nil, fmt.Errorf("Unknown email transport %s", cfg.EmailTransport)
+}
+
 type EmailSender struct {
 	opseeHost  string
-	mailClient *mandrill.Client
+	mailer     Mailer
 	catsClient opsee.CatsClient
 }
 
@@ -69,7 +154,8 @@ func (es EmailSender) Send(n *obj.Notification, e *obj.Event) error {
 		"instance_count": len(result.Responses),
 		"instances":      instances,
 		"fail_count":     result.FailingCount(),
-		"opsee_host":     config.GetConfig().OpseeHost,
+		"opsee_host":     es.opseeHost,
+		"check_url":      fmt.Sprintf("%s/check/%s", strings.TrimSuffix(es.opseeHost, "/"), result.CheckId),
 	}
 	log.WithFields(log.Fields{"template_content": templateContent}).Debug("Build template content")
 
@@ -139,36 +225,19 @@ func (es EmailSender) Send(n *obj.Notification, e *obj.Event) error {
 		templateContent["fail_count"] = failCount
 	}
 
-	mergeVars := templateContent
-	mergeVars["opsee_host"] = es.opseeHost
-	message := &mandrill.Message{}
-	message.AddRecipient(n.Value, n.Value, "to")
-	message.Merge = true
-	message.MergeLanguage = "handlebars"
-	message.MergeVars = []*mandrill.RcptMergeVars{mandrill.MapToRecipientVars(n.Value, mergeVars)}
-
-	log.Debug(message)
+	if e.Flapping != nil {
+		templateName = eventTemplateKey(e)
+		templateContent["transitions"] = e.Flapping.Transitions
+		templateContent["passing"] = result.Passing
+	}
 
-	_, err = es.mailClient.MessagesSendTemplate(message, templateName, templateContent)
-	return err
+	return es.mailer.SendTemplate(n.Value, templateName, templateContent)
 }
 
-func NewEmailSender(host string, mandrillKey string) (*EmailSender, error) {
-	catsConn, err := grpc.Dial(
-		"cats.in.opsee.com:443",
-		grpc.WithTransportCredentials(
-			credentials.NewTLS(&tls.Config{
-				InsecureSkipVerify: true,
-			}),
-		),
-	)
-	if err != nil {
-		return nil, err
-	}
-
+func NewEmailSender(catsClient opsee.CatsClient, host string, mailer Mailer) (*EmailSender, error) {
 	return &EmailSender{
 		opseeHost:  host,
-		mailClient: mandrill.ClientWithKey(mandrillKey),
-		catsClient: opsee.NewCatsClient(catsConn),
+		mailer:     mailer,
+		catsClient: catsClient,
 	}, nil
 }
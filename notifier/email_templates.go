@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"fmt"
+
+	"github.com/hoisie/mustache"
+)
+
+// Local versions of the check emails Mandrill hosts, for the SMTP mailer.
+// They're rendered with the same template content, keyed by the same names.
+// Mandrill has no hosted flapping emails, so the Mandrill mailer renders
+// those locally too.
+
+type emailTemplateSource struct {
+	subject string
+	html    string
+	text    string
+}
+
+type emailTemplate struct {
+	subject *mustache.Template
+	html    *mustache.Template
+	text    *mustache.Template
+}
+
+func parseEmailTemplates(sources map[string]emailTemplateSource) (map[string]*emailTemplate, error) {
+	templates := map[string]*emailTemplate{}
+	for name, source := range sources {
+		subject, err := mustache.ParseString(source.subject)
+		if err != nil {
+			return nil, err
+		}
+		html, err := mustache.ParseString(source.html)
+		if err != nil {
+			return nil, err
+		}
+		text, err := mustache.ParseString(source.text)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = &emailTemplate{subject: subject, html: html, text: text}
+	}
+	return templates, nil
+}
+
+const emailHTMLHeader = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #303030;">
+`
+
+const emailHTMLFooter = `<p><a href="{{check_url}}">View the check in Opsee</a>{{#json_url}} &middot; <a href="{{json_url}}">Check responses</a>{{/json_url}}</p>
+<p style="color: #9e9e9e; font-size: 12px;">You're receiving this because of a notification on your Opsee check.</p>
+</body>
+</html>
+`
+
+const emailTextFooter = `
+View the check in Opsee: {{{check_url}}}
+{{#json_url}}Check responses: {{{json_url}}}
+{{/json_url}}`
+
+// failingEmail is the failing check email, counting instances as noun.
+func failingEmail(noun string) emailTemplateSource {
+	return emailTemplateSource{
+		subject: `{{{check_name}}} failing in {{{group_name}}}`,
+		html: emailHTMLHeader + fmt.Sprintf(`<h2 style="color: #f44336;">Failing check</h2>
+<p><strong>{{check_name}}</strong> is failing in <strong>{{group_name}}</strong>{{#rds_db_name}} (database {{rds_db_name}}){{/rds_db_name}}.</p>
+<p>{{fail_count}} of {{instance_count}} %s failing.</p>
+<pre style="background: #f5f5f5; padding: 8px;">{{first_response}}</pre>
+`, noun) + emailHTMLFooter,
+		text: fmt.Sprintf(`Failing check
+
+{{{check_name}}} is failing in {{{group_name}}}{{#rds_db_name}} (database {{{rds_db_name}}}){{/rds_db_name}}.
+{{fail_count}} of {{instance_count}} %s failing.
+
+{{{first_response}}}
+`, noun) + emailTextFooter,
+	}
+}
+
+// passingEmail is the passing check email, counting instances as noun.
+func passingEmail(noun string) emailTemplateSource {
+	return emailTemplateSource{
+		subject: `{{{check_name}}} passing in {{{group_name}}}`,
+		html: emailHTMLHeader + fmt.Sprintf(`<h2 style="color: #69a92c;">Passing check</h2>
+<p><strong>{{check_name}}</strong> is passing again in <strong>{{group_name}}</strong>{{#rds_db_name}} (database {{rds_db_name}}){{/rds_db_name}}.</p>
+<p>{{instance_count}} %s passing.</p>
+`, noun) + emailHTMLFooter,
+		text: fmt.Sprintf(`Passing check
+
+{{{check_name}}} is passing again in {{{group_name}}}{{#rds_db_name}} (database {{{rds_db_name}}}){{/rds_db_name}}.
+{{instance_count}} %s passing.
+`, noun) + emailTextFooter,
+	}
+}
+
+var emailTemplates = map[string]emailTemplateSource{
+	"check-fail":      failingEmail("instances"),
+	"check-fail-json": failingEmail("instances"),
+	"check-fail-rds":  failingEmail("instances"),
+	"check-fail-url":  failingEmail("locations"),
+	"check-pass":      passingEmail("instances"),
+	"check-pass-json": passingEmail("instances"),
+	"check-pass-rds":  passingEmail("instances"),
+	"check-pass-url":  passingEmail("locations"),
+
+	"check-flapping": {
+		subject: `{{{check_name}}} flapping in {{{group_name}}}`,
+		html: emailHTMLHeader + `<h2 style="color: #ff9800;">Flapping check</h2>
+<p><strong>{{check_name}}</strong> is flapping in <strong>{{group_name}}</strong>.</p>
+<p>It changed state {{transitions}} times recently. Further notifications are paused until it is stable.</p>
+` + emailHTMLFooter,
+		text: `Flapping check
+
+{{{check_name}}} is flapping in {{{group_name}}}.
+It changed state {{transitions}} times recently. Further notifications are paused until it is stable.
+` + emailTextFooter,
+	},
+
+	"check-stable": {
+		subject: `{{{check_name}}} stopped flapping in {{{group_name}}}`,
+		html: emailHTMLHeader + `<h2>Check stopped flapping</h2>
+<p><strong>{{check_name}}</strong> is {{#passing}}passing{{/passing}}{{^passing}}failing{{/passing}} in <strong>{{group_name}}</strong> after changing state {{transitions}} times while flapping.</p>
+` + emailHTMLFooter,
+		text: `Check stopped flapping
+
+{{{check_name}}} is {{#passing}}passing{{/passing}}{{^passing}}failing{{/passing}} in {{{group_name}}} after changing state {{transitions}} times while flapping.
+` + emailTextFooter,
+	},
+}
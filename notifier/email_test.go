@@ -0,0 +1,162 @@
+package notifier
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailSender(t *testing.T) {
+	mandrill := hugstest.NewMandrill()
+	defer mandrill.Close()
+
+	sender, err := NewEmailSender(nil, "https://app.opsee.com", mustMandrillMailer(t, "test-key", mandrill.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{
+		CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+		UserId:     13,
+		CheckId:    "test",
+		Value:      "dan@opsee.com",
+		Type:       "email",
+	}
+
+	assert.Nil(t, sender.Send(notif, obj.GenerateFailingTestEvent()))
+	assert.Nil(t, sender.Send(notif, obj.GenerateTestEvent()))
+
+	messages := mandrill.Messages()
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "check-fail", messages[0].TemplateName)
+		assert.Equal(t, "check-pass-json", messages[1].TemplateName)
+		assert.Equal(t, "test-key", messages[0].Key)
+		assert.Equal(t, "dan@opsee.com", messages[0].Message.To[0].Email)
+	}
+
+	// Mandrill doesn't host flapping emails, they're rendered by us
+	flapping := obj.GenerateFailingTestEvent()
+	flapping.Flapping = &obj.Flapping{Transitions: 5}
+	assert.Nil(t, sender.Send(notif, flapping))
+	assert.Len(t, mandrill.Messages(), 2)
+	if sent := mandrill.Sent(); assert.Len(t, sent, 1) {
+		assert.Equal(t, "alerts@opsee.com", sent[0].Message.FromEmail)
+		assert.Equal(t, "dan@opsee.com", sent[0].Message.To[0].Email)
+		assert.Contains(t, sent[0].Message.Subject, "flapping")
+		assert.Contains(t, sent[0].Message.Text, "changed state 5 times")
+	}
+
+	mandrill.Key = "another-key"
+	assert.NotNil(t, sender.Send(notif, obj.GenerateTestEvent()))
+}
+
+func TestEmailSenderSMTP(t *testing.T) {
+	server := hugstest.NewSMTP()
+	defer server.Close()
+	server.Username = "hugs"
+	server.Password = "secret"
+
+	mailer, err := NewSMTPMailer(server.Addr, "hugs", "secret", "Opsee <alerts@opsee.com>", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer.TLSConfig = server.ClientTLSConfig()
+
+	sender, err := NewEmailSender(nil, "https://app.opsee.com/", mailer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: "test", Type: "email", Value: "dan@opsee.com"}
+	event := obj.GenerateFailingTestEvent()
+	assert.Nil(t, sender.Send(notif, event))
+
+	messages := server.Messages()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+	assert.True(t, messages[0].TLS)
+	assert.Equal(t, "hugs", messages[0].Username)
+	assert.Equal(t, "alerts@opsee.com", messages[0].From)
+	assert.Equal(t, []string{"dan@opsee.com"}, messages[0].To)
+
+	msg, err := messages[0].Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, msg.Header.Get("Subject"), "failing")
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	contentTypes := []string{}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(part)
+		assert.Contains(t, string(body), "https://app.opsee.com/check/"+event.Result.CheckId)
+	}
+	assert.Equal(t, []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}, contentTypes)
+
+	// wrong credentials and servers without STARTTLS are refused
+	mailer.Password = "wrong"
+	assert.NotNil(t, sender.Send(notif, event))
+
+	server.DisableTLS = true
+	mailer.Password = "secret"
+	err = sender.Send(notif, event)
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "STARTTLS"))
+	}
+	assert.Len(t, server.Messages(), 1)
+}
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	mailer, err := NewSMTPMailer(listener.Addr().String(), "", "", "alerts@opsee.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	assert.NotNil(t, mailer.SendTemplate("dan@opsee.com", "check-fail", map[string]interface{}{}))
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func mustMandrillMailer(t *testing.T, key, url string) *MandrillMailer {
+	mailer, err := NewMandrillMailer(key, url, config.DefaultMandrillFrom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mailer
+}
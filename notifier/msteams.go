@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hoisie/mustache"
+	opsee "github.com/opsee/basic/service"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// Teams card formats. Office 365 connector webhooks take MessageCards, while
+// webhooks created with the Workflows app only take Adaptive Cards.
+const (
+	msTeamsMessageCard  = "MessageCard"
+	msTeamsAdaptiveCard = "AdaptiveCard"
+)
+
+// MSTeamsSender posts cards to a Microsoft Teams incoming webhook. The
+// notification value is the webhook url the customer created in their channel,
+// so it is posted to through the same client as webhooks.
+type MSTeamsSender struct {
+	templates  map[string]map[string]*mustache.Template
+	catsClient opsee.CatsClient
+	client     *WebhookClient
+}
+
+// Send notification to customer.  At this point we have done basic validation on notification and event
+func (this *MSTeamsSender) Send(n *obj.Notification, e *obj.Event) error {
+	format := msTeamsCardFormat(n.Value)
+	key := eventTemplateKey(e)
+	teamsTemplate, ok := this.templates[format][key]
+	if !ok {
+		return fmt.Errorf("no %s template for %s", format, key)
+	}
+
+	templateContent, err := checkTemplateContent(this.catsClient, e)
+	if err != nil {
+		return err
+	}
+	if format == msTeamsAdaptiveCard {
+		escape := webhookEscaper("application/json")
+		for k, v := range templateContent {
+			if str, ok := v.(string); ok {
+				templateContent[k] = escape(str)
+			}
+		}
+	}
+
+	body := []byte(teamsTemplate.Render(templateContent))
+	card := map[string]interface{}{}
+	if err := json.Unmarshal(body, &card); err != nil {
+		log.WithFields(log.Fields{"msteams": "Send", "check_id": e.Result.CheckId, "error": err}).Error("Rendered invalid card.")
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.Value, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{"msteams": "Send", "error": err}).Error("Error sending notification to teams.")
+		return err
+	}
+	defer resp.Body.Close()
+
+	// teams answers with a plain text reason when it rejects a card
+	if resp.StatusCode >= 300 {
+		reason, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Teams returned status code %d: %s", resp.StatusCode, string(reason))
+	}
+
+	return nil
+}
+
+// msTeamsCardFormat returns the card format a webhook url takes. Workflows
+// webhooks, on logic.azure.com or powerplatform.com, post to a
+// .../workflows/<id>/... path, connector webhooks don't.
+func msTeamsCardFormat(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err == nil && strings.Contains(u.Path, "/workflows/") {
+		return msTeamsAdaptiveCard
+	}
+	return msTeamsMessageCard
+}
+
+func NewMSTeamsSender(catsClient opsee.CatsClient, client *WebhookClient) (*MSTeamsSender, error) {
+	templateMap := map[string]map[string]*mustache.Template{}
+	for format, templates := range map[string]map[string]string{
+		msTeamsMessageCard: {
+			"check-failing":  msTeamsCheckFailing,
+			"check-passing":  msTeamsCheckPassing,
+			"check-flapping": msTeamsCheckFlapping,
+			"check-stable":   msTeamsCheckStable,
+		},
+		msTeamsAdaptiveCard: {
+			"check-failing":  msTeamsAdaptiveCheckFailing,
+			"check-passing":  msTeamsAdaptiveCheckPassing,
+			"check-flapping": msTeamsAdaptiveCheckFlapping,
+			"check-stable":   msTeamsAdaptiveCheckStable,
+		},
+	} {
+		templateMap[format] = map[string]*mustache.Template{}
+		for key, tmpl := range templates {
+			template, err := mustache.ParseString(tmpl)
+			if err != nil {
+				return nil, err
+			}
+			templateMap[format][key] = template
+		}
+	}
+
+	return &MSTeamsSender{
+		templates:  templateMap,
+		catsClient: catsClient,
+		client:     client,
+	}, nil
+}
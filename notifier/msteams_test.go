@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMSTeamsSend(t *testing.T) {
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
+
+	sender, err := NewMSTeamsSender(nil, testWebhookClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: "test", Type: "msteams", Value: hook.URL + "/webhook"}
+	for _, event := range []*obj.Event{obj.GenerateFailingTestEvent(), obj.GenerateTestEvent()} {
+		assert.NoError(t, sender.Send(notif, event))
+	}
+
+	requests := hook.Requests("/webhook")
+	if assert.Len(t, requests, 2) {
+		failing := map[string]interface{}{}
+		assert.NoError(t, requests[0].Decode(&failing))
+		assert.Equal(t, "MessageCard", failing["@type"])
+		assert.Equal(t, "f44336", failing["themeColor"])
+
+		passing := map[string]interface{}{}
+		assert.NoError(t, requests[1].Decode(&passing))
+		assert.Equal(t, "69a92c", passing["themeColor"])
+	}
+
+	hook.Status = http.StatusBadRequest
+	assert.Error(t, sender.Send(notif, obj.GenerateTestEvent()))
+
+	// internal hosts can't be reached through a teams notification
+	client, err := NewWebhookClient(time.Second, 5*time.Second, config.DefaultWebhookDeniedNetworks, config.DefaultWebhookMaxRedirects, config.DefaultWebhookMaxResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err = NewMSTeamsSender(nil, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, sender.Send(notif, obj.GenerateTestEvent()))
+	assert.Len(t, hook.Requests("/webhook"), 3)
+}
+
+func TestMSTeamsSendAdaptiveCard(t *testing.T) {
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
+
+	sender, err := NewMSTeamsSender(nil, testWebhookClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// workflows webhooks only take adaptive cards
+	path := "/workflows/abc/triggers/manual/paths/invoke"
+	notif := &obj.Notification{CustomerId: "test", Type: "msteams", Value: hook.URL + path + "?api-version=2016-06-01"}
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CheckName = `a "quoted" \ check`
+	assert.NoError(t, sender.Send(notif, event))
+
+	requests := hook.Requests(path)
+	if assert.Len(t, requests, 1) {
+		message := struct {
+			Type        string `json:"type"`
+			Attachments []struct {
+				ContentType string `json:"contentType"`
+				Content     struct {
+					Type string `json:"type"`
+					Body []struct {
+						Text  string `json:"text"`
+						Color string `json:"color"`
+					} `json:"body"`
+				} `json:"content"`
+			} `json:"attachments"`
+		}{}
+		assert.NoError(t, requests[0].Decode(&message))
+		assert.Equal(t, "message", message.Type)
+		if assert.Len(t, message.Attachments, 1) {
+			card := message.Attachments[0]
+			assert.Equal(t, "application/vnd.microsoft.card.adaptive", card.ContentType)
+			assert.Equal(t, "AdaptiveCard", card.Content.Type)
+			if assert.Len(t, card.Content.Body, 3) {
+				assert.Equal(t, "Attention", card.Content.Body[0].Color)
+				assert.Contains(t, card.Content.Body[1].Text, event.Result.CheckName)
+			}
+		}
+	}
+
+	// a missing template is an error rather than a silently dropped card
+	delete(sender.templates[msTeamsAdaptiveCard], "check-failing")
+	assert.Error(t, sender.Send(notif, obj.GenerateFailingTestEvent()))
+	assert.Len(t, hook.Requests(path), 1)
+}
@@ -3,8 +3,10 @@ package notifier
 import (
 	"fmt"
 
+	opsee "github.com/opsee/basic/service"
 	"github.com/opsee/hugs/config"
 	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
 )
 
 // Interface implemented by everything that wants to send notifications
@@ -18,15 +20,51 @@ type Notifier struct {
 	Senders map[string]Sender
 }
 
+// Dependencies are shared by every sender, so that credentials are cached,
+// cats is dialed once and SMS are rate limited across workers of a process.
+type Dependencies struct {
+	Credentials  *CredentialCache
+	Cats         opsee.CatsClient
+	SMSLimiter   *SMSRateLimiter
+	Webhooks     *WebhookClient
+	SlackThreads SlackThreads
+}
+
+func NewDependencies(s store.Store) (*Dependencies, error) {
+	cfg := config.GetConfig()
+
+	catsClient, err := NewCatsClient(cfg.CatsAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := NewWebhookClient(cfg.WebhookConnectTimeout, cfg.WebhookTimeout, cfg.WebhookDeniedNetworks, cfg.WebhookMaxRedirects, int64(cfg.WebhookMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dependencies{
+		Credentials:  NewCredentialCache(&StoreCredentials{Store: s}, cfg.CredentialCacheTTL),
+		Cats:         catsClient,
+		SMSLimiter:   NewSMSRateLimiter(cfg.SMSRateLimit, cfg.SMSRateWindow),
+		Webhooks:     webhooks,
+		SlackThreads: s,
+	}, nil
+}
+
 // A collection of Senders, utilized by Workers to send notifications, return map of sender initialization errors to Warn on
-func NewNotifier() (*Notifier, map[string]error) {
+func NewNotifier(deps *Dependencies) (*Notifier, map[string]error) {
+	return newNotifier(config.GetConfig(), deps)
+}
+
+func newNotifier(cfg *config.Config, deps *Dependencies) (*Notifier, map[string]error) {
 	errMap := make(map[string]error)
 	notifier := &Notifier{
 		Senders: map[string]Sender{},
 	}
 
 	// try add slack webhook sender
-	webHookSender, err := NewWebHookSender()
+	webHookSender, err := NewWebHookSender(deps.Credentials, deps.Webhooks)
 	if err != nil {
 		errMap["webhook"] = err
 	} else {
@@ -34,29 +72,73 @@ func NewNotifier() (*Notifier, map[string]error) {
 	}
 
 	// try add slack bot sender
-	slackBotSender, err := NewSlackBotSender()
+	slackBotSender, err := NewSlackBotSender(deps.Credentials, deps.SlackThreads, deps.Cats, cfg.SlackAPIURL)
 	if err != nil {
 		errMap["slackbot"] = err
 	} else {
 		notifier.addSender("slack_bot", slackBotSender)
 	}
 
-	// try add pagerduty sender
-	emailSender, err := NewEmailSender(config.GetConfig().OpseeHost, config.GetConfig().MandrillApiKey)
+	// try add email sender
+	mailer, err := NewMailer(cfg)
 	if err != nil {
 		errMap["email"] = err
 	} else {
-		notifier.addSender("email", emailSender)
+		emailSender, err := NewEmailSender(deps.Cats, cfg.OpseeHost, mailer)
+		if err != nil {
+			errMap["email"] = err
+		} else {
+			notifier.addSender("email", emailSender)
+		}
 	}
 
 	// try add pagerduty sender
-	pagerDutySender, err := NewPagerDutySender()
+	pagerDutySender, err := NewPagerDutySender(deps.Credentials, cfg.PagerDutyEventsURL)
 	if err != nil {
 		errMap["pagerduty"] = err
 	} else {
 		notifier.addSender("pagerduty", pagerDutySender)
 	}
 
+	// try add opsgenie sender
+	opsgenieSender, err := NewOpsgenieSender(deps.Credentials, cfg.OpsgenieAPIURL)
+	if err != nil {
+		errMap["opsgenie"] = err
+	} else {
+		notifier.addSender("opsgenie", opsgenieSender)
+	}
+
+	// try add sms sender, only if twilio is configured
+	if cfg.TwilioAccountSid != "" || cfg.TwilioAuthToken != "" || cfg.TwilioFromNumber != "" {
+		twilioGateway, err := NewTwilioGateway(cfg.TwilioAccountSid, cfg.TwilioAuthToken, cfg.TwilioFromNumber, cfg.TwilioAPIURL)
+		if err != nil {
+			errMap["sms"] = err
+		} else {
+			smsSender, err := NewSMSSender(deps.Cats, twilioGateway, deps.SMSLimiter)
+			if err != nil {
+				errMap["sms"] = err
+			} else {
+				notifier.addSender("sms", smsSender)
+			}
+		}
+	}
+
+	// try add sns sender
+	snsSender, err := NewSNSSender(cfg.AWSSession, cfg.SNSEndpoint)
+	if err != nil {
+		errMap["sns"] = err
+	} else {
+		notifier.addSender("sns", snsSender)
+	}
+
+	// try add microsoft teams sender
+	msTeamsSender, err := NewMSTeamsSender(deps.Cats, deps.Webhooks)
+	if err != nil {
+		errMap["msteams"] = err
+	} else {
+		notifier.addSender("msteams", msTeamsSender)
+	}
+
 	return notifier, errMap
 }
 
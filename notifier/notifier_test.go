@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNotifierWithoutTwilio(t *testing.T) {
+	cfg := &config.Config{
+		EmailTransport: config.EmailTransportMandrill,
+		MandrillApiKey: "key",
+		MandrillFrom:   config.DefaultMandrillFrom,
+		AWSSession:     session.New(),
+	}
+
+	webhooks, err := NewWebhookClient(time.Second, 5*time.Second, nil, config.DefaultWebhookMaxRedirects, config.DefaultWebhookMaxResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := store.NewMemory()
+	deps := &Dependencies{
+		Credentials:  NewCredentialCache(&StoreCredentials{Store: db}, time.Minute),
+		SMSLimiter:   NewSMSRateLimiter(config.DefaultSMSRateLimit, config.DefaultSMSRateWindow),
+		Webhooks:     webhooks,
+		SlackThreads: db,
+	}
+
+	notifier, errMap := newNotifier(cfg, deps)
+	assert.Empty(t, errMap)
+	assert.NotContains(t, notifier.Senders, "sms")
+	assert.Contains(t, notifier.Senders, "email")
+
+	// a partial twilio config is still an error
+	cfg.TwilioAccountSid = "AC00000000000000000000000000000000"
+	_, errMap = newNotifier(cfg, deps)
+	assert.Contains(t, errMap, "sms")
+}
@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+const opsgenieSource = "Opsee"
+
+// OpsgenieSender opens an Opsgenie alert when a check fails and closes it when
+// the check passes again. Alerts are aliased by check id, so Opsgenie
+// deduplicates repeated failures and the passing result closes the right one.
+type OpsgenieSender struct {
+	credentials Credentials
+	apiURL      string
+}
+
+// Send notification to customer.  At this point we have done basic validation on notification and event
+func (this OpsgenieSender) Send(n *obj.Notification, e *obj.Event) error {
+	result := e.Result
+
+	integration, err := this.credentials.OpsgenieIntegration(n.CustomerId)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case e.Acknowledged:
+		return this.action(integration.APIKey, result.CheckId, "acknowledge", "Acknowledged in Opsee")
+	case e.Flapping != nil && !e.Flapping.Stable:
+	case result.Passing:
+		return this.action(integration.APIKey, result.CheckId, "close", "Check passing")
+	default:
+		if len(result.FailingResponses()) < 1 {
+			return errors.New("Received failing CheckResult with no failing responses.")
+		}
+	}
+
+	response, err := obj.OpsgenieDo(integration.APIKey, this.apiURL+obj.OpsgenieAlertsPath, NewOpsgenieAlertRequest(e))
+	log.Debug(response)
+	return err
+}
+
+// action closes or acknowledges the alert for a check.
+func (this OpsgenieSender) action(apiKey, checkId, action, note string) error {
+	request := &obj.OpsgenieActionRequest{
+		Source: opsgenieSource,
+		Note:   note,
+	}
+
+	response, err := obj.OpsgenieDo(apiKey, this.apiURL+obj.OpsgenieAlertPath(checkId, action)+"?identifierType=alias", request)
+	log.Debug(response)
+	return err
+}
+
+// NewOpsgenieAlertRequest returns the alert to open for a failing or flapping
+// check.
+func NewOpsgenieAlertRequest(e *obj.Event) *obj.OpsgenieAlertRequest {
+	result := e.Result
+	checkURL := fmt.Sprintf("https://%s/check/%s", pagerDutyOpseeHost, result.CheckId)
+
+	var group, targetType string
+	if result.Target != nil {
+		group = result.Target.Id
+		if result.Target.Name != "" {
+			group = result.Target.Name
+		}
+		targetType = result.Target.Type
+	}
+
+	request := &obj.OpsgenieAlertRequest{
+		Message:     fmt.Sprintf("%s failing in %s", result.CheckName, group),
+		Alias:       result.CheckId,
+		Description: fmt.Sprintf("%d of %d targets failing.", len(result.FailingResponses()), len(result.Responses)),
+		Source:      opsgenieSource,
+		Entity:      group,
+		Tags:        []string{"opsee"},
+		Priority:    "P1",
+		Details: map[string]string{
+			"check_id":  result.CheckId,
+			"check_url": checkURL,
+		},
+	}
+
+	if e.Flapping != nil && !e.Flapping.Stable {
+		request.Message = fmt.Sprintf("%s flapping in %s", result.CheckName, group)
+		request.Description = fmt.Sprintf("Changed state %d times recently. Further notifications are paused until it is stable.", e.Flapping.Transitions)
+		request.Priority = "P3"
+	}
+
+	if targetType != "" {
+		request.Tags = append(request.Tags, targetType)
+	}
+
+	if e.Nocap != nil && e.Nocap.JSONUrl != "" {
+		request.Details["responses_url"] = e.Nocap.JSONUrl
+	}
+
+	if len(request.Message) > obj.OpsgenieMessageLimit {
+		request.Message = request.Message[:obj.OpsgenieMessageLimit]
+	}
+
+	return request
+}
+
+func NewOpsgenieSender(credentials Credentials, apiURL string) (*OpsgenieSender, error) {
+	return &OpsgenieSender{
+		credentials: credentials,
+		apiURL:      apiURL,
+	}, nil
+}
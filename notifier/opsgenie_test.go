@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpsgenieSenderLifecycle(t *testing.T) {
+	og := hugstest.NewOpsgenie()
+	defer og.Close()
+
+	db := store.NewMemory()
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+	db.PutOpsgenieIntegration(user, &obj.OpsgenieIntegration{APIKey: og.Key, Enabled: true})
+
+	sender, err := NewOpsgenieSender(&StoreCredentials{Store: db}, og.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notif := &obj.Notification{CustomerId: user.CustomerId, Type: "opsgenie", Value: "opsgenie"}
+
+	failing := obj.GenerateFailingTestEvent()
+	assert.Nil(t, sender.Send(notif, failing))
+
+	acknowledged := obj.GenerateFailingTestEvent()
+	acknowledged.Acknowledged = true
+	assert.Nil(t, sender.Send(notif, acknowledged))
+
+	passing := obj.GenerateTestEvent()
+	passing.Result.CheckId = failing.Result.CheckId
+	assert.Nil(t, sender.Send(notif, passing))
+
+	alerts := og.Alerts()
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, failing.Result.CheckId, alerts[0].Alias)
+		assert.Equal(t, "P1", alerts[0].Priority)
+	}
+	assert.Equal(t, []string{failing.Result.CheckId}, og.Actions("acknowledge"))
+	assert.Equal(t, []string{failing.Result.CheckId}, og.Actions("close"))
+
+	// a bad key is reported, a missing integration is never sent
+	og.Key = "other"
+	assert.NotNil(t, sender.Send(notif, failing))
+
+	og.Reset()
+	notif.CustomerId = "11111111-1111-1111-1111-111111111111"
+	assert.EqualError(t, sender.Send(notif, failing), "integration_inactive")
+	assert.Empty(t, og.Requests(""))
+}
+
+func TestOpsgenieAlertRequest(t *testing.T) {
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CheckName = strings.Repeat("a", 200)
+	request := NewOpsgenieAlertRequest(event)
+	assert.Len(t, request.Message, obj.OpsgenieMessageLimit)
+	assert.Equal(t, event.Result.CheckId, request.Details["check_id"])
+
+	event.Flapping = &obj.Flapping{Transitions: 6}
+	request = NewOpsgenieAlertRequest(event)
+	assert.Equal(t, "P3", request.Priority)
+	assert.Contains(t, request.Description, "6 times")
+
+	event.Flapping.Stable = true
+	assert.Equal(t, "P1", NewOpsgenieAlertRequest(event).Priority)
+}
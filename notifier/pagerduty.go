@@ -5,23 +5,40 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"sort"
+	"time"
 
 	"github.com/hoisie/mustache"
-	"github.com/opsee/basic/schema"
 	"github.com/opsee/hugs/obj"
-	"github.com/opsee/hugs/store"
 	log "github.com/opsee/logrus"
 	pdtmpl "github.com/opsee/notification-templates/dist/go/pagerduty"
 )
 
+const pagerDutyOpseeHost = "app.opsee.com"
+
 type PagerDutySender struct {
-	templates map[string]*mustache.Template
+	templates   map[string]*mustache.Template
+	credentials Credentials
+	eventsURL   string
 }
 
 // Send notification to customer.  At this point we have done basic validation on notification and event
 func (this PagerDutySender) Send(n *obj.Notification, e *obj.Event) error {
 	result := e.Result
 
+	integration, err := this.credentials.PagerDutyIntegration(n.CustomerId)
+	if err != nil {
+		return err
+	}
+
+	if integration.Version() == obj.PagerDutyAPIVersion2 {
+		return this.sendV2(integration.ServiceKey, e)
+	}
+
+	if e.Acknowledged {
+		return this.acknowledge(integration.ServiceKey, e)
+	}
+
 	templateKey := "check-passing"
 	eventType := "resolve"
 	if !result.Passing {
@@ -29,6 +46,12 @@ func (this PagerDutySender) Send(n *obj.Notification, e *obj.Event) error {
 		templateKey = "check-failing"
 	}
 
+	// a stable check resolves or triggers as usual, a flapping one triggers
+	if e.Flapping != nil && !e.Flapping.Stable {
+		eventType = "trigger"
+		templateKey = "check-flapping"
+	}
+
 	failingResponses := result.FailingResponses()
 
 	if len(failingResponses) < 1 && !result.Passing {
@@ -39,11 +62,7 @@ func (this PagerDutySender) Send(n *obj.Notification, e *obj.Event) error {
 		return fmt.Errorf("Template key not found")
 	}
 	pdTemplate := this.templates[templateKey]
-
-	serviceKey, err := this.getPagerDutyServiceKey(n)
-	if err != nil {
-		return err
-	}
+	serviceKey := integration.ServiceKey
 
 	postMessageRequest := &obj.PagerDutyRequest{}
 	switch eventType {
@@ -65,7 +84,7 @@ func (this PagerDutySender) Send(n *obj.Notification, e *obj.Event) error {
 			"check_name":  result.CheckName,
 			"check_id":    result.CheckId,
 			"group_name":  result.Target.Id,
-			"opsee_host":  "app.opsee.com",
+			"opsee_host":  pagerDutyOpseeHost,
 		}
 
 		if e.Nocap != nil {
@@ -88,29 +107,119 @@ func (this PagerDutySender) Send(n *obj.Notification, e *obj.Event) error {
 
 	}
 
-	response, err := postMessageRequest.Do()
+	response, err := postMessageRequest.Do(this.eventsURL + obj.PagerDutyEventsV1Path)
 	log.Debug(response)
 	return err
 }
 
-func (this PagerDutySender) getPagerDutyServiceKey(n *obj.Notification) (string, error) {
-	s, err := store.NewPostgres()
-	if err != nil {
-		return "", err
+// acknowledge tells PagerDuty someone is handling the check's incident.
+func (this PagerDutySender) acknowledge(serviceKey string, e *obj.Event) error {
+	postMessageRequest := &obj.PagerDutyRequest{
+		ServiceKey:  serviceKey,
+		EventType:   "acknowledge",
+		IncidentKey: e.Result.CheckId,
+		Description: "Acknowledged in Opsee",
 	}
 
-	oaResponse, err := s.GetPagerDutyOAuthResponse(&schema.User{CustomerId: n.CustomerId})
-	if err != nil {
-		return "", err
+	response, err := postMessageRequest.Do(this.eventsURL + obj.PagerDutyEventsV1Path)
+	log.Debug(response)
+	return err
+}
+
+// sendV2 notifies the customer through the Events API v2.
+func (this PagerDutySender) sendV2(routingKey string, e *obj.Event) error {
+	if !e.Acknowledged && !e.Result.Passing && len(e.Result.FailingResponses()) < 1 {
+		return errors.New("Received failing CheckResult with no failing responses.")
 	}
-	if oaResponse.Enabled == false {
-		return "", fmt.Errorf("integration_disabled")
+
+	postMessageRequest := NewPagerDutyV2Request(routingKey, e)
+	response, err := postMessageRequest.Do(this.eventsURL + obj.PagerDutyEventsV2Path)
+	log.Debug(response)
+	return err
+}
+
+// NewPagerDutyV2Request returns the Events API v2 event for an Event. Events
+// are deduplicated by check, like v1 incident keys, so an incident opened
+// through v1 is resolved through v2.
+func NewPagerDutyV2Request(routingKey string, e *obj.Event) *obj.PagerDutyV2Request {
+	result := e.Result
+	checkURL := fmt.Sprintf("https://%s/check/%s", pagerDutyOpseeHost, result.CheckId)
+
+	request := &obj.PagerDutyV2Request{
+		RoutingKey: routingKey,
+		DedupKey:   result.CheckId,
+	}
+
+	switch {
+	case e.Acknowledged:
+		request.EventAction = "acknowledge"
+		return request
+	case e.Flapping != nil && !e.Flapping.Stable:
+		request.EventAction = "trigger"
+	case result.Passing:
+		request.EventAction = "resolve"
+		return request
+	default:
+		request.EventAction = "trigger"
+	}
+
+	var group, targetType string
+	if result.Target != nil {
+		group = result.Target.Id
+		if result.Target.Name != "" {
+			group = result.Target.Name
+		}
+		targetType = result.Target.Type
+	}
+
+	payload := &obj.PagerDutyV2Payload{
+		Summary:       fmt.Sprintf("%s failure in %s", result.CheckName, group),
+		Source:        group,
+		Severity:      "critical",
+		Component:     result.CheckName,
+		Group:         targetType,
+		Class:         "check",
+		CustomDetails: result,
+	}
+	if e.Flapping != nil {
+		payload.Summary = fmt.Sprintf("%s flapping in %s", result.CheckName, group)
+		payload.Severity = "warning"
+	}
+	if result.Timestamp != nil {
+		payload.Timestamp = time.Unix(result.Timestamp.Seconds, int64(result.Timestamp.Nanos)).UTC().Format(time.RFC3339)
 	}
 
-	return oaResponse.ServiceKey, nil
+	request.Payload = payload
+	request.Client = "Opsee"
+	request.ClientURL = checkURL
+	request.Links = []*obj.PagerDutyV2Link{
+		{Href: checkURL, Text: "View check in Opsee"},
+	}
+
+	if e.Nocap != nil {
+		if e.Nocap.JSONUrl != "" {
+			request.Links = append(request.Links, &obj.PagerDutyV2Link{Href: e.Nocap.JSONUrl, Text: "Check responses"})
+		}
+
+		names := make([]string, 0, len(e.Nocap.Images))
+		for name := range e.Nocap.Images {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			request.Images = append(request.Images, &obj.PagerDutyV2Image{
+				Src:  e.Nocap.Images[name],
+				Href: checkURL,
+				Alt:  name,
+			})
+		}
+	}
+
+	return request
 }
 
-func NewPagerDutySender() (*PagerDutySender, error) {
+func NewPagerDutySender(credentials Credentials, eventsURL string) (*PagerDutySender, error) {
 	// initialize check failing template
 	failTemplate, err := mustache.ParseString(pdtmpl.CheckFailing)
 	if err != nil {
@@ -123,12 +232,20 @@ func NewPagerDutySender() (*PagerDutySender, error) {
 		return nil, err
 	}
 
+	flappingTemplate, err := mustache.ParseString(pagerDutyCheckFlapping)
+	if err != nil {
+		return nil, err
+	}
+
 	templateMap := map[string]*mustache.Template{
-		"check-failing": failTemplate,
-		"check-passing": passTemplate,
+		"check-failing":  failTemplate,
+		"check-passing":  passTemplate,
+		"check-flapping": flappingTemplate,
 	}
 
 	return &PagerDutySender{
-		templates: templateMap,
+		templates:   templateMap,
+		credentials: credentials,
+		eventsURL:   eventsURL,
 	}, nil
 }
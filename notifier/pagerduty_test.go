@@ -0,0 +1,157 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerDutyV2RequestTrigger(t *testing.T) {
+	event := obj.GenerateFailingTestEvent()
+	event.Nocap = &obj.NocapResponse{
+		Images:  map[string]string{"default": "https://example.com/default.png"},
+		JSONUrl: "https://example.com/responses.json",
+	}
+
+	request := NewPagerDutyV2Request("routing-key", event)
+	assert.Nil(t, request.Validate())
+	assert.Equal(t, "trigger", request.EventAction)
+	assert.Equal(t, "routing-key", request.RoutingKey)
+	assert.Equal(t, event.Result.CheckId, request.DedupKey)
+
+	if assert.NotNil(t, request.Payload) {
+		assert.Equal(t, "critical", request.Payload.Severity)
+		assert.Equal(t, "Test Target", request.Payload.Source)
+		assert.Equal(t, "Test Check failure in Test Target", request.Payload.Summary)
+		assert.Equal(t, event.Result, request.Payload.CustomDetails)
+	}
+
+	assert.Len(t, request.Links, 2)
+	if assert.Len(t, request.Images, 1) {
+		assert.Equal(t, "https://example.com/default.png", request.Images[0].Src)
+	}
+}
+
+func TestPagerDutyV2RequestFlapping(t *testing.T) {
+	event := obj.GenerateTestEvent()
+	event.Flapping = &obj.Flapping{Transitions: 6}
+
+	request := NewPagerDutyV2Request("routing-key", event)
+	assert.Equal(t, "trigger", request.EventAction)
+	assert.Equal(t, "warning", request.Payload.Severity)
+}
+
+func TestPagerDutyV2RequestResolveAndAcknowledge(t *testing.T) {
+	event := obj.GenerateTestEvent()
+
+	request := NewPagerDutyV2Request("routing-key", event)
+	assert.Equal(t, "resolve", request.EventAction)
+	assert.Nil(t, request.Payload)
+
+	event = obj.GenerateFailingTestEvent()
+	event.Acknowledged = true
+
+	request = NewPagerDutyV2Request("routing-key", event)
+	assert.Equal(t, "acknowledge", request.EventAction)
+	assert.Equal(t, event.Result.CheckId, request.DedupKey)
+	assert.Nil(t, request.Payload)
+}
+
+func TestPagerDutyIntegrationVersion(t *testing.T) {
+	integration := &obj.PagerDutyOAuthResponse{Account: "opsee"}
+	assert.Equal(t, obj.PagerDutyAPIVersion1, integration.Version())
+	assert.Nil(t, integration.Validate())
+
+	integration.APIVersion = obj.PagerDutyAPIVersion2
+	assert.Equal(t, obj.PagerDutyAPIVersion2, integration.Version())
+	assert.Nil(t, integration.Validate())
+
+	integration.APIVersion = "v3"
+	assert.NotNil(t, integration.Validate())
+}
+
+func TestPagerDutySenderV2(t *testing.T) {
+	pd := hugstest.NewPagerDuty()
+	defer pd.Close()
+
+	sender, err := NewPagerDutySender(&StoreCredentials{Store: store.NewMemory()}, pd.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := obj.GenerateFailingTestEvent()
+	assert.Nil(t, sender.sendV2("routing-key", event))
+
+	event.Acknowledged = true
+	assert.Nil(t, sender.sendV2("routing-key", event))
+
+	events := pd.V2Events()
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "trigger", events[0].EventAction)
+		assert.Equal(t, "acknowledge", events[1].EventAction)
+		assert.Equal(t, event.Result.CheckId, events[1].DedupKey)
+	}
+
+	assert.NotNil(t, sender.sendV2("", event))
+}
+
+func TestPagerDutySenderAcknowledge(t *testing.T) {
+	pd := hugstest.NewPagerDuty()
+	defer pd.Close()
+
+	sender, err := NewPagerDutySender(&StoreCredentials{Store: store.NewMemory()}, pd.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := obj.GenerateFailingTestEvent()
+	event.Acknowledged = true
+	assert.Nil(t, sender.acknowledge("service-key", event))
+
+	events := pd.Events()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "acknowledge", events[0].EventType)
+		assert.Equal(t, "service-key", events[0].ServiceKey)
+		assert.Equal(t, event.Result.CheckId, events[0].IncidentKey)
+	}
+}
+
+func TestPagerDutySenderSend(t *testing.T) {
+	pd := hugstest.NewPagerDuty()
+	defer pd.Close()
+
+	db := store.NewMemory()
+	sender, err := NewPagerDutySender(&StoreCredentials{Store: db}, pd.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{
+		CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+		CheckId:    "00002",
+		Type:       "pagerduty",
+	}
+	event := obj.GenerateFailingTestEvent()
+
+	assert.EqualError(t, sender.Send(notif, event), "integration_inactive")
+
+	user := &schema.User{CustomerId: notif.CustomerId}
+	err = db.PutPagerDutyOAuthResponse(user, &obj.PagerDutyOAuthResponse{
+		ServiceKey: "routing-key",
+		Enabled:    true,
+		APIVersion: obj.PagerDutyAPIVersion2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, sender.Send(notif, event))
+	if events := pd.V2Events(); assert.Len(t, events, 1) {
+		assert.Equal(t, "routing-key", events[0].RoutingKey)
+		assert.Equal(t, "trigger", events[0].EventAction)
+	}
+}
@@ -1,114 +1,89 @@
 package notifier
 
 import (
-	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"net/url"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-
-	"golang.org/x/net/context"
+	"time"
 
 	"github.com/hoisie/mustache"
-	"github.com/opsee/basic/schema"
 	opsee "github.com/opsee/basic/service"
 	"github.com/opsee/hugs/obj"
-	"github.com/opsee/hugs/store"
 	log "github.com/opsee/logrus"
 	slacktmpl "github.com/opsee/notification-templates/dist/go/slack"
 )
 
+// SlackThreads remembers the message a check's open failure was posted as in
+// each channel, see obj.SlackThread.
+type SlackThreads interface {
+	GetSlackThread(customerId, checkId, channel string) (*obj.SlackThread, error)
+	PutSlackThread(*obj.SlackThread) error
+	DeleteSlackThread(customerId, checkId, channel string) error
+}
+
+// SlackBotSender posts a check's first failure as a new message, and later
+// results as replies in its thread until the check recovers. The original
+// message is then updated to show the check passing. Expired threads, and
+// threads that can't be replied to, are replaced by a new message.
 type SlackBotSender struct {
-	templates  map[string]*mustache.Template
-	catsClient opsee.CatsClient
+	templates   map[string]*mustache.Template
+	catsClient  opsee.CatsClient
+	credentials Credentials
+	threads     SlackThreads
+	apiURL      string
 }
 
 // Send notification to customer.  At this point we have done basic validation on notification and event
 func (this SlackBotSender) Send(n *obj.Notification, e *obj.Event) error {
-	result := e.Result
-
-	templateKey := "check-passing"
-	if !result.Passing {
-		templateKey = "check-failing"
-	}
-
-	// Bleh. This is copypasta from email.go
-	// TODO(greg): When we move to a generic model, we can figure out a way
-	// to centralize all of this logic so that senders can finally be dumb.
-	failingResponses := result.FailingResponses()
-
-	// It's a possible error state that if the CheckResult.Passing field is false,
-	// i.e. this is a failing event, that there are somehow no constituent failing
-	// CheckResponse objects contained within the CheckResult. We cannot know _why_
-	// these CheckResponse objects aren't failing. Because we cannot ordain the reason
-	// for this error state, let us first err on the side of not bugging a customer.
-	if len(failingResponses) < 1 && !result.Passing {
-		return errors.New("Received failing CheckResult with no failing responses.")
-	}
+	templateKey := eventTemplateKey(e)
 
 	if slackTemplate, ok := this.templates[templateKey]; ok {
-		token, err := this.getSlackToken(n)
+		templateContent, err := checkTemplateContent(this.catsClient, e)
 		if err != nil {
 			return err
 		}
 
-		templateContent := map[string]interface{}{
-			"check_id":       result.CheckId,
-			"check_name":     result.CheckName,
-			"group_name":     result.Target.Id,
-			"instance_count": len(result.Responses),
-			"fail_count":     len(failingResponses),
-			"type":           "target",
-			"token":          token,
-			"channel":        n.Value,
+		token, err := this.credentials.SlackBotToken(n.CustomerId)
+		if err != nil {
+			return err
 		}
+		templateContent["token"] = token
+		templateContent["channel"] = n.Value
 
-		if e.Nocap != nil && e.Nocap.JSONUrl != "" {
-			templateContent["json_url"] = fmt.Sprintf("/event?json=%s&", url.QueryEscape(e.Nocap.JSONUrl))
-		} else {
-			templateContent["json_url"] = "?"
+		postMessageRequest := &obj.SlackPostChatMessageRequest{}
+		log.Debug(string(slackTemplate.Render(templateContent)))
+		err = json.Unmarshal([]byte(slackTemplate.Render(templateContent)), postMessageRequest)
+		if err != nil {
+			return err
 		}
 
-		if result.Target.Type == "external_host" {
-			catsResponse, err := this.catsClient.GetCheckResults(context.Background(), &opsee.GetCheckResultsRequest{
-				CheckId:    result.CheckId,
-				CustomerId: result.CustomerId,
-			})
+		// test notifications stay out of real threads
+		var thread *obj.SlackThread
+		if !e.Test {
+			thread, err = this.threads.GetSlackThread(n.CustomerId, e.Result.CheckId, n.Value)
 			if err != nil {
 				return err
 			}
-			results := catsResponse.Results
-
-			var (
-				instanceCount = len(results)
-				failCount     int
-			)
-
-			for _, r := range results {
-				failCount += r.FailingCount()
-			}
+		}
+		if thread != nil && thread.Expired(time.Now()) {
+			this.closeThread(thread)
+			thread = nil
+		}
+		if thread != nil {
+			postMessageRequest.ThreadTs = thread.Ts
+		}
 
-			// we have inconsistent results, so don't do anything
-			if !result.Passing && failCount == 0 {
-				return fmt.Errorf("Failing result, but fail count == 0")
-			}
+		slackPostMessageResponse, err := postMessageRequest.Do(this.apiURL + "/chat.postMessage")
 
-			templateContent["instance_count"] = instanceCount
-			templateContent["fail_count"] = failCount
-			templateContent["type"] = "points-of-presence (PoPs)"
-		}
+		// the thread's message is gone, e.g. deleted, so post it on its own
+		// instead. Other errors are retried with the thread kept.
+		if err != nil && slackPostMessageResponse != nil && thread != nil && slackThreadGone(slackPostMessageResponse.Error) {
+			log.WithFields(log.Fields{"slackbot": "Send", "check_id": thread.CheckId, "error": err}).Warn("Couldn't reply in slack thread, starting a new one.")
+			this.closeThread(thread)
+			thread = nil
+			postMessageRequest.ThreadTs = ""
 
-		postMessageRequest := &obj.SlackPostChatMessageRequest{}
-		log.Debug(string(slackTemplate.Render(templateContent)))
-		err = json.Unmarshal([]byte(slackTemplate.Render(templateContent)), postMessageRequest)
-		if err != nil {
-			return err
+			slackPostMessageResponse, err = postMessageRequest.Do(this.apiURL + "/chat.postMessage")
 		}
-
-		slackPostMessageResponse, err := postMessageRequest.Do("https://slack.com/api/chat.postMessage")
 		if err != nil {
 			log.WithFields(log.Fields{"slackbot": "Send", "error": err}).Error("Error sending notification to slack.")
 			return err
@@ -116,33 +91,55 @@ func (this SlackBotSender) Send(n *obj.Notification, e *obj.Event) error {
 		if slackPostMessageResponse.OK != true {
 			return fmt.Errorf(slackPostMessageResponse.Error)
 		}
+
+		if e.Test {
+			return nil
+		}
+
+		// the notification has been delivered, so don't fail it and have it
+		// posted again if we can't keep track of the thread
+		switch {
+		case thread == nil && !e.Result.Passing:
+			thread = &obj.SlackThread{
+				CustomerId: n.CustomerId,
+				CheckId:    e.Result.CheckId,
+				Channel:    n.Value,
+				ChannelId:  slackPostMessageResponse.Channel,
+				Ts:         slackPostMessageResponse.Ts,
+			}
+			if err := this.threads.PutSlackThread(thread); err != nil {
+				log.WithFields(log.Fields{"slackbot": "Send", "check_id": thread.CheckId, "error": err}).Warn("Couldn't save slack thread.")
+			}
+		case thread != nil && slackRecovered(e):
+			if _, err := postMessageRequest.Update(this.apiURL+"/chat.update", thread.ChannelId, thread.Ts); err != nil {
+				log.WithFields(log.Fields{"slackbot": "Send", "check_id": thread.CheckId, "error": err}).Warn("Couldn't update slack thread.")
+			}
+			this.closeThread(thread)
+		}
 	}
 
 	return nil
 }
 
-func (this SlackBotSender) getSlackToken(n *obj.Notification) (string, error) {
-	s, err := store.NewPostgres()
-	if err != nil {
-		return "", err
+func (this SlackBotSender) closeThread(thread *obj.SlackThread) {
+	if err := this.threads.DeleteSlackThread(thread.CustomerId, thread.CheckId, thread.Channel); err != nil {
+		log.WithFields(log.Fields{"slackbot": "Send", "check_id": thread.CheckId, "error": err}).Warn("Couldn't close slack thread.")
 	}
+}
 
-	oaResponse, err := s.GetSlackOAuthResponse(&schema.User{CustomerId: n.CustomerId})
-	if err != nil {
-		return "", err
-	}
-
-	// if for whatever reason we don't have a bot
-	if oaResponse.Bot == nil {
-		log.WithFields(log.Fields{"slackbot": "getSlackToken"}).Error("User does not have a bot token associated with this slack integration.")
-		return "", fmt.Errorf("integration_inactive")
-
-	}
+// slackThreadGone is whether a Slack error means a thread can't be replied to
+// anymore.
+func slackThreadGone(slackError string) bool {
+	return slackError == "thread_not_found" || slackError == "message_not_found"
+}
 
-	return oaResponse.Bot.BotAccessToken, nil
+// slackRecovered is whether an event closes the check's thread. A flapping
+// check stays in its thread even while passing.
+func slackRecovered(e *obj.Event) bool {
+	return e.Result.Passing && (e.Flapping == nil || e.Flapping.Stable)
 }
 
-func NewSlackBotSender() (*SlackBotSender, error) {
+func NewSlackBotSender(credentials Credentials, threads SlackThreads, catsClient opsee.CatsClient, apiURL string) (*SlackBotSender, error) {
 
 	// initialize check failing template
 	failTemplate, err := mustache.ParseString(slacktmpl.CheckFailing)
@@ -156,25 +153,28 @@ func NewSlackBotSender() (*SlackBotSender, error) {
 		return nil, err
 	}
 
-	templateMap := map[string]*mustache.Template{
-		"check-failing": failTemplate,
-		"check-passing": passTemplate,
+	flappingTemplate, err := mustache.ParseString(slackCheckFlapping)
+	if err != nil {
+		return nil, err
 	}
 
-	catsConn, err := grpc.Dial(
-		"cats.in.opsee.com:443",
-		grpc.WithTransportCredentials(
-			credentials.NewTLS(&tls.Config{
-				InsecureSkipVerify: true,
-			}),
-		),
-	)
+	stableTemplate, err := mustache.ParseString(slackCheckStable)
 	if err != nil {
 		return nil, err
 	}
 
+	templateMap := map[string]*mustache.Template{
+		"check-failing":  failTemplate,
+		"check-passing":  passTemplate,
+		"check-flapping": flappingTemplate,
+		"check-stable":   stableTemplate,
+	}
+
 	return &SlackBotSender{
-		templates:  templateMap,
-		catsClient: opsee.NewCatsClient(catsConn),
+		templates:   templateMap,
+		catsClient:  catsClient,
+		credentials: credentials,
+		threads:     threads,
+		apiURL:      apiURL,
 	}, nil
 }
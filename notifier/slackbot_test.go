@@ -0,0 +1,173 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackBotSenderThreads(t *testing.T) {
+	slack := hugstest.NewSlack()
+	defer slack.Close()
+
+	db := store.NewMemory()
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+	db.PutSlackOAuthResponse(user, slack.OAuthResponse)
+
+	sender, err := NewSlackBotSender(&StoreCredentials{Store: db}, db, nil, slack.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: user.CustomerId, Type: "slack_bot", Value: "C00000001"}
+	failing := obj.GenerateFailingTestEvent()
+	failing.Test = false
+	passing := obj.GenerateTestEvent()
+	passing.Test = false
+	passing.Result.CheckId = failing.Result.CheckId
+
+	// the first failure starts a thread and repeats are posted in it
+	assert.NoError(t, sender.Send(notif, failing))
+	assert.NoError(t, sender.Send(notif, failing))
+
+	thread, err := db.GetSlackThread(user.CustomerId, failing.Result.CheckId, notif.Value)
+	if assert.NoError(t, err) && assert.NotNil(t, thread) {
+		assert.Equal(t, "C00000001", thread.ChannelId)
+	}
+
+	// flapping checks stay in the thread
+	flapping := obj.GenerateTestEvent()
+	flapping.Test = false
+	flapping.Result.CheckId = failing.Result.CheckId
+	flapping.Flapping = &obj.Flapping{Transitions: 5, Since: time.Now()}
+	assert.NoError(t, sender.Send(notif, flapping))
+
+	// the recovery is the last reply and marks the original message passing
+	assert.NoError(t, sender.Send(notif, passing))
+
+	messages := slack.Messages()
+	if assert.Len(t, messages, 4) {
+		assert.Equal(t, "", messages[0].Form.Get("thread_ts"))
+		for _, reply := range messages[1:] {
+			assert.Equal(t, thread.Ts, reply.Form.Get("thread_ts"))
+		}
+	}
+
+	updates := slack.Updates()
+	if assert.Len(t, updates, 1) {
+		assert.Equal(t, thread.ChannelId, updates[0].Form.Get("channel"))
+		assert.Equal(t, thread.Ts, updates[0].Form.Get("ts"))
+		assert.Contains(t, updates[0].Form.Get("attachments"), "#69a92c")
+	}
+
+	thread, err = db.GetSlackThread(user.CustomerId, failing.Result.CheckId, notif.Value)
+	assert.NoError(t, err)
+	assert.Nil(t, thread)
+
+	// the next failure starts a new thread
+	slack.Reset()
+	assert.NoError(t, sender.Send(notif, failing))
+	if messages := slack.Messages(); assert.Len(t, messages, 1) {
+		assert.Equal(t, "", messages[0].Form.Get("thread_ts"))
+	}
+}
+
+func TestSlackBotSenderTestEvents(t *testing.T) {
+	slack := hugstest.NewSlack()
+	defer slack.Close()
+
+	db := store.NewMemory()
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+	db.PutSlackOAuthResponse(user, slack.OAuthResponse)
+
+	sender, err := NewSlackBotSender(&StoreCredentials{Store: db}, db, nil, slack.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: user.CustomerId, Type: "slack_bot", Value: "C00000001"}
+	event := obj.GenerateFailingTestEvent()
+	assert.NoError(t, sender.Send(notif, event))
+
+	thread, err := db.GetSlackThread(user.CustomerId, event.Result.CheckId, notif.Value)
+	assert.NoError(t, err)
+	assert.Nil(t, thread)
+}
+
+// agedThreads makes every stored thread older than it is
+type agedThreads struct {
+	*store.Memory
+	age time.Duration
+}
+
+func (a *agedThreads) GetSlackThread(customerId, checkId, channel string) (*obj.SlackThread, error) {
+	thread, err := a.Memory.GetSlackThread(customerId, checkId, channel)
+	if thread != nil {
+		thread.CreatedAt = thread.CreatedAt.Add(-a.age)
+	}
+	return thread, err
+}
+
+func TestSlackBotSenderStaleThreads(t *testing.T) {
+	slack := hugstest.NewSlack()
+	defer slack.Close()
+
+	db := store.NewMemory()
+	user := &schema.User{CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5"}
+	db.PutSlackOAuthResponse(user, slack.OAuthResponse)
+
+	threads := &agedThreads{Memory: db}
+	sender, err := NewSlackBotSender(&StoreCredentials{Store: db}, threads, nil, slack.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: user.CustomerId, Type: "slack_bot", Value: "C00000001"}
+	failing := obj.GenerateFailingTestEvent()
+	failing.Test = false
+
+	// a thread whose message is gone is replaced by a new message
+	db.PutSlackThread(&obj.SlackThread{CustomerId: user.CustomerId, CheckId: failing.Result.CheckId, Channel: notif.Value, ChannelId: notif.Value, Ts: "1466000000.000001"})
+	assert.NoError(t, sender.Send(notif, failing))
+
+	messages := slack.Messages()
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "1466000000.000001", messages[0].Form.Get("thread_ts"))
+		assert.Equal(t, "", messages[1].Form.Get("thread_ts"))
+	}
+
+	thread, err := db.GetSlackThread(user.CustomerId, failing.Result.CheckId, notif.Value)
+	if assert.NoError(t, err) && assert.NotNil(t, thread) {
+		assert.NotEqual(t, "1466000000.000001", thread.Ts)
+	}
+
+	// an expired thread isn't replied to
+	slack.Reset()
+	threads.age = obj.SlackThreadTTL + time.Hour
+	assert.NoError(t, sender.Send(notif, failing))
+	if messages := slack.Messages(); assert.Len(t, messages, 1) {
+		assert.Equal(t, "", messages[0].Form.Get("thread_ts"))
+	}
+
+	latest, err := db.GetSlackThread(user.CustomerId, failing.Result.CheckId, notif.Value)
+	if assert.NoError(t, err) && assert.NotNil(t, latest) {
+		assert.NotEqual(t, thread.Ts, latest.Ts)
+	}
+
+	// other errors are returned to be retried, keeping the thread
+	slack.Reset()
+	threads.age = 0
+	slack.Token = "revoked"
+	assert.Error(t, sender.Send(notif, failing))
+	assert.Len(t, slack.Messages(), 1)
+
+	kept, err := db.GetSlackThread(user.CustomerId, failing.Result.CheckId, notif.Value)
+	if assert.NoError(t, err) && assert.NotNil(t, kept) {
+		assert.Equal(t, latest.Ts, kept.Ts)
+	}
+}
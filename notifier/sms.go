@@ -0,0 +1,187 @@
+package notifier
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoisie/mustache"
+	opsee "github.com/opsee/basic/service"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// SMS bodies longer than this many characters are truncated, two
+// concatenated messages' worth, so a long check name can't run up the bill.
+const smsMaxLength = 306
+
+// ErrSMSRateLimited is returned for notifications dropped by the rate limiter.
+var ErrSMSRateLimited = &obj.SuppressedError{Status: obj.DeliveryStatusRateLimited, Reason: "sms rate limit exceeded"}
+
+// SMSGateway sends a plain text message to an E.164 phone number.
+type SMSGateway interface {
+	SendSMS(to, body string) error
+}
+
+// TwilioGateway sends text messages through the Twilio REST API, or any API
+// compatible with its Messages resource.
+type TwilioGateway struct {
+	AccountSid string
+	AuthToken  string
+	From       string
+	APIURL     string
+}
+
+func (g *TwilioGateway) SendSMS(to, body string) error {
+	request := &obj.TwilioMessageRequest{
+		To:   to,
+		From: g.From,
+		Body: body,
+	}
+
+	message, err := request.Do(g.APIURL+obj.TwilioMessagesPath(g.AccountSid), g.AccountSid, g.AuthToken)
+	log.Debug(message)
+	return err
+}
+
+func NewTwilioGateway(accountSid, authToken, from, apiURL string) (*TwilioGateway, error) {
+	if accountSid == "" || authToken == "" || from == "" {
+		return nil, errors.New("Twilio account sid, auth token and from number are required for sms")
+	}
+
+	return &TwilioGateway{
+		AccountSid: accountSid,
+		AuthToken:  authToken,
+		From:       from,
+		APIURL:     apiURL,
+	}, nil
+}
+
+// SMSRateLimiter allows each phone number Limit messages within any Window.
+// It only counts messages sent by this process, so share one between the
+// SMS senders of a process. With several worker processes a number can get
+// up to Limit messages from each of them.
+type SMSRateLimiter struct {
+	Limit  int
+	Window time.Duration
+
+	sync.Mutex
+	sent map[string][]time.Time
+	now  func() time.Time
+}
+
+func NewSMSRateLimiter(limit int, window time.Duration) *SMSRateLimiter {
+	return &SMSRateLimiter{
+		Limit:  limit,
+		Window: window,
+		sent:   map[string][]time.Time{},
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether another message may be sent to number, and counts it
+// if so.
+func (l *SMSRateLimiter) Allow(number string) bool {
+	if l.Limit <= 0 {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	recent := []time.Time{}
+	for _, t := range l.sent[number] {
+		if now.Sub(t) < l.Window {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.Limit {
+		l.sent[number] = recent
+		return false
+	}
+
+	l.sent[number] = append(recent, now)
+	return true
+}
+
+// Refund uncounts the latest message Allow counted for number, for messages
+// that couldn't be sent after all.
+func (l *SMSRateLimiter) Refund(number string) {
+	if l.Limit <= 0 {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if sent := l.sent[number]; len(sent) > 0 {
+		l.sent[number] = sent[:len(sent)-1]
+	}
+}
+
+// SMSSender texts a short plain text version of the notification to the phone
+// number in the notification's value.
+type SMSSender struct {
+	templates  map[string]*mustache.Template
+	catsClient opsee.CatsClient
+	gateway    SMSGateway
+	limiter    *SMSRateLimiter
+}
+
+// Send notification to customer.  At this point we have done basic validation on notification and event
+func (this *SMSSender) Send(n *obj.Notification, e *obj.Event) error {
+	smsTemplate, ok := this.templates[eventTemplateKey(e)]
+	if !ok {
+		return nil
+	}
+
+	templateContent, err := checkTemplateContent(this.catsClient, e)
+	if err != nil {
+		return err
+	}
+
+	// dropped rather than failed, retrying would only send a burst later
+	if !this.limiter.Allow(n.Value) {
+		log.WithFields(log.Fields{"sms": "Send", "customer_id": n.CustomerId, "check_id": e.Result.CheckId}).Warn("SMS rate limit exceeded, dropping notification.")
+		return ErrSMSRateLimited
+	}
+
+	body := []rune(strings.TrimSpace(smsTemplate.Render(templateContent)))
+	if len(body) > smsMaxLength {
+		body = append(body[:smsMaxLength-3], []rune("...")...)
+	}
+
+	// only messages the gateway accepted count towards the limit, so an
+	// outage and the retries it causes can't use it up
+	if err := this.gateway.SendSMS(n.Value, string(body)); err != nil {
+		this.limiter.Refund(n.Value)
+		return err
+	}
+	return nil
+}
+
+func NewSMSSender(catsClient opsee.CatsClient, gateway SMSGateway, limiter *SMSRateLimiter) (*SMSSender, error) {
+	templateMap := map[string]*mustache.Template{}
+	for key, tmpl := range map[string]string{
+		"check-failing":  smsCheckFailing,
+		"check-passing":  smsCheckPassing,
+		"check-flapping": smsCheckFlapping,
+		"check-stable":   smsCheckStable,
+	} {
+		template, err := mustache.ParseString(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		templateMap[key] = template
+	}
+
+	return &SMSSender{
+		templates:  templateMap,
+		catsClient: catsClient,
+		gateway:    gateway,
+		limiter:    limiter,
+	}, nil
+}
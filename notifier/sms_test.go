@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMSSenderSend(t *testing.T) {
+	twilio := hugstest.NewTwilio()
+	defer twilio.Close()
+
+	gateway, err := NewTwilioGateway(twilio.AccountSid, twilio.AuthToken, "+14155550100", twilio.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := NewSMSSender(nil, gateway, NewSMSRateLimiter(0, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: "test", Type: "sms", Value: "+14155552671"}
+	failing := obj.GenerateFailingTestEvent()
+	failing.Result.CheckName = strings.Repeat("é", 400)
+	assert.Nil(t, sender.Send(notif, failing))
+	assert.Nil(t, sender.Send(notif, obj.GenerateTestEvent()))
+
+	messages := twilio.Messages()
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "+14155552671", messages[0].To)
+		assert.Equal(t, "+14155550100", messages[0].From)
+		assert.Len(t, []rune(messages[0].Body), smsMaxLength)
+		assert.Contains(t, messages[1].Body, "passing")
+	}
+
+	twilio.AuthToken = "other"
+	assert.NotNil(t, sender.Send(notif, obj.GenerateTestEvent()))
+
+	_, err = NewTwilioGateway("", "", "", twilio.URL)
+	assert.NotNil(t, err)
+}
+
+type recordingGateway struct {
+	sent []string
+	err  error
+}
+
+func (g *recordingGateway) SendSMS(to, body string) error {
+	if g.err != nil {
+		return g.err
+	}
+	g.sent = append(g.sent, to)
+	return nil
+}
+
+func TestSMSSenderRateLimit(t *testing.T) {
+	gateway := &recordingGateway{}
+	limiter := NewSMSRateLimiter(2, time.Hour)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	sender, err := NewSMSSender(nil, gateway, limiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notif := &obj.Notification{CustomerId: "test", Type: "sms", Value: "+14155552671"}
+	other := &obj.Notification{CustomerId: "test", Type: "sms", Value: "+14155552672"}
+
+	// failed sends don't count
+	gateway.err = errors.New("gateway unavailable")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, gateway.err, sender.Send(notif, obj.GenerateTestEvent()))
+	}
+	gateway.err = nil
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, sender.Send(notif, obj.GenerateTestEvent()))
+	}
+	assert.Equal(t, ErrSMSRateLimited, sender.Send(notif, obj.GenerateTestEvent()))
+	assert.Nil(t, sender.Send(other, obj.GenerateTestEvent()))
+	assert.Equal(t, []string{notif.Value, notif.Value, other.Value}, gateway.sent)
+
+	// the window slides
+	now = now.Add(time.Hour)
+	assert.Nil(t, sender.Send(notif, obj.GenerateTestEvent()))
+	assert.Len(t, gateway.sent, 4)
+}
@@ -0,0 +1,218 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	log "github.com/opsee/logrus"
+)
+
+const (
+	// smtpConnectTimeout bounds connecting to the SMTP server, and
+	// smtpTimeout the whole conversation with it.
+	smtpConnectTimeout = 10 * time.Second
+	smtpTimeout        = time.Minute
+)
+
+// SMTPMailer renders the check email templates locally and sends them as
+// multipart HTML and plain text over SMTP. STARTTLS is used whenever the
+// server offers it, and the server must authenticate when a username is set.
+type SMTPMailer struct {
+	Address    string
+	Username   string
+	Password   string
+	From       string
+	RequireTLS bool
+	// TLSConfig is used for STARTTLS, verifying the server's certificate
+	// against its host name if nil.
+	TLSConfig *tls.Config
+	// ConnectTimeout bounds connecting to the server and Timeout sending a
+	// message, so a stalled server can't hold up a worker.
+	ConnectTimeout time.Duration
+	Timeout        time.Duration
+
+	templates map[string]*emailTemplate
+}
+
+func (m *SMTPMailer) SendTemplate(to string, templateName string, templateContent map[string]interface{}) error {
+	tmpl, ok := m.templates[templateName]
+	if !ok {
+		return fmt.Errorf("Unknown email template %s", templateName)
+	}
+
+	message, err := m.message(to, tmpl, templateContent)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{"smtp": "SendTemplate", "template": templateName, "address": m.Address}).Debug("Sending email.")
+	return m.send(to, message)
+}
+
+// message builds a multipart/alternative MIME message, the text part first so
+// that clients prefer HTML.
+func (m *SMTPMailer) message(to string, tmpl *emailTemplate, templateContent map[string]interface{}) ([]byte, error) {
+	body := &bytes.Buffer{}
+	parts := multipart.NewWriter(body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", tmpl.text.Render(templateContent)},
+		{"text/html; charset=utf-8", tmpl.html.Render(templateContent)},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := io.WriteString(qp, part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	message := &bytes.Buffer{}
+	headers := [][2]string{
+		{"From", m.From},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", tmpl.subject.Render(templateContent))},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-Id", messageId(m.From)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%s", parts.Boundary())},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func (m *SMTPMailer) send(to string, message []byte) error {
+	host, _, err := net.SplitHostPort(m.Address)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", m.Address, m.ConnectTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(m.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConfig := m.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	} else if m.RequireTLS {
+		return fmt.Errorf("SMTP server %s doesn't support STARTTLS", m.Address)
+	}
+
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	// the envelope sender is the bare address, From may include a name
+	from := m.From
+	if addr, err := mail.ParseAddress(m.From); err == nil {
+		from = addr.Address
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// messageId returns a unique Message-Id in the sender's domain.
+func messageId(from string) string {
+	domain := "hugs"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%x.%d@%s>", b, time.Now().UnixNano(), domain)
+}
+
+func NewSMTPMailer(address, username, password, from string, requireTLS bool) (*SMTPMailer, error) {
+	if address == "" || from == "" {
+		return nil, errors.New("SMTP address and from address are required")
+	}
+
+	templates, err := parseEmailTemplates(emailTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SMTPMailer{
+		Address:        address,
+		Username:       username,
+		Password:       password,
+		From:           from,
+		RequireTLS:     requireTLS,
+		ConnectTimeout: smtpConnectTimeout,
+		Timeout:        smtpTimeout,
+		templates:      templates,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// SNSSender publishes the same JSON webhooks receive to the SNS topic ARN in
+// the notification's value, with the customer, check and whether it's passing
+// as message attributes so subscribers can filter on them.
+type SNSSender struct {
+	session  *session.Session
+	endpoint string
+
+	sync.Mutex
+	clients map[string]*sns.SNS
+}
+
+// Send notification to customer.  At this point we have done basic validation on notification and event
+func (this *SNSSender) Send(n *obj.Notification, e *obj.Event) error {
+	region, err := snsTopicRegion(n.Value)
+	if err != nil {
+		return err
+	}
+
+	body, err := fullCheckResultJSON(e)
+	if err != nil {
+		return err
+	}
+
+	output, err := this.client(region).Publish(&sns.PublishInput{
+		TopicArn: aws.String(n.Value),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"customer_id": snsString(e.Result.CustomerId),
+			"check_id":    snsString(e.Result.CheckId),
+			"passing":     snsString(strconv.FormatBool(e.Result.Passing)),
+		},
+	})
+	if err != nil {
+		log.WithFields(log.Fields{"sns": "Send", "topic": n.Value, "error": err}).Error("Error publishing notification to sns.")
+		return err
+	}
+
+	log.Debug(output)
+	return nil
+}
+
+// client returns a client for the topic's region, SNS only accepts publishes
+// to topics in the region it's called in.
+func (this *SNSSender) client(region string) *sns.SNS {
+	this.Lock()
+	defer this.Unlock()
+
+	if c, ok := this.clients[region]; ok {
+		return c
+	}
+
+	cfg := aws.NewConfig().WithRegion(region)
+	if this.endpoint != "" {
+		cfg = cfg.WithEndpoint(this.endpoint)
+	}
+
+	c := sns.New(this.session, cfg)
+	this.clients[region] = c
+	return c
+}
+
+func snsString(value string) *sns.MessageAttributeValue {
+	return &sns.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
+
+// snsTopicRegion returns the region in a topic ARN,
+// arn:aws:sns:<region>:<account>:<topic>.
+func snsTopicRegion(arn string) (string, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return "", fmt.Errorf("Invalid SNS topic ARN %s", arn)
+	}
+	return parts[3], nil
+}
+
+// NewSNSSender publishes with the given AWS session, which is usually the
+// shared config.Config.AWSSession. Endpoint overrides the SNS endpoint, e.g.
+// for a local SNS compatible service, and is normally empty.
+func NewSNSSender(awsSession *session.Session, endpoint string) (*SNSSender, error) {
+	if awsSession == nil {
+		return nil, fmt.Errorf("An AWS session is required for sns")
+	}
+
+	return &SNSSender{
+		session:  awsSession,
+		endpoint: endpoint,
+		clients:  map[string]*sns.SNS{},
+	}, nil
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSNSSenderSend(t *testing.T) {
+	fake := hugstest.NewSNS()
+	defer fake.Close()
+
+	awsSession := session.New(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		Region:      aws.String("us-east-1"),
+		MaxRetries:  aws.Int(0),
+	})
+
+	sender, err := NewSNSSender(awsSession, fake.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := "arn:aws:sns:us-west-2:123456789012:alerts"
+	event := obj.GenerateFailingTestEvent()
+	assert.Nil(t, sender.Send(&obj.Notification{CustomerId: "test", Type: "sns", Value: topic}, event))
+
+	messages := fake.Messages()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, topic, messages[0].TopicArn)
+		assert.Equal(t, map[string]string{
+			"customer_id": event.Result.CustomerId,
+			"check_id":    event.Result.CheckId,
+			"passing":     "false",
+		}, messages[0].Attributes)
+
+		result := &FullCheckResult{}
+		assert.Nil(t, json.Unmarshal([]byte(messages[0].Message), result))
+		assert.Equal(t, event.Result.CheckId, result.CheckId)
+		assert.NotEmpty(t, result.Responses)
+	}
+
+	// the client is for the topic's region, not the session's
+	assert.Len(t, sender.clients, 1)
+	assert.NotNil(t, sender.clients["us-west-2"])
+
+	assert.NotNil(t, sender.Send(&obj.Notification{CustomerId: "test", Type: "sns", Value: "alerts"}, event))
+}
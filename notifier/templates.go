@@ -0,0 +1,354 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/net/context"
+
+	opsee "github.com/opsee/basic/service"
+	"github.com/opsee/hugs/obj"
+)
+
+// Templates for flapping checks, which notification-templates doesn't have.
+
+var slackCheckFlapping = `{
+  "token": "{{token}}",
+  "channel":"{{channel}}",
+  "username": "OpseeBot",
+  "icon_url": "https://s3-us-west-1.amazonaws.com/opsee-public-images/slack-avi-48-red.png",
+  "attachments": [
+    {
+      "pretext": "Flapping check",
+      "title": "{{check_name}} flapping in {{group_name}}",
+      "title_link": "https://app.opsee.com/check/{{check_id}}{{json_url}}utm_source=notification&utm_medium=slack&utm_campaign=app",
+      "text": "Changed state {{transitions}} times recently. Further notifications are paused until it is stable.",
+      "color": "#ff9800"
+    }
+  ]
+}
+`
+
+var slackCheckStable = `{
+  "token": "{{token}}",
+  "channel":"{{channel}}",
+  "username": "OpseeBot",
+  "icon_url": "https://s3-us-west-1.amazonaws.com/opsee-public-images/slack-avi-48-{{#passing}}green{{/passing}}{{^passing}}red{{/passing}}.png",
+  "attachments": [
+    {
+      "pretext": "Check stopped flapping",
+      "title": "{{check_name}} {{#passing}}passing{{/passing}}{{^passing}}failing{{/passing}} in {{group_name}}",
+      "title_link": "https://app.opsee.com/check/{{check_id}}{{json_url}}utm_source=notification&utm_medium=slack&utm_campaign=app",
+      "text": "Changed state {{transitions}} times while flapping. {{#passing}}{{instance_count}} {{type}} Passing{{/passing}}{{^passing}}{{fail_count}} of {{instance_count}} {{type}} Failing{{/passing}}",
+      "color": "{{#passing}}#69a92c{{/passing}}{{^passing}}#f44336{{/passing}}"
+    }
+  ]
+}
+`
+
+var pagerDutyCheckFlapping = `{
+  "service_key": "{{service_key}}",
+  "incident_key":"{{check_id}}",
+  "description":"{{check_name}} flapping in {{group_name}}",
+  "client_url":"https://{{opsee_host}}/check/{{check_id}}",
+  "event_type":"trigger"
+}
+`
+
+// Templates for Microsoft Teams incoming webhooks. These are legacy
+// actionable message cards, which Office 365 connector webhooks accept, and
+// read the same as their slack equivalents.
+
+var msTeamsCheckFailing = `{
+  "@type": "MessageCard",
+  "@context": "https://schema.org/extensions",
+  "summary": "{{check_name}} failing in {{group_name}}",
+  "themeColor": "f44336",
+  "sections": [
+    {
+      "activityTitle": "Failing check",
+      "activitySubtitle": "{{check_name}} failing in {{group_name}}",
+      "activityImage": "https://s3-us-west-1.amazonaws.com/opsee-public-images/slack-avi-48-red.png",
+      "text": "{{fail_count}} of {{instance_count}} {{type}} Failing"
+    }
+  ],
+  "potentialAction": [
+    {
+      "@type": "OpenUri",
+      "name": "View check",
+      "targets": [
+        {"os": "default", "uri": "https://app.opsee.com/check/{{check_id}}{{json_url}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+      ]
+    }
+  ]
+}
+`
+
+var msTeamsCheckPassing = `{
+  "@type": "MessageCard",
+  "@context": "https://schema.org/extensions",
+  "summary": "{{check_name}} passing in {{group_name}}",
+  "themeColor": "69a92c",
+  "sections": [
+    {
+      "activityTitle": "Passing check",
+      "activitySubtitle": "{{check_name}} passing in {{group_name}}",
+      "activityImage": "https://s3-us-west-1.amazonaws.com/opsee-public-images/slack-avi-48-green.png",
+      "text": "{{instance_count}} {{type}} Passing"
+    }
+  ],
+  "potentialAction": [
+    {
+      "@type": "OpenUri",
+      "name": "View check",
+      "targets": [
+        {"os": "default", "uri": "https://app.opsee.com/check/{{check_id}}{{json_url}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+      ]
+    }
+  ]
+}
+`
+
+var msTeamsCheckFlapping = `{
+  "@type": "MessageCard",
+  "@context": "https://schema.org/extensions",
+  "summary": "{{check_name}} flapping in {{group_name}}",
+  "themeColor": "ff9800",
+  "sections": [
+    {
+      "activityTitle": "Flapping check",
+      "activitySubtitle": "{{check_name}} flapping in {{group_name}}",
+      "activityImage": "https://s3-us-west-1.amazonaws.com/opsee-public-images/slack-avi-48-red.png",
+      "text": "Changed state {{transitions}} times recently. Further notifications are paused until it is stable."
+    }
+  ],
+  "potentialAction": [
+    {
+      "@type": "OpenUri",
+      "name": "View check",
+      "targets": [
+        {"os": "default", "uri": "https://app.opsee.com/check/{{check_id}}{{json_url}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+      ]
+    }
+  ]
+}
+`
+
+var msTeamsCheckStable = `{
+  "@type": "MessageCard",
+  "@context": "https://schema.org/extensions",
+  "summary": "{{check_name}} stopped flapping in {{group_name}}",
+  "themeColor": "{{#passing}}69a92c{{/passing}}{{^passing}}f44336{{/passing}}",
+  "sections": [
+    {
+      "activityTitle": "Check stopped flapping",
+      "activitySubtitle": "{{check_name}} {{#passing}}passing{{/passing}}{{^passing}}failing{{/passing}} in {{group_name}}",
+      "activityImage": "https://s3-us-west-1.amazonaws.com/opsee-public-images/slack-avi-48-{{#passing}}green{{/passing}}{{^passing}}red{{/passing}}.png",
+      "text": "Changed state {{transitions}} times while flapping. {{#passing}}{{instance_count}} {{type}} Passing{{/passing}}{{^passing}}{{fail_count}} of {{instance_count}} {{type}} Failing{{/passing}}"
+    }
+  ],
+  "potentialAction": [
+    {
+      "@type": "OpenUri",
+      "name": "View check",
+      "targets": [
+        {"os": "default", "uri": "https://app.opsee.com/check/{{check_id}}{{json_url}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+      ]
+    }
+  ]
+}
+`
+
+// Adaptive Cards for Teams webhooks created with the Workflows app, which
+// don't take MessageCards. Values are JSON escaped by the sender, so they're
+// inserted with triple mustaches.
+
+var msTeamsAdaptiveCheckFailing = `{
+  "type": "message",
+  "attachments": [
+    {
+      "contentType": "application/vnd.microsoft.card.adaptive",
+      "contentUrl": null,
+      "content": {
+        "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+        "type": "AdaptiveCard",
+        "version": "1.4",
+        "body": [
+          {"type": "TextBlock", "text": "Failing check", "weight": "Bolder", "size": "Medium", "color": "Attention"},
+          {"type": "TextBlock", "text": "{{{check_name}}} failing in {{{group_name}}}", "wrap": true},
+          {"type": "TextBlock", "text": "{{{fail_count}}} of {{{instance_count}}} {{{type}}} Failing", "wrap": true, "isSubtle": true}
+        ],
+        "actions": [
+          {"type": "Action.OpenUrl", "title": "View check", "url": "https://app.opsee.com/check/{{{check_id}}}{{{json_url}}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+        ]
+      }
+    }
+  ]
+}
+`
+
+var msTeamsAdaptiveCheckPassing = `{
+  "type": "message",
+  "attachments": [
+    {
+      "contentType": "application/vnd.microsoft.card.adaptive",
+      "contentUrl": null,
+      "content": {
+        "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+        "type": "AdaptiveCard",
+        "version": "1.4",
+        "body": [
+          {"type": "TextBlock", "text": "Passing check", "weight": "Bolder", "size": "Medium", "color": "Good"},
+          {"type": "TextBlock", "text": "{{{check_name}}} passing in {{{group_name}}}", "wrap": true},
+          {"type": "TextBlock", "text": "{{{instance_count}}} {{{type}}} Passing", "wrap": true, "isSubtle": true}
+        ],
+        "actions": [
+          {"type": "Action.OpenUrl", "title": "View check", "url": "https://app.opsee.com/check/{{{check_id}}}{{{json_url}}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+        ]
+      }
+    }
+  ]
+}
+`
+
+var msTeamsAdaptiveCheckFlapping = `{
+  "type": "message",
+  "attachments": [
+    {
+      "contentType": "application/vnd.microsoft.card.adaptive",
+      "contentUrl": null,
+      "content": {
+        "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+        "type": "AdaptiveCard",
+        "version": "1.4",
+        "body": [
+          {"type": "TextBlock", "text": "Flapping check", "weight": "Bolder", "size": "Medium", "color": "Warning"},
+          {"type": "TextBlock", "text": "{{{check_name}}} flapping in {{{group_name}}}", "wrap": true},
+          {"type": "TextBlock", "text": "Changed state {{{transitions}}} times recently. Further notifications are paused until it is stable.", "wrap": true, "isSubtle": true}
+        ],
+        "actions": [
+          {"type": "Action.OpenUrl", "title": "View check", "url": "https://app.opsee.com/check/{{{check_id}}}{{{json_url}}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+        ]
+      }
+    }
+  ]
+}
+`
+
+var msTeamsAdaptiveCheckStable = `{
+  "type": "message",
+  "attachments": [
+    {
+      "contentType": "application/vnd.microsoft.card.adaptive",
+      "contentUrl": null,
+      "content": {
+        "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+        "type": "AdaptiveCard",
+        "version": "1.4",
+        "body": [
+          {"type": "TextBlock", "text": "Check stopped flapping", "weight": "Bolder", "size": "Medium", "color": "{{#passing}}Good{{/passing}}{{^passing}}Attention{{/passing}}"},
+          {"type": "TextBlock", "text": "{{{check_name}}} {{#passing}}passing{{/passing}}{{^passing}}failing{{/passing}} in {{{group_name}}}", "wrap": true},
+          {"type": "TextBlock", "text": "Changed state {{{transitions}}} times while flapping. {{#passing}}{{{instance_count}}} {{{type}}} Passing{{/passing}}{{^passing}}{{{fail_count}}} of {{{instance_count}}} {{{type}}} Failing{{/passing}}", "wrap": true, "isSubtle": true}
+        ],
+        "actions": [
+          {"type": "Action.OpenUrl", "title": "View check", "url": "https://app.opsee.com/check/{{{check_id}}}{{{json_url}}}utm_source=notification&utm_medium=msteams&utm_campaign=app"}
+        ]
+      }
+    }
+  ]
+}
+`
+
+// Plain text templates for SMS. Triple mustaches, since nothing is escaped
+// in a text message.
+
+var smsCheckFailing = `Opsee: {{{check_name}}} failing in {{{group_name}}}. {{fail_count}} of {{instance_count}} {{type}} failing. https://app.opsee.com/check/{{check_id}}`
+
+var smsCheckPassing = `Opsee: {{{check_name}}} passing in {{{group_name}}}. {{instance_count}} {{type}} passing.`
+
+var smsCheckFlapping = `Opsee: {{{check_name}}} flapping in {{{group_name}}}, changed state {{transitions}} times. Paused until stable. https://app.opsee.com/check/{{check_id}}`
+
+var smsCheckStable = `Opsee: {{{check_name}}} stopped flapping and is {{#passing}}passing{{/passing}}{{^passing}}failing{{/passing}} in {{{group_name}}}. https://app.opsee.com/check/{{check_id}}`
+
+// eventTemplateKey returns the name of the template to notify about an event with.
+func eventTemplateKey(e *obj.Event) string {
+	switch {
+	case e.Flapping != nil && e.Flapping.Stable:
+		return "check-stable"
+	case e.Flapping != nil:
+		return "check-flapping"
+	case e.Result.Passing:
+		return "check-passing"
+	}
+	return "check-failing"
+}
+
+// checkTemplateContent builds the template variables shared by the chat and
+// text message senders: the check, its group, how many of its instances are
+// failing and a link to the check. External host checks are counted by
+// points of presence, which only cats knows about.
+func checkTemplateContent(catsClient opsee.CatsClient, e *obj.Event) (map[string]interface{}, error) {
+	result := e.Result
+	failingResponses := result.FailingResponses()
+
+	// It's a possible error state that if the CheckResult.Passing field is false,
+	// i.e. this is a failing event, that there are somehow no constituent failing
+	// CheckResponse objects contained within the CheckResult. We cannot know _why_
+	// these CheckResponse objects aren't failing. Because we cannot ordain the reason
+	// for this error state, let us first err on the side of not bugging a customer.
+	if len(failingResponses) < 1 && !result.Passing {
+		return nil, errors.New("Received failing CheckResult with no failing responses.")
+	}
+
+	templateContent := map[string]interface{}{
+		"check_id":       result.CheckId,
+		"check_name":     result.CheckName,
+		"group_name":     result.Target.Id,
+		"instance_count": len(result.Responses),
+		"fail_count":     len(failingResponses),
+		"type":           "target",
+		"passing":        result.Passing,
+	}
+
+	if e.Flapping != nil {
+		templateContent["transitions"] = e.Flapping.Transitions
+	}
+
+	if e.Nocap != nil && e.Nocap.JSONUrl != "" {
+		templateContent["json_url"] = fmt.Sprintf("/event?json=%s&", url.QueryEscape(e.Nocap.JSONUrl))
+	} else {
+		templateContent["json_url"] = "?"
+	}
+
+	if result.Target.Type == "external_host" {
+		catsResponse, err := catsClient.GetCheckResults(context.Background(), &opsee.GetCheckResultsRequest{
+			CheckId:    result.CheckId,
+			CustomerId: result.CustomerId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		results := catsResponse.Results
+
+		var (
+			instanceCount = len(results)
+			failCount     int
+		)
+
+		for _, r := range results {
+			failCount += r.FailingCount()
+		}
+
+		// we have inconsistent results, so don't do anything
+		if !result.Passing && failCount == 0 {
+			return nil, fmt.Errorf("Failing result, but fail count == 0")
+		}
+
+		templateContent["instance_count"] = instanceCount
+		templateContent["fail_count"] = failCount
+		templateContent["type"] = "points-of-presence (PoPs)"
+	}
+
+	return templateContent, nil
+}
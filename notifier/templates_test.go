@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hoisie/mustache"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventTemplateKey(t *testing.T) {
+	failing := obj.GenerateFailingTestEvent()
+	assert.Equal(t, "check-failing", eventTemplateKey(failing))
+
+	passing := obj.GenerateTestEvent()
+	assert.Equal(t, "check-passing", eventTemplateKey(passing))
+
+	failing.Flapping = &obj.Flapping{Transitions: 5}
+	assert.Equal(t, "check-flapping", eventTemplateKey(failing))
+
+	failing.Flapping.Stable = true
+	assert.Equal(t, "check-stable", eventTemplateKey(failing))
+}
+
+func TestSlackFlappingTemplates(t *testing.T) {
+	for _, passing := range []bool{true, false} {
+		for _, source := range []string{slackCheckFlapping, slackCheckStable} {
+			tmpl, err := mustache.ParseString(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rendered := tmpl.Render(map[string]interface{}{
+				"check_id":       "check",
+				"check_name":     "Test Check",
+				"group_name":     "sg-1234",
+				"instance_count": 3,
+				"fail_count":     1,
+				"type":           "target",
+				"channel":        "#ops",
+				"passing":        passing,
+				"transitions":    6,
+				"json_url":       "?",
+			})
+
+			request := &obj.SlackPostChatMessageRequest{}
+			if err := json.Unmarshal([]byte(rendered), request); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "#ops", request.Channel)
+			assert.Contains(t, request.Attachments[0].Text, "6 times")
+		}
+	}
+
+	stable, err := mustache.ParseString(slackCheckStable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, stable.Render(map[string]interface{}{"passing": true}), "#69a92c")
+	assert.Contains(t, stable.Render(map[string]interface{}{"passing": false}), "#f44336")
+}
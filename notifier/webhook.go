@@ -4,21 +4,44 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/hoisie/mustache"
 	"github.com/opsee/basic/schema"
 	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/webhook"
 	log "github.com/opsee/logrus"
 	opsee_types "github.com/opsee/protobuf/opseeproto/types"
 )
 
-type WebHookSender struct{}
+// WebHookSender posts the full check result to a customer's URL, signed with
+// their webhook secret if they have one.
+type WebHookSender struct {
+	credentials Credentials
+	client      *WebhookClient
+}
 
 type FullCheckResponse struct {
 	Target   *schema.Target   `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
 	Response *json.RawMessage `protobuf:"bytes,2,opt,name=response" json:"response,omitempty"`
 	Error    string           `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
 	Passing  bool             `protobuf:"varint,4,opt,name=passing" json:"passing,omitempty"`
+	// ResponseType is the message name of Response, e.g. HttpResponse or
+	// CloudWatchResponse, however the worker sent it.
+	ResponseType string `json:"response_type,omitempty"`
+}
+
+// UnknownResponse is how a response of a type we can't decode is passed
+// through, its protobuf encoding base64 encoded in Value.
+type UnknownResponse struct {
+	TypeUrl string `json:"type_url"`
+	Value   []byte `json:"value"`
 }
 
 type FullCheckResult struct {
@@ -30,6 +53,7 @@ type FullCheckResult struct {
 	Target     *schema.Target         `protobuf:"bytes,6,opt,name=target" json:"target,omitempty"`
 	CheckName  string                 `protobuf:"bytes,7,opt,name=check_name" json:"check_name,omitempty"`
 	Version    int32                  `protobuf:"varint,8,opt,name=version" json:"version,omitempty"`
+	Flapping   *obj.Flapping          `json:"flapping,omitempty"`
 }
 
 func NewFullCheckResult(checkResult *schema.CheckResult) (*FullCheckResult, error) {
@@ -51,56 +75,201 @@ func NewFullCheckResult(checkResult *schema.CheckResult) (*FullCheckResult, erro
 			Passing: response.Passing,
 		}
 
-		// Unmarshal protobuf
-		any, err := opsee_types.UnmarshalAny(response.Response)
+		responseType, responseJSON, err := checkResponseJSON(response)
 		if err != nil {
-			log.Error(err)
 			return nil, err
 		}
+		fullResponse.ResponseType = responseType
+		fullResponse.Response = responseJSON
 
-		// marshal json
-		// TODO(dan) marshal other any types
-		// this will get replaced when we replace any with oneof
-		httpResponse, ok := any.(*schema.HttpResponse)
-		if !ok {
-			return nil, fmt.Errorf("Couldn't assert type on any.")
-		}
+		fullResponses = append(fullResponses, fullResponse)
+	}
+
+	fullCheckResult.Responses = fullResponses
 
-		anyjson, err := json.Marshal(httpResponse)
+	return fullCheckResult, nil
+}
+
+// checkResponseJSON converts a response's payload to JSON. The payload is
+// usually an Any, newer workers may set the Reply oneof instead. Any types
+// that aren't in the registry are passed through undecoded rather than
+// failing the whole result.
+func checkResponseJSON(response *schema.CheckResponse) (string, *json.RawMessage, error) {
+	var (
+		responseType string
+		payload      interface{}
+	)
+
+	switch {
+	case response.Response != nil:
+		// the registry is keyed by message names, which are also the
+		// names of the oneof variants
+		responseType = responseTypeName(response.Response.TypeUrl)
+		any, err := opsee_types.UnmarshalAny(&opsee_types.Any{TypeUrl: responseType, Value: response.Response.Value})
 		if err != nil {
-			return nil, err
+			log.WithFields(log.Fields{"type_url": response.Response.TypeUrl, "error": err}).Warn("Couldn't decode check response, passing it through.")
+			payload = &UnknownResponse{TypeUrl: response.Response.TypeUrl, Value: response.Response.Value}
+		} else {
+			payload = any
 		}
+	case response.GetHttpResponse() != nil:
+		responseType = "HttpResponse"
+		payload = response.GetHttpResponse()
+	case response.GetCloudwatchResponse() != nil:
+		responseType = "CloudWatchResponse"
+		payload = response.GetCloudwatchResponse()
+	default:
+		return "", nil, nil
+	}
+
+	responseJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
 
-		rawmsg := json.RawMessage(anyjson)
-		fullResponse.Response = &rawmsg
+	rawmsg := json.RawMessage(responseJSON)
+	return responseType, &rawmsg, nil
+}
 
-		fullResponses = append(fullResponses, fullResponse)
+// responseTypeName returns the message name of an Any's type url, e.g.
+// HttpResponse for both HttpResponse and type.googleapis.com/opsee.HttpResponse.
+func responseTypeName(typeUrl string) string {
+	name := typeUrl[strings.LastIndex(typeUrl, "/")+1:]
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// fullCheckResultJSON is the JSON document webhooks and SNS topics receive
+// for an event.
+func fullCheckResultJSON(e *obj.Event) ([]byte, error) {
+	fullResult, err := NewFullCheckResult(e.Result)
+	if err != nil {
+		return nil, err
 	}
+	fullResult.Flapping = e.Flapping
 
-	fullCheckResult.Responses = fullResponses
+	return json.Marshal(fullResult)
+}
 
-	return fullCheckResult, nil
+// webhookEscaper returns how template values are escaped in a webhook body
+// of the given content type, so that they can't break out of a JSON string
+// or form value.
+func webhookEscaper(contentType string) func(string) string {
+	if jsonMediaType(contentType) {
+		return func(value string) string {
+			quoted, _ := json.Marshal(value)
+			return string(quoted[1 : len(quoted)-1])
+		}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		return url.QueryEscape
+	}
+	return func(value string) string { return value }
+}
+
+func jsonMediaType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// webhookTemplateContent is what webhook body templates are rendered over.
+// String values are escaped for the body's content type. In JSON bodies the
+// result is the check result document itself, to embed as is.
+func webhookTemplateContent(e *obj.Event, resultJSON []byte, contentType string) map[string]interface{} {
+	result := e.Result
+
+	state := "failing"
+	if result.Passing {
+		state = "passing"
+	}
+
+	templateContent := map[string]interface{}{
+		"check_id":       result.CheckId,
+		"check_name":     result.CheckName,
+		"customer_id":    result.CustomerId,
+		"passing":        result.Passing,
+		"state":          state,
+		"fail_count":     len(result.FailingResponses()),
+		"response_count": len(result.Responses),
+		"flapping":       e.Flapping != nil && !e.Flapping.Stable,
+		"acknowledged":   e.Acknowledged,
+		"result":         string(resultJSON),
+	}
+
+	if result.Timestamp != nil {
+		templateContent["timestamp"] = time.Unix(result.Timestamp.Seconds, int64(result.Timestamp.Nanos)).UTC().Format(time.RFC3339)
+	}
+
+	if result.Target != nil {
+		templateContent["target_id"] = result.Target.Id
+		templateContent["target_name"] = result.Target.Name
+		templateContent["target_type"] = result.Target.Type
+	}
+
+	escape := webhookEscaper(contentType)
+	for name, value := range templateContent {
+		if s, ok := value.(string); ok {
+			templateContent[name] = escape(s)
+		}
+	}
+	if jsonMediaType(contentType) {
+		templateContent["result"] = string(resultJSON)
+	}
+
+	return templateContent
 }
 
 // Send notification to customer.  At this point we have done basic validation on notification and event
 func (this *WebHookSender) Send(n *obj.Notification, e *obj.Event) error {
+	options := n.Webhook
+	if options == nil {
+		options = &obj.WebhookOptions{}
+	}
 
-	fullResult, err := NewFullCheckResult(e.Result)
+	body, err := fullCheckResultJSON(e)
 	if err != nil {
 		return err
 	}
 
-	body, err := json.Marshal(fullResult)
+	method := options.Method
+	if method == "" {
+		method = "POST"
+	}
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	if options.Template != "" {
+		tmpl, err := mustache.ParseString(options.Template)
+		if err != nil {
+			return err
+		}
+		body = []byte(tmpl.Render(webhookTemplateContent(e, body, contentType)))
+	}
+
+	secret, err := this.credentials.WebhookSecret(n.CustomerId)
 	if err != nil {
 		return err
 	}
 
-	buff := bytes.NewBufferString(string(body))
+	req, err := http.NewRequest(method, n.Value, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range options.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if secret != "" {
+		webhook.SignRequest(req, secret, time.Now(), body)
+	}
 
-	resp, err := http.Post(n.Value, "application/json", buff)
+	resp, err := this.client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
 
 	if resp.StatusCode >= 300 {
 		return fmt.Errorf("Remote server returned status code %d: %s", resp.StatusCode, resp.Status)
@@ -109,6 +278,6 @@ func (this *WebHookSender) Send(n *obj.Notification, e *obj.Event) error {
 	return nil
 }
 
-func NewWebHookSender() (*WebHookSender, error) {
-	return &WebHookSender{}, nil
+func NewWebHookSender(credentials Credentials, client *WebhookClient) (*WebHookSender, error) {
+	return &WebHookSender{credentials: credentials, client: client}, nil
 }
@@ -0,0 +1,162 @@
+package notifier
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookClient makes requests to customer supplied webhook URLs. It refuses
+// to connect to denied networks, checking the address of every connection
+// after DNS resolution so a hostname or redirect can't be used to reach them,
+// and bounds how long requests take, how many redirects they follow and how
+// much of the response is read.
+type WebhookClient struct {
+	client          *http.Client
+	dialer          *net.Dialer
+	denied          []*net.IPNet
+	maxResponseSize int64
+}
+
+func NewWebhookClient(connectTimeout, timeout time.Duration, deniedNetworks []string, maxRedirects int, maxResponseSize int64) (*WebhookClient, error) {
+	denied := []*net.IPNet{}
+	for _, cidr := range deniedNetworks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		denied = append(denied, network)
+	}
+
+	c := &WebhookClient{
+		dialer: &net.Dialer{
+			Timeout:   connectTimeout,
+			KeepAlive: 30 * time.Second,
+		},
+		denied:          denied,
+		maxResponseSize: maxResponseSize,
+	}
+
+	c.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// never go through a proxy, which would connect on our behalf
+			Proxy:                 nil,
+			Dial:                  c.dial,
+			TLSHandshakeTimeout:   connectTimeout,
+			ResponseHeaderTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("can't redirect to %s", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+
+	return c, nil
+}
+
+// Do sends a request. Only the first maxResponseSize bytes of the response
+// body can be read.
+func (c *WebhookClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &limitedBody{Reader: io.LimitReader(resp.Body, c.maxResponseSize), Closer: resp.Body}
+	return resp, nil
+}
+
+// CheckURL returns an error for webhook URLs that aren't http(s) or whose
+// host is, or resolves to, a denied address. Hosts that don't resolve are
+// allowed, every connection is checked again when webhooks are delivered.
+func (c *WebhookClient) CheckURL(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("webhook url must be http or https")
+	}
+
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" {
+		return fmt.Errorf("webhook url must have a host")
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return nil
+		}
+	}
+
+	for _, ip := range ips {
+		if c.deniedIP(ip) {
+			return fmt.Errorf("webhook host %s is not allowed", host)
+		}
+	}
+
+	return nil
+}
+
+func (c *WebhookClient) deniedIP(ip net.IP) bool {
+	for _, network := range c.denied {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// dial resolves the host of every connection itself and connects to the
+// first allowed address, so the address checked is the one connected to.
+func (c *WebhookClient) dial(network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ip := range ips {
+		if c.deniedIP(ip) {
+			return nil, fmt.Errorf("webhook address %s is not allowed", ip)
+		}
+	}
+
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = c.dialer.Dial(network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
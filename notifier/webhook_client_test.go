@@ -0,0 +1,83 @@
+package notifier
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/hugstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookClientDeniedNetworks(t *testing.T) {
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
+
+	client, err := NewWebhookClient(time.Second, 5*time.Second, config.DefaultWebhookDeniedNetworks, config.DefaultWebhookMaxRedirects, config.DefaultWebhookMaxResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", hook.URL+"/hook", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Do(req)
+	assert.NotNil(t, err)
+
+	req, err = http.NewRequest("POST", strings.Replace(hook.URL, "127.0.0.1", "localhost", 1)+"/hook", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Do(req)
+	assert.NotNil(t, err)
+	assert.Empty(t, hook.Requests(""))
+
+	for _, rawurl := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://127.0.0.1:8080/",
+		"http://[::1]/",
+		"http://10.1.2.3/hook",
+		"ftp://example.com/hook",
+		"example.com/hook",
+	} {
+		assert.NotNil(t, client.CheckURL(rawurl), rawurl)
+	}
+	assert.Nil(t, client.CheckURL("https://93.184.216.34/hook"))
+}
+
+func TestWebhookClientLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/redirect", http.StatusFound)
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 1024)))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewWebhookClient(time.Second, 5*time.Second, nil, 2, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/redirect", nil)
+	_, err = client.Do(req)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "stopped after 2 redirects")
+	}
+
+	req, _ = http.NewRequest("GET", server.URL+"/large", nil)
+	resp, err := client.Do(req)
+	if assert.Nil(t, err) {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Len(t, body, 16)
+	}
+}
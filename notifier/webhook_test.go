@@ -1,41 +1,44 @@
 package notifier
 
 import (
-	"io/ioutil"
-	"net/http"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/hugstest"
 	"github.com/opsee/hugs/obj"
+	"github.com/opsee/hugs/store"
+	"github.com/opsee/hugs/webhook"
 	log "github.com/opsee/logrus"
+	opsee_types "github.com/opsee/protobuf/opseeproto/types"
+	"github.com/stretchr/testify/assert"
 )
 
-func webhooktest(rw http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
+// testWebhookClient can reach the fakes on localhost.
+func testWebhookClient(t *testing.T) *WebhookClient {
+	client, err := NewWebhookClient(time.Second, 5*time.Second, nil, config.DefaultWebhookMaxRedirects, config.DefaultWebhookMaxResponse)
 	if err != nil {
-		log.Error(err)
-	} else {
-		log.Info("Test webhook endpoint got: ", string(body))
+		t.Fatal(err)
 	}
-}
-
-func setupWebhookTestServer() {
-	http.HandleFunc("/hook", webhooktest)
-	log.Fatal(http.ListenAndServe(":8888", nil))
+	return client
 }
 
 func TestWebHookNotifier(t *testing.T) {
-	go setupWebhookTestServer()
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
 
 	notif := &obj.Notification{
 		CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
 		UserId:     13,
 		CheckId:    "test",
-		Value:      "http://localhost:8888/hook",
+		Value:      hook.URL + "/hook",
 		Type:       "webhook",
 	}
 	event := obj.GenerateTestEvent()
 
-	webhookSender, err := NewWebHookSender()
+	webhookSender, err := NewWebHookSender(&StoreCredentials{Store: store.NewMemory()}, testWebhookClient(t))
 	if err != nil {
 		log.Error(err)
 		t.FailNow()
@@ -46,4 +49,190 @@ func TestWebHookNotifier(t *testing.T) {
 		log.Error(err)
 		t.FailNow()
 	}
+
+	requests := hook.Requests("/hook")
+	if assert.Len(t, requests, 1) {
+		log.Info("Test webhook endpoint got: ", string(requests[0].Body))
+		assert.Equal(t, "POST", requests[0].Method)
+		assert.Equal(t, "", requests[0].Header.Get(webhook.SignatureHeader))
+	}
+}
+
+func TestWebHookNotifierSigned(t *testing.T) {
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
+
+	notif := &obj.Notification{
+		CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+		UserId:     13,
+		CheckId:    "test",
+		Value:      hook.URL + "/hook",
+		Type:       "webhook",
+	}
+
+	db := store.NewMemory()
+	secret := obj.NewWebhookSecret(notif.CustomerId)
+	if err := db.PutWebhookSecret(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	webhookSender, err := NewWebHookSender(&StoreCredentials{Store: db}, testWebhookClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := webhookSender.Send(notif, obj.GenerateTestEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := hook.Requests("/hook")
+	if assert.Len(t, requests, 1) {
+		assert.Nil(t, webhook.Verify(secret.Secret, requests[0].Header, requests[0].Body, webhook.DefaultTolerance))
+		assert.Equal(t, webhook.ErrInvalidSignature, webhook.Verify("wrong", requests[0].Header, requests[0].Body, webhook.DefaultTolerance))
+	}
+}
+
+func TestWebHookNotifierOptions(t *testing.T) {
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
+
+	notif := &obj.Notification{
+		CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+		UserId:     13,
+		CheckId:    "test",
+		Value:      hook.URL + "/api/alerts",
+		Type:       "webhook",
+		Webhook: &obj.WebhookOptions{
+			Method:      "PUT",
+			ContentType: "application/x-www-form-urlencoded",
+			Headers:     map[string]string{"Authorization": "Bearer token"},
+			Template:    "check={{{check_id}}}&name={{{check_name}}}&state={{{state}}}",
+		},
+	}
+
+	webhookSender, err := NewWebHookSender(&StoreCredentials{Store: store.NewMemory()}, testWebhookClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CheckName = `a "check" & more`
+	if err := webhookSender.Send(notif, event); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := hook.Requests("/api/alerts")
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "PUT", requests[0].Method)
+		assert.Equal(t, "Bearer token", requests[0].Header.Get("Authorization"))
+		assert.Equal(t, "application/x-www-form-urlencoded", requests[0].Header.Get("Content-Type"))
+		assert.Equal(t, event.Result.CheckId, requests[0].Form.Get("check"))
+		assert.Equal(t, event.Result.CheckName, requests[0].Form.Get("name"))
+		assert.Equal(t, "failing", requests[0].Form.Get("state"))
+	}
+}
+
+func TestWebHookNotifierJSONTemplate(t *testing.T) {
+	hook := hugstest.NewWebhook()
+	defer hook.Close()
+
+	notif := &obj.Notification{
+		CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+		UserId:     13,
+		CheckId:    "test",
+		Value:      hook.URL + "/api/alerts",
+		Type:       "webhook",
+		Webhook: &obj.WebhookOptions{
+			Template: `{"name": "{{{check_name}}}", "failing": {{{fail_count}}}, "result": {{{result}}}}`,
+		},
+	}
+
+	webhookSender, err := NewWebHookSender(&StoreCredentials{Store: store.NewMemory()}, testWebhookClient(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CheckName = "a \"check\" & <more>\n"
+	if err := webhookSender.Send(notif, event); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := hook.Requests("/api/alerts")
+	if assert.Len(t, requests, 1) {
+		body := struct {
+			Name    string          `json:"name"`
+			Failing int             `json:"failing"`
+			Result  FullCheckResult `json:"result"`
+		}{}
+		if assert.Nil(t, json.Unmarshal(requests[0].Body, &body)) {
+			assert.Equal(t, event.Result.CheckName, body.Name)
+			assert.Equal(t, len(event.Result.FailingResponses()), body.Failing)
+			assert.Equal(t, event.Result.CheckId, body.Result.CheckId)
+			assert.Equal(t, event.Result.CheckName, body.Result.CheckName)
+		}
+	}
+}
+
+func TestNewFullCheckResultResponseTypes(t *testing.T) {
+	httpAny, err := opsee_types.MarshalAny(&schema.HttpResponse{Code: 200, Body: "ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloudWatchAny, err := opsee_types.MarshalAny(&schema.CloudWatchResponse{
+		Namespace: "AWS/RDS",
+		Metrics:   []*schema.Metric{{Name: "CPUUtilization", Value: 42}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &schema.CheckResult{
+		CheckId: "00000",
+		Responses: []*schema.CheckResponse{
+			{Response: httpAny},
+			{Response: cloudWatchAny},
+			{Response: &opsee_types.Any{TypeUrl: "SomethingNew", Value: []byte{1, 2, 3}}},
+			{Reply: &schema.CheckResponse_HttpResponse{HttpResponse: &schema.HttpResponse{Code: 503}}},
+			{Error: "timed out"},
+			{Response: &opsee_types.Any{TypeUrl: "type.googleapis.com/opsee.HttpResponse", Value: httpAny.Value}},
+		},
+	}
+
+	full, err := NewFullCheckResult(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, full.Responses, 6) {
+		return
+	}
+
+	httpResponse := &schema.HttpResponse{}
+	assert.Equal(t, "HttpResponse", full.Responses[0].ResponseType)
+	assert.Nil(t, json.Unmarshal(*full.Responses[0].Response, httpResponse))
+	assert.Equal(t, int32(200), httpResponse.Code)
+
+	cloudWatch := &schema.CloudWatchResponse{}
+	assert.Equal(t, "CloudWatchResponse", full.Responses[1].ResponseType)
+	assert.Nil(t, json.Unmarshal(*full.Responses[1].Response, cloudWatch))
+	assert.Equal(t, "AWS/RDS", cloudWatch.Namespace)
+
+	unknown := &UnknownResponse{}
+	assert.Equal(t, "SomethingNew", full.Responses[2].ResponseType)
+	assert.Nil(t, json.Unmarshal(*full.Responses[2].Response, unknown))
+	assert.Equal(t, []byte{1, 2, 3}, unknown.Value)
+	assert.Contains(t, string(*full.Responses[2].Response), `"value":"AQID"`)
+
+	assert.Equal(t, "HttpResponse", full.Responses[3].ResponseType)
+	assert.Nil(t, json.Unmarshal(*full.Responses[3].Response, httpResponse))
+	assert.Equal(t, int32(503), httpResponse.Code)
+
+	assert.Nil(t, full.Responses[4].Response)
+	assert.Equal(t, "timed out", full.Responses[4].Error)
+
+	// fully qualified type urls are named the same as the rest
+	httpResponse = &schema.HttpResponse{}
+	assert.Equal(t, "HttpResponse", full.Responses[5].ResponseType)
+	assert.Nil(t, json.Unmarshal(*full.Responses[5].Response, httpResponse))
+	assert.Equal(t, int32(200), httpResponse.Code)
 }
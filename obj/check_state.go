@@ -0,0 +1,84 @@
+package obj
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/util"
+)
+
+// CheckState is the last passing/failing state we notified a customer about
+// for a (customer, check, target).
+type CheckState struct {
+	CustomerId string `json:"customer_id" db:"customer_id" required:"true"`
+	CheckId    string `json:"check_id" db:"check_id" required:"true"`
+	TargetId   string `json:"target_id" db:"target_id"`
+	Passing    bool   `json:"passing" db:"passing"`
+	// Observed is the state of the latest results. It only becomes the
+	// notified Passing state once it has held long enough to meet the check's
+	// threshold.
+	Observed      bool      `json:"observed" db:"observed"`
+	Consecutive   int       `json:"consecutive" db:"consecutive"`
+	ObservedSince time.Time `json:"observed_since" db:"observed_since"`
+	// Transitions are the times of the state changes we notified about
+	// within the flap detection window.
+	Transitions Timestamps `json:"transitions" db:"transitions"`
+	Flapping    bool       `json:"flapping" db:"flapping"`
+	// FlapCount is the number of state changes since the check started
+	// flapping at FlappingSince.
+	FlapCount     int        `json:"flap_count" db:"flap_count"`
+	FlappingSince *time.Time `json:"flapping_since,omitempty" db:"flapping_since"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (this *CheckState) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(this)
+}
+
+// NewCheckState returns the state described by a CheckResult.
+func NewCheckState(result *schema.CheckResult) *CheckState {
+	now := time.Now().UTC()
+	state := &CheckState{
+		CustomerId:    result.CustomerId,
+		CheckId:       result.CheckId,
+		Passing:       result.Passing,
+		Observed:      result.Passing,
+		Consecutive:   1,
+		ObservedSince: now,
+		UpdatedAt:     now,
+	}
+	if result.Target != nil {
+		state.TargetId = result.Target.Id
+	}
+	return state
+}
+
+// Timestamps is stored as a jsonb column.
+type Timestamps []time.Time
+
+func (this Timestamps) Value() (driver.Value, error) {
+	if this == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(this)
+}
+
+func (this *Timestamps) Scan(src interface{}) error {
+	var source []byte
+	switch t := src.(type) {
+	case []byte:
+		source = t
+	case string:
+		source = []byte(t)
+	case nil:
+		*this = Timestamps{}
+		return nil
+	default:
+		return fmt.Errorf("incompatible type for Timestamps: %T", src)
+	}
+	return json.Unmarshal(source, this)
+}
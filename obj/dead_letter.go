@@ -0,0 +1,70 @@
+package obj
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/util"
+)
+
+// DeadLetter is a CheckResult we gave up on, along with the reasons we could
+// not deliver notifications for it.
+type DeadLetter struct {
+	Id          int                 `json:"id" db:"id"`
+	CustomerId  string              `json:"customer_id" db:"customer_id" required:"true"`
+	CheckId     string              `json:"check_id" db:"check_id" required:"true"`
+	Result      []byte              `json:"-" db:"result" required:"true"`
+	Reasons     types.JSONText      `json:"reasons" db:"reasons" required:"true"`
+	Attempts    int                 `json:"attempts" db:"attempts"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	ReplayedAt  *time.Time          `json:"replayed_at,omitempty" db:"replayed_at"`
+	CheckResult *schema.CheckResult `json:"check_result,omitempty" db:"-"`
+}
+
+func (this *DeadLetter) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(this)
+}
+
+// NewDeadLetter returns a dead letter holding the marshalled CheckResult.
+func NewDeadLetter(result *schema.CheckResult, attempts int, reasons []string) (*DeadLetter, error) {
+	resultBytes, err := proto.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if reasons == nil {
+		reasons = []string{}
+	}
+	reasonBytes, err := json.Marshal(reasons)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetter{
+		CustomerId:  result.CustomerId,
+		CheckId:     result.CheckId,
+		Result:      resultBytes,
+		Reasons:     types.JSONText(reasonBytes),
+		Attempts:    attempts,
+		CreatedAt:   time.Now().UTC(),
+		CheckResult: result,
+	}, nil
+}
+
+// UnmarshalResult decodes the parked CheckResult into CheckResult.
+func (this *DeadLetter) UnmarshalResult() error {
+	result := &schema.CheckResult{}
+	if err := proto.Unmarshal(this.Result, result); err != nil {
+		return err
+	}
+	this.CheckResult = result
+	return nil
+}
+
+type DeadLetters struct {
+	DeadLetters []*DeadLetter `json:"dead_letters"`
+}
@@ -0,0 +1,86 @@
+package obj
+
+import (
+	"time"
+
+	"github.com/opsee/hugs/util"
+)
+
+const (
+	// DeliveryStatusSent is recorded when a Sender delivered a notification.
+	DeliveryStatusSent = "sent"
+	// DeliveryStatusRetrying is recorded when a delivery failed and another
+	// attempt has been scheduled.
+	DeliveryStatusRetrying = "retrying"
+	// DeliveryStatusFailed is recorded when we gave up on a delivery.
+	DeliveryStatusFailed = "failed"
+	// DeliveryStatusSilenced is recorded instead of sending a notification
+	// while a silence matches the check.
+	DeliveryStatusSilenced = "silenced"
+	// DeliveryStatusFlapping is recorded instead of sending a notification
+	// while the check is flapping.
+	DeliveryStatusFlapping = "flapping"
+	// DeliveryStatusAcknowledged is recorded instead of sending a
+	// notification while the check's incident is acknowledged.
+	DeliveryStatusAcknowledged = "acknowledged"
+	// DeliveryStatusRateLimited is recorded when a Sender dropped a
+	// notification to stay within a rate limit.
+	DeliveryStatusRateLimited = "rate_limited"
+)
+
+// SuppressedError is returned by a Sender that deliberately didn't send a
+// notification. The delivery is recorded with Status and isn't retried.
+type SuppressedError struct {
+	Status string
+	Reason string
+}
+
+func (e *SuppressedError) Error() string {
+	return e.Reason
+}
+
+// Delivery is a single attempt at sending a notification to a customer.
+type Delivery struct {
+	Id             int       `json:"id" db:"id"`
+	CustomerId     string    `json:"customer_id" db:"customer_id" required:"true"`
+	NotificationId int       `json:"notification_id" db:"notification_id"`
+	CheckId        string    `json:"check_id" db:"check_id" required:"true"`
+	Type           string    `json:"type" db:"type" required:"true"`
+	Passing        bool      `json:"passing" db:"passing"`
+	Status         string    `json:"status" db:"status" required:"true"`
+	Error          string    `json:"error" db:"error"`
+	Attempt        int       `json:"attempt" db:"attempt"`
+	LatencyMs      int64     `json:"latency_ms" db:"latency_ms"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+func (this *Delivery) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(this)
+}
+
+// NewDelivery returns a delivery record for a notification sent for an event.
+func NewDelivery(n *Notification, e *Event, status string, err error) *Delivery {
+	delivery := &Delivery{
+		CustomerId:     n.CustomerId,
+		NotificationId: n.Id,
+		CheckId:        e.Result.CheckId,
+		Type:           n.Type,
+		Passing:        e.Result.Passing,
+		Status:         status,
+		Attempt:        1,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if delivery.CustomerId == "" {
+		delivery.CustomerId = e.Result.CustomerId
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	return delivery
+}
+
+type Deliveries struct {
+	CheckId    string      `json:"check_id"`
+	Deliveries []*Delivery `json:"deliveries"`
+}
@@ -0,0 +1,97 @@
+package obj
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/opsee/hugs/util"
+)
+
+// EscalationStep notifies a set of targets once a check has been failing for
+// Delay seconds.
+type EscalationStep struct {
+	Delay         int             `json:"delay"`
+	Notifications []*Notification `json:"notifications" required:"true"`
+}
+
+func (this *EscalationStep) Validate() error {
+	validator := &util.Validator{}
+	if err := validator.Validate(this); err != nil {
+		return err
+	}
+	if this.Delay < 0 {
+		return errors.New("delay must not be negative")
+	}
+	for _, notification := range this.Notifications {
+		if err := notification.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DelayDuration returns the step's delay as a time.Duration.
+func (this *EscalationStep) DelayDuration() time.Duration {
+	return time.Duration(this.Delay) * time.Second
+}
+
+// EscalationSteps is stored as a jsonb column.
+type EscalationSteps []*EscalationStep
+
+func (this EscalationSteps) Value() (driver.Value, error) {
+	return json.Marshal(this)
+}
+
+func (this *EscalationSteps) Scan(src interface{}) error {
+	var source []byte
+	switch t := src.(type) {
+	case []byte:
+		source = t
+	case string:
+		source = []byte(t)
+	case nil:
+		*this = EscalationSteps{}
+		return nil
+	default:
+		return fmt.Errorf("incompatible type for EscalationSteps: %T", src)
+	}
+	return json.Unmarshal(source, this)
+}
+
+// EscalationPolicy is the ordered list of steps we walk through while a check
+// keeps failing.
+type EscalationPolicy struct {
+	CustomerId string          `json:"customer_id" db:"customer_id"`
+	CheckId    string          `json:"check_id" db:"check_id"`
+	Steps      EscalationSteps `json:"steps" db:"steps" required:"true"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+func (this *EscalationPolicy) Validate() error {
+	validator := &util.Validator{}
+	if err := validator.Validate(this); err != nil {
+		return err
+	}
+	if len(this.Steps) == 0 {
+		return errors.New("escalation policy must have at least one step")
+	}
+
+	previous := 0
+	for _, step := range this.Steps {
+		if step == nil {
+			return errors.New("escalation step must not be null")
+		}
+		if err := step.Validate(); err != nil {
+			return err
+		}
+		if step.Delay < previous {
+			return errors.New("escalation steps must be ordered by delay")
+		}
+		previous = step.Delay
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package obj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscalationPolicyValidate(t *testing.T) {
+	slack := &Notification{Type: "slack_bot", Value: "#ops"}
+	email := &Notification{Type: "email", Value: "ops@example.com"}
+
+	policy := &EscalationPolicy{
+		CheckId: "check",
+		Steps: EscalationSteps{
+			{Delay: 0, Notifications: []*Notification{slack}},
+			{Delay: 600, Notifications: []*Notification{email}},
+		},
+	}
+	assert.Nil(t, policy.Validate())
+
+	policy.Steps[0].Delay = 900
+	assert.NotNil(t, policy.Validate())
+
+	assert.NotNil(t, (&EscalationPolicy{Steps: EscalationSteps{}}).Validate())
+	assert.NotNil(t, (&EscalationPolicy{Steps: EscalationSteps{{Delay: -1, Notifications: []*Notification{slack}}}}).Validate())
+	assert.NotNil(t, (&EscalationPolicy{Steps: EscalationSteps{{Notifications: []*Notification{{Type: "email"}}}}}).Validate())
+}
+
+func TestEscalationStepsScan(t *testing.T) {
+	steps := EscalationSteps{{Delay: 60, Notifications: []*Notification{{Type: "email", Value: "ops@example.com"}}}}
+
+	value, err := steps.Value()
+	assert.Nil(t, err)
+
+	scanned := EscalationSteps{}
+	assert.Nil(t, scanned.Scan(value))
+	assert.Equal(t, steps, scanned)
+}
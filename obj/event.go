@@ -1,6 +1,8 @@
 package obj
 
 import (
+	"time"
+
 	"github.com/opsee/basic/schema"
 	"github.com/opsee/hugs/util"
 	log "github.com/opsee/logrus"
@@ -16,6 +18,21 @@ type Event struct {
 	Result *schema.CheckResult
 	Nocap  *NocapResponse
 	Test   bool
+	// Flapping is set when the event is about a check changing state too
+	// often, rather than about a single state change.
+	Flapping *Flapping
+	// Acknowledged is set on events telling services that someone is handling
+	// the check's incident.
+	Acknowledged bool
+}
+
+// Flapping describes a check that changed state too often for us to notify
+// about every change.
+type Flapping struct {
+	// Stable is set on the summary sent once the check has settled down.
+	Stable      bool      `json:"stable"`
+	Transitions int       `json:"transitions"`
+	Since       time.Time `json:"since"`
 }
 
 func (this *Event) Validate() error {
@@ -0,0 +1,29 @@
+package obj
+
+import (
+	"time"
+
+	"github.com/opsee/hugs/util"
+)
+
+// Incident spans the time a check is failing, from the first failing
+// notification until it recovers. Acknowledging it stops re-notifications
+// and escalations for the check.
+type Incident struct {
+	Id             int        `json:"id" db:"id"`
+	CustomerId     string     `json:"customer_id" db:"customer_id" required:"true"`
+	CheckId        string     `json:"check_id" db:"check_id" required:"true"`
+	OpenedAt       time.Time  `json:"opened_at" db:"opened_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	AcknowledgedBy *int       `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+func (this *Incident) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(this)
+}
+
+func (this *Incident) Acknowledged() bool {
+	return this.AcknowledgedAt != nil
+}
@@ -1,19 +1,34 @@
 package obj
 
 import (
+	"errors"
+	"net/url"
+	"regexp"
+
 	_ "github.com/lib/pq"
 	"github.com/opsee/hugs/util"
 )
 
+// E.164 phone numbers: a plus, a country code and up to 15 digits in total.
+var e164Number = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// SNS topic ARNs, arn:aws:sns:<region>:<account>:<topic>.
+var snsTopicArn = regexp.MustCompile(`^arn:aws[a-z-]*:sns:[a-z0-9-]+:[0-9]{12}:[A-Za-z0-9_-]{1,256}(\.fifo)?$`)
+
 type Notifications struct {
 	CheckId       string          `json:"check-id"`
 	Notifications []*Notification `json:"notifications" db:"notifications"`
+	Threshold     *Threshold      `json:"threshold,omitempty"`
 }
 
 func (this *Notifications) Validate() error {
-	validator := &util.Validator{}
+	if this.Threshold != nil {
+		if err := this.Threshold.Validate(); err != nil {
+			return err
+		}
+	}
 	for _, notification := range this.Notifications {
-		if err := validator.Validate(notification); err != nil {
+		if err := notification.Validate(); err != nil {
 			return err
 		}
 	}
@@ -27,9 +42,46 @@ type Notification struct {
 	CheckId    string `json:"check_id" db:"check_id"`
 	Value      string `json:"value" db:"value" required:"true"`
 	Type       string `json:"type" db:"type" required:"true"`
+	// Webhook customises the request made by webhook notifications.
+	Webhook *WebhookOptions `json:"webhook,omitempty" db:"webhook"`
 }
 
 func (this *Notification) Validate() error {
 	validator := &util.Validator{}
-	return validator.Validate(this)
+	if err := validator.Validate(this); err != nil {
+		return err
+	}
+
+	if this.Webhook != nil {
+		if this.Type != "webhook" {
+			return errors.New("only webhook notifications can have webhook options")
+		}
+		if err := this.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch this.Type {
+	case "webhook":
+		u, err := url.Parse(this.Value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook notification value must be an http or https url")
+		}
+	case "msteams":
+		// teams incoming webhooks are always https
+		u, err := url.Parse(this.Value)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("msteams notification value must be an https webhook url")
+		}
+	case "sms":
+		if !e164Number.MatchString(this.Value) {
+			return errors.New("sms notification value must be an E.164 phone number, e.g. +14155552671")
+		}
+	case "sns":
+		if !snsTopicArn.MatchString(this.Value) {
+			return errors.New("sns notification value must be a topic arn")
+		}
+	}
+
+	return nil
 }
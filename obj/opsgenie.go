@@ -0,0 +1,122 @@
+package obj
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/opsee/hugs/util"
+	log "github.com/opsee/logrus"
+)
+
+const (
+	// Alert API paths, relative to the configured Opsgenie API URL.
+	OpsgenieAlertsPath = "/v2/alerts"
+
+	// Opsgenie truncates alert messages longer than this.
+	OpsgenieMessageLimit = 130
+)
+
+// OpsgenieAlertPath returns the path of an action on the alert with the given
+// alias, e.g. close or acknowledge.
+func OpsgenieAlertPath(alias, action string) string {
+	return fmt.Sprintf("%s/%s/%s", OpsgenieAlertsPath, url.QueryEscape(alias), action)
+}
+
+// OpsgenieAlertRequest creates an alert. Alerts with the same alias are
+// deduplicated by Opsgenie while the first one is open.
+type OpsgenieAlertRequest struct {
+	Message     string            `json:"message" required:"true"`
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+}
+
+func (og *OpsgenieAlertRequest) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(og)
+}
+
+// OpsgenieActionRequest closes or acknowledges an alert.
+type OpsgenieActionRequest struct {
+	Source string `json:"source,omitempty"`
+	User   string `json:"user,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// OpsgenieResponse is returned by every alert API request. Requests are
+// processed asynchronously, so success only means the request was accepted.
+type OpsgenieResponse struct {
+	Result    string  `json:"result"`
+	Message   string  `json:"message"`
+	Took      float64 `json:"took"`
+	RequestId string  `json:"requestId"`
+}
+
+// OpsgenieDo posts an alert API request authorized with apiKey.
+func OpsgenieDo(apiKey, url string, request interface{}) (*OpsgenieResponse, error) {
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug(string(bodyBytes))
+	ogResponse := &OpsgenieResponse{}
+	if err := json.Unmarshal(bodyBytes, ogResponse); err != nil && resp.StatusCode < 300 {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return ogResponse, fmt.Errorf("Opsgenie returned status code %d: %s", resp.StatusCode, ogResponse.Message)
+	}
+
+	return ogResponse, nil
+}
+
+type OpsgenieIntegrationDBWrapper struct {
+	Id         int            `json:"id" db:"id"`
+	CustomerId string         `json:"customer_id" db:"customer_id" required:"true"`
+	Data       types.JSONText `json:"data" db:"data"`
+}
+
+func (og *OpsgenieIntegrationDBWrapper) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(og)
+}
+
+// OpsgenieIntegration is a customer's Opsgenie API integration.
+type OpsgenieIntegration struct {
+	APIKey  string `json:"api_key" db:"api_key" required:"true"`
+	Enabled bool   `json:"enabled" db:"enabled"`
+}
+
+func (og *OpsgenieIntegration) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(og)
+}
@@ -3,15 +3,30 @@ package obj
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/jmoiron/sqlx/types"
 	"github.com/opsee/hugs/util"
 	log "github.com/opsee/logrus"
 )
 
-const PagerDutyIntegrationsAPIEndpoint = "https://events.pagerduty.com/generic/2010-04-15/create_event.json"
+var pagerDutyClient = &http.Client{
+	Timeout: 15 * time.Second,
+}
+
+const (
+	// Events API paths, relative to the configured PagerDuty events URL.
+	PagerDutyEventsV1Path = "/generic/2010-04-15/create_event.json"
+	PagerDutyEventsV2Path = "/v2/enqueue"
+
+	// Integrations stored before Events API v2 support have no version and
+	// keep using v1.
+	PagerDutyAPIVersion1 = "v1"
+	PagerDutyAPIVersion2 = "v2"
+)
 
 type PagerDutyContext struct {
 	Type string `json:"type" required:"true"`
@@ -34,7 +49,7 @@ func (pd *PagerDutyRequest) Validate() error {
 	return validator.Validate(pd)
 }
 
-func (pdr *PagerDutyRequest) Do() (*PagerDutyResponse, error) {
+func (pdr *PagerDutyRequest) Do(url string) (*PagerDutyResponse, error) {
 	if err := pdr.Validate(); err != nil {
 		return nil, err
 	}
@@ -44,7 +59,7 @@ func (pdr *PagerDutyRequest) Do() (*PagerDutyResponse, error) {
 		return nil, err
 	}
 
-	resp, err := http.Post(PagerDutyIntegrationsAPIEndpoint, "application/json", bytes.NewReader(reqBody))
+	resp, err := pagerDutyClient.Post(url, "application/json", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +80,87 @@ func (pdr *PagerDutyRequest) Do() (*PagerDutyResponse, error) {
 	return pdResponse, nil
 }
 
+// PagerDutyV2Request is an Events API v2 event.
+type PagerDutyV2Request struct {
+	RoutingKey  string              `json:"routing_key" required:"true"`
+	EventAction string              `json:"event_action" required:"true"`
+	DedupKey    string              `json:"dedup_key,omitempty"`
+	Payload     *PagerDutyV2Payload `json:"payload,omitempty"`
+	Client      string              `json:"client,omitempty"`
+	ClientURL   string              `json:"client_url,omitempty"`
+	Links       []*PagerDutyV2Link  `json:"links,omitempty"`
+	Images      []*PagerDutyV2Image `json:"images,omitempty"`
+}
+
+// PagerDutyV2Payload is required when triggering an event.
+type PagerDutyV2Payload struct {
+	Summary       string      `json:"summary"`
+	Source        string      `json:"source"`
+	Severity      string      `json:"severity"`
+	Timestamp     string      `json:"timestamp,omitempty"`
+	Component     string      `json:"component,omitempty"`
+	Group         string      `json:"group,omitempty"`
+	Class         string      `json:"class,omitempty"`
+	CustomDetails interface{} `json:"custom_details,omitempty"`
+}
+
+type PagerDutyV2Link struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+type PagerDutyV2Image struct {
+	Src  string `json:"src"`
+	Href string `json:"href,omitempty"`
+	Alt  string `json:"alt,omitempty"`
+}
+
+type PagerDutyV2Response struct {
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	DedupKey string   `json:"dedup_key"`
+	Errors   []string `json:"errors"`
+}
+
+func (pd *PagerDutyV2Request) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(pd)
+}
+
+func (pdr *PagerDutyV2Request) Do(url string) (*PagerDutyV2Response, error) {
+	if err := pdr.Validate(); err != nil {
+		return nil, err
+	}
+
+	reqBody, err := json.Marshal(pdr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pagerDutyClient.Post(url, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	pdResponse := &PagerDutyV2Response{}
+	if err := json.Unmarshal(bodyBytes, pdResponse); err != nil && resp.StatusCode < 300 {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"status_code": resp.StatusCode, "dedup_key": pdResponse.DedupKey}).Debug("PagerDuty responded to event.")
+
+	if resp.StatusCode >= 300 {
+		return pdResponse, fmt.Errorf("PagerDuty returned status code %d: %s %v", resp.StatusCode, pdResponse.Message, pdResponse.Errors)
+	}
+
+	return pdResponse, nil
+}
+
 type PagerDutyBadRequest struct {
 	Errors string `json:"errors"`
 }
@@ -93,10 +189,27 @@ type PagerDutyOAuthResponse struct {
 	ServiceKey  string `json:"service_key" db:"service_key"`
 	ServiceName string `json:"service_name" db:"service_name"`
 	Enabled     bool   `json:"enabled" db:"enabled"`
+	// APIVersion selects the Events API to send to, v1 if empty.
+	APIVersion string `json:"api_version,omitempty" db:"api_version"`
 	PagerDutyResponse
 }
 
 func (pd *PagerDutyOAuthResponse) Validate() error {
 	validator := &util.Validator{}
-	return validator.Validate(pd)
+	if err := validator.Validate(pd); err != nil {
+		return err
+	}
+	switch pd.APIVersion {
+	case "", PagerDutyAPIVersion1, PagerDutyAPIVersion2:
+		return nil
+	}
+	return fmt.Errorf("Unknown PagerDuty API version %s", pd.APIVersion)
+}
+
+// Version returns the Events API version the integration uses.
+func (pd *PagerDutyOAuthResponse) Version() string {
+	if pd.APIVersion == "" {
+		return PagerDutyAPIVersion1
+	}
+	return pd.APIVersion
 }
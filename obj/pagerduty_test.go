@@ -0,0 +1,31 @@
+package obj
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerDutyV2RequestTimeout(t *testing.T) {
+	// PagerDuty accepts the connection, but never answers
+	stall := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stall
+	}))
+	defer server.Close()
+	defer close(stall)
+
+	timeout := pagerDutyClient.Timeout
+	pagerDutyClient.Timeout = 100 * time.Millisecond
+	defer func() { pagerDutyClient.Timeout = timeout }()
+
+	request := &PagerDutyV2Request{RoutingKey: "routing-key", EventAction: "resolve", DedupKey: "check"}
+
+	start := time.Now()
+	_, err := request.Do(server.URL + PagerDutyEventsV2Path)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second, "request outlived the client timeout")
+}
@@ -0,0 +1,61 @@
+package obj
+
+import (
+	"errors"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/util"
+)
+
+// Silence mutes notifications for a customer during a time window. It applies
+// to a single check when CheckId is set, to a single target when TargetId is
+// set, and to every check the customer has otherwise.
+type Silence struct {
+	Id         int       `json:"id" db:"id"`
+	CustomerId string    `json:"customer_id" db:"customer_id"`
+	UserId     int       `json:"user_id" db:"user_id"`
+	CheckId    string    `json:"check_id" db:"check_id"`
+	TargetId   string    `json:"target_id" db:"target_id"`
+	Reason     string    `json:"reason" db:"reason"`
+	StartsAt   time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt     time.Time `json:"ends_at" db:"ends_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+func (this *Silence) Validate() error {
+	validator := &util.Validator{}
+	if err := validator.Validate(this); err != nil {
+		return err
+	}
+	if this.StartsAt.IsZero() || this.EndsAt.IsZero() {
+		return errors.New("starts_at and ends_at are required")
+	}
+	if !this.EndsAt.After(this.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// Active reports whether the silence is in effect at the given time.
+func (this *Silence) Active(at time.Time) bool {
+	return !at.Before(this.StartsAt) && at.Before(this.EndsAt)
+}
+
+// Matches reports whether the silence applies to a CheckResult.
+func (this *Silence) Matches(result *schema.CheckResult) bool {
+	if this.CustomerId != result.CustomerId {
+		return false
+	}
+	if this.CheckId != "" && this.CheckId != result.CheckId {
+		return false
+	}
+	if this.TargetId != "" && (result.Target == nil || this.TargetId != result.Target.Id) {
+		return false
+	}
+	return true
+}
+
+type Silences struct {
+	Silences []*Silence `json:"silences"`
+}
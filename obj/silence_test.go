@@ -0,0 +1,42 @@
+package obj
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	result := &schema.CheckResult{
+		CustomerId: "customer",
+		CheckId:    "check",
+		Target:     &schema.Target{Id: "sg-1234"},
+	}
+
+	assert.True(t, (&Silence{CustomerId: "customer"}).Matches(result))
+	assert.True(t, (&Silence{CustomerId: "customer", CheckId: "check"}).Matches(result))
+	assert.True(t, (&Silence{CustomerId: "customer", TargetId: "sg-1234"}).Matches(result))
+	assert.False(t, (&Silence{CustomerId: "other"}).Matches(result))
+	assert.False(t, (&Silence{CustomerId: "customer", CheckId: "other"}).Matches(result))
+	assert.False(t, (&Silence{CustomerId: "customer", TargetId: "sg-5678"}).Matches(result))
+}
+
+func TestSilenceActive(t *testing.T) {
+	now := time.Now()
+	silence := &Silence{StartsAt: now, EndsAt: now.Add(time.Hour)}
+
+	assert.True(t, silence.Active(now))
+	assert.True(t, silence.Active(now.Add(time.Minute)))
+	assert.False(t, silence.Active(now.Add(-time.Minute)))
+	assert.False(t, silence.Active(now.Add(time.Hour)))
+}
+
+func TestSilenceValidate(t *testing.T) {
+	now := time.Now()
+
+	assert.Nil(t, (&Silence{StartsAt: now, EndsAt: now.Add(time.Hour)}).Validate())
+	assert.NotNil(t, (&Silence{StartsAt: now, EndsAt: now}).Validate())
+	assert.NotNil(t, (&Silence{EndsAt: now}).Validate())
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx/types"
 	"github.com/nlopes/slack"
@@ -149,6 +150,10 @@ func (this *SlackOAuthRequest) Do(endpoint string) (*SlackOAuthResponse, error)
 
 type SlackPostChatMessageResponse struct {
 	SlackResponse
+	// Channel is the id of the channel the message was posted to, and Ts
+	// the message's timestamp, which identifies it within the channel.
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
 }
 
 type SlackPostChatMessageRequest struct {
@@ -166,6 +171,9 @@ type SlackPostChatMessageRequest struct {
 	IconEmoji   string             `json:"icon_emoji"`
 	Markdown    bool               `json:"mrkdwn,omitempty"`
 	EscapeText  bool               `json:"escape_text"`
+	// ThreadTs posts the message as a reply to the thread of the message
+	// with this timestamp.
+	ThreadTs string `json:"thread_ts,omitempty"`
 }
 
 // unescapes for chars like ' " etc, escapes slack control characters
@@ -183,14 +191,43 @@ func (this *SlackPostChatMessageRequest) Do(endpoint string) (*SlackPostChatMess
 	/*
 		https://slack.com/api/chat.postMessage
 	*/
+	values, err := this.values()
+	if err != nil {
+		return nil, err
+	}
+	values.Set("username", string(this.Username))
+	values.Set("as_user", "false")
+	values.Set("icon_url", this.IconURL)
+	if this.ThreadTs != "" {
+		values.Set("thread_ts", this.ThreadTs)
+	}
+
+	return postChatMessage(endpoint, values, "chat.postMessage")
+}
+
+// Update replaces the attachments of the message with timestamp ts in
+// channel with this message's.
+func (this *SlackPostChatMessageRequest) Update(endpoint, channel, ts string) (*SlackPostChatMessageResponse, error) {
+	/*
+		https://slack.com/api/chat.update
+	*/
+	values, err := this.values()
+	if err != nil {
+		return nil, err
+	}
+	values.Set("channel", channel)
+	values.Set("ts", ts)
+
+	return postChatMessage(endpoint, values, "chat.update")
+}
+
+func (this *SlackPostChatMessageRequest) values() (url.Values, error) {
 	values := url.Values{
 		"token":   {this.Token},
 		"channel": {this.Channel},
 	}
 
 	this.prepareText()
-	values.Set("username", string(this.Username))
-	values.Set("as_user", "false")
 	if this.Attachments != nil {
 		attachments, err := json.Marshal(this.Attachments)
 		if err != nil {
@@ -198,13 +235,17 @@ func (this *SlackPostChatMessageRequest) Do(endpoint string) (*SlackPostChatMess
 		}
 		values.Set("attachments", string(attachments))
 	}
-	values.Set("icon_url", this.IconURL)
 	values.Set("parse", "full")
 
+	return values, nil
+}
+
+func postChatMessage(endpoint string, values url.Values, method string) (*SlackPostChatMessageResponse, error) {
 	resp, err := http.PostForm(endpoint, values)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	slackResponse := &SlackPostChatMessageResponse{}
 
@@ -214,11 +255,41 @@ func (this *SlackPostChatMessageRequest) Do(endpoint string) (*SlackPostChatMess
 		return nil, err
 	}
 
+	// the response is returned with the error so callers can see why
 	if !slackResponse.OK {
 		err = fmt.Errorf("Slack Error: %s", slackResponse.Error)
-		log.WithError(err).Error("Slack chat.postMessage failed.")
-		return nil, err
+		log.WithError(err).Errorf("Slack %s failed.", method)
+		return slackResponse, err
 	}
 
 	return slackResponse, nil
 }
+
+// SlackThreadTTL is how long a thread is replied to. A thread whose recovery
+// was never posted, e.g. because the notification was removed or the recovery
+// silenced, would otherwise be replied to whenever the check next fails.
+const SlackThreadTTL = 24 * time.Hour
+
+// SlackThread is the message a check's open failure was posted as in a
+// channel. Later results for the check are posted in its thread until the
+// check recovers or the thread expires.
+type SlackThread struct {
+	CustomerId string `json:"customer_id" db:"customer_id" required:"true"`
+	CheckId    string `json:"check_id" db:"check_id" required:"true"`
+	// Channel is the channel the notification is configured with, which
+	// may be a name, and ChannelId the id Slack posted the message to.
+	Channel   string    `json:"channel" db:"channel" required:"true"`
+	ChannelId string    `json:"channel_id" db:"channel_id" required:"true"`
+	Ts        string    `json:"ts" db:"ts" required:"true"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (this *SlackThread) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(this)
+}
+
+// Expired is whether the thread is older than SlackThreadTTL.
+func (this *SlackThread) Expired(now time.Time) bool {
+	return now.Sub(this.CreatedAt) > SlackThreadTTL
+}
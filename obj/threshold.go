@@ -0,0 +1,36 @@
+package obj
+
+import (
+	"errors"
+	"time"
+
+	"github.com/opsee/hugs/util"
+)
+
+// Threshold holds off notifying about a check changing state until the new
+// state has been seen in Consecutive results in a row and for at least
+// Duration seconds. It applies to failures and recoveries alike. A threshold
+// stored without a CheckId is the customer's default.
+type Threshold struct {
+	CustomerId  string `json:"-" db:"customer_id"`
+	CheckId     string `json:"-" db:"check_id"`
+	Consecutive int    `json:"consecutive" db:"consecutive"`
+	Duration    int    `json:"duration" db:"duration"`
+}
+
+func (this *Threshold) Validate() error {
+	validator := &util.Validator{}
+	if err := validator.Validate(this); err != nil {
+		return err
+	}
+	if this.Consecutive < 0 || this.Duration < 0 {
+		return errors.New("threshold must not be negative")
+	}
+	return nil
+}
+
+// Met reports whether a state seen in consecutive results for the given time
+// satisfies the threshold.
+func (this *Threshold) Met(consecutive int, held time.Duration) bool {
+	return consecutive >= this.Consecutive && held >= time.Duration(this.Duration)*time.Second
+}
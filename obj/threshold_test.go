@@ -0,0 +1,31 @@
+package obj
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdMet(t *testing.T) {
+	assert.True(t, (&Threshold{}).Met(1, 0))
+
+	consecutive := &Threshold{Consecutive: 3}
+	assert.False(t, consecutive.Met(2, time.Hour))
+	assert.True(t, consecutive.Met(3, 0))
+
+	duration := &Threshold{Duration: 300}
+	assert.False(t, duration.Met(10, 4*time.Minute))
+	assert.True(t, duration.Met(1, 5*time.Minute))
+
+	both := &Threshold{Consecutive: 3, Duration: 300}
+	assert.False(t, both.Met(3, time.Minute))
+	assert.False(t, both.Met(2, 10*time.Minute))
+	assert.True(t, both.Met(3, 5*time.Minute))
+}
+
+func TestThresholdValidate(t *testing.T) {
+	assert.Nil(t, (&Threshold{Consecutive: 3}).Validate())
+	assert.NotNil(t, (&Threshold{Consecutive: -1}).Validate())
+	assert.NotNil(t, (&Threshold{Duration: -1}).Validate())
+}
@@ -0,0 +1,80 @@
+package obj
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/opsee/logrus"
+)
+
+var twilioClient = &http.Client{
+	Timeout: 15 * time.Second,
+}
+
+// TwilioMessagesPath returns the path messages are created at for an account,
+// relative to the configured Twilio API URL.
+func TwilioMessagesPath(accountSid string) string {
+	return fmt.Sprintf("/2010-04-01/Accounts/%s/Messages.json", accountSid)
+}
+
+// TwilioMessageRequest is a text message to send.
+type TwilioMessageRequest struct {
+	To   string
+	From string
+	Body string
+}
+
+// TwilioMessage is the message resource Twilio returns when a message is
+// queued, or an error when it isn't.
+type TwilioMessage struct {
+	Sid     string `json:"sid"`
+	Status  string `json:"status"`
+	To      string `json:"to"`
+	From    string `json:"from"`
+	Body    string `json:"body"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Do creates the message at endpoint, authenticated as accountSid.
+func (tm *TwilioMessageRequest) Do(endpoint, accountSid, authToken string) (*TwilioMessage, error) {
+	form := url.Values{}
+	form.Set("To", tm.To)
+	form.Set("From", tm.From)
+	form.Set("Body", tm.Body)
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(accountSid, authToken)
+
+	resp, err := twilioClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug(string(bodyBytes))
+	message := &TwilioMessage{}
+	if err := json.Unmarshal(bodyBytes, message); err != nil && resp.StatusCode < 300 {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return message, fmt.Errorf("Twilio returned status code %d: %d %s", resp.StatusCode, message.Code, message.Message)
+	}
+
+	return message, nil
+}
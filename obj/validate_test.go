@@ -33,3 +33,77 @@ func TestValidatorIsInvalid(t *testing.T) {
 		log.WithFields(log.Fields{"test": "TestValidatorIsInvalid", "error": err}).Debug("received error.")
 	}
 }
+
+func TestValidateMSTeamsNotification(t *testing.T) {
+	n := Notification{CustomerId: "test", Type: "msteams", Value: "https://outlook.office.com/webhook/abc"}
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"http://outlook.office.com/webhook/abc", "outlook.office.com/webhook/abc", "https://"} {
+		n.Value = value
+		if err := n.Validate(); err == nil {
+			t.Fatalf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestValidateSMSNotification(t *testing.T) {
+	n := Notification{CustomerId: "test", Type: "sms", Value: "+14155552671"}
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"4155552671", "+1 415 555 2671", "+04155552671", "+1234567890123456", "+"} {
+		n.Value = value
+		if err := n.Validate(); err == nil {
+			t.Fatalf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestValidateSNSNotification(t *testing.T) {
+	n := Notification{CustomerId: "test", Type: "sns", Value: "arn:aws:sns:us-west-2:123456789012:alerts"}
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"alerts", "arn:aws:sqs:us-west-2:123456789012:alerts", "arn:aws:sns:us-west-2:1234:alerts"} {
+		n.Value = value
+		if err := n.Validate(); err == nil {
+			t.Fatalf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestValidateWebhookOptions(t *testing.T) {
+	n := Notification{CustomerId: "test", Type: "webhook", Value: "https://example.com/hook", Webhook: &WebhookOptions{
+		Method:      "PUT",
+		ContentType: "application/x-www-form-urlencoded",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		Template:    "check={{{check_id}}}&state={{{state}}}{{#flapping}}&flapping=1{{/flapping}}",
+	}}
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, options := range []*WebhookOptions{
+		{Method: "GET"},
+		{ContentType: "json; ="},
+		{Headers: map[string]string{"Bad Header": "value"}},
+		{Headers: map[string]string{"x-hugs-signature": "forged"}},
+		{Headers: map[string]string{"X-Token": "value\r\nX-Injected: true"}},
+		{Template: "{{#check_id}}"},
+		{Template: `{"name": "{{check_name}}"}`},
+	} {
+		n.Webhook = options
+		if err := n.Validate(); err == nil {
+			t.Fatalf("expected %+v to be invalid", options)
+		}
+	}
+
+	n = Notification{CustomerId: "test", Type: "email", Value: "dan@opsee.com", Webhook: &WebhookOptions{}}
+	if err := n.Validate(); err == nil {
+		t.Fatal("expected webhook options on an email notification to be invalid")
+	}
+}
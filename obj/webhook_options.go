@@ -0,0 +1,153 @@
+package obj
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/hoisie/mustache"
+	"github.com/opsee/hugs/webhook"
+)
+
+// WebhookMethods are the HTTP methods a webhook notification may use.
+var WebhookMethods = []string{"POST", "PUT", "PATCH"}
+
+// headers hugs sets itself, which webhook options can't override
+var reservedWebhookHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Host",
+	"Transfer-Encoding",
+	webhook.SignatureHeader,
+	webhook.TimestampHeader,
+}
+
+// WebhookOptions customise the request a webhook notification makes, so that
+// hugs can call an API directly rather than through an adapter. Without
+// options a webhook POSTs the full check result as JSON.
+type WebhookOptions struct {
+	// Method defaults to POST.
+	Method string `json:"method,omitempty"`
+	// ContentType defaults to application/json.
+	ContentType string            `json:"content_type,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// Template is a mustache template for the request body, rendered over
+	// the event's check_id, check_name, customer_id, passing, state,
+	// timestamp, target_id, target_name, target_type, fail_count,
+	// response_count, flapping, acknowledged and result, the full check
+	// result JSON. Values are inserted with triple mustaches, e.g.
+	// {"name": "{{{check_name}}}", "result": {{{result}}}}, and escaped for
+	// the content type: JSON string escaping for JSON bodies, URL encoding
+	// for forms.
+	Template string `json:"template,omitempty"`
+}
+
+// RedactedHeaderValue replaces webhook header values, which usually hold
+// credentials, in API responses. Sending it back keeps the stored value.
+const RedactedHeaderValue = "[redacted]"
+
+// mustacheTag matches mustache tags, triple mustaches included.
+var mustacheTag = regexp.MustCompile(`\{\{(\{?)\s*(.*?)\s*\}\}\}?`)
+
+func (this *WebhookOptions) Validate() error {
+	if this.Method != "" {
+		valid := false
+		for _, method := range WebhookMethods {
+			if this.Method == method {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("webhook method must be one of %s", strings.Join(WebhookMethods, ", "))
+		}
+	}
+
+	if this.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(this.ContentType); err != nil {
+			return fmt.Errorf("invalid webhook content type: %s", err)
+		}
+	}
+
+	for name, value := range this.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid webhook header name %q", name)
+		}
+		for _, reserved := range reservedWebhookHeaders {
+			if http.CanonicalHeaderKey(name) == reserved {
+				return fmt.Errorf("webhook header %s can't be set", reserved)
+			}
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid value for webhook header %s", name)
+		}
+	}
+
+	if this.Template != "" {
+		if _, err := mustache.ParseString(this.Template); err != nil {
+			return fmt.Errorf("invalid webhook template: %s", err)
+		}
+
+		// double mustaches HTML escape values, which breaks JSON and forms
+		for _, tag := range mustacheTag.FindAllStringSubmatch(this.Template, -1) {
+			if tag[1] == "" && (tag[2] == "" || !strings.ContainsAny(tag[2][:1], "#^/!&")) {
+				return fmt.Errorf("invalid webhook template: use {{{%s}}} rather than {{%s}} to insert values", tag[2], tag[2])
+			}
+		}
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of the options with the header values redacted.
+func (this *WebhookOptions) Redacted() *WebhookOptions {
+	if this == nil {
+		return nil
+	}
+
+	redacted := *this
+	if this.Headers != nil {
+		redacted.Headers = map[string]string{}
+		for name := range this.Headers {
+			redacted.Headers[name] = RedactedHeaderValue
+		}
+	}
+	return &redacted
+}
+
+// validHeaderName is whether name is an RFC 7230 token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookOptions is stored as a nullable jsonb column.
+func (this WebhookOptions) Value() (driver.Value, error) {
+	return json.Marshal(this)
+}
+
+func (this *WebhookOptions) Scan(src interface{}) error {
+	var source []byte
+	switch t := src.(type) {
+	case []byte:
+		source = t
+	case string:
+		source = []byte(t)
+	default:
+		return fmt.Errorf("incompatible type for WebhookOptions: %T", src)
+	}
+	return json.Unmarshal(source, this)
+}
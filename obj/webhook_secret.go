@@ -0,0 +1,31 @@
+package obj
+
+import (
+	"time"
+
+	"github.com/opsee/hugs/util"
+)
+
+// WebhookSecretLength is the length of generated webhook signing secrets.
+const WebhookSecretLength = 40
+
+// WebhookSecret is the key a customer's webhook deliveries are signed with.
+type WebhookSecret struct {
+	CustomerId string    `json:"customer_id" db:"customer_id" required:"true"`
+	Secret     string    `json:"secret" db:"secret" required:"true"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewWebhookSecret generates a new random signing secret for a customer.
+func NewWebhookSecret(customerId string) *WebhookSecret {
+	return &WebhookSecret{
+		CustomerId: customerId,
+		Secret:     util.RandomString(WebhookSecretLength),
+		CreatedAt:  time.Now(),
+	}
+}
+
+func (ws *WebhookSecret) Validate() error {
+	validator := &util.Validator{}
+	return validator.Validate(ws)
+}
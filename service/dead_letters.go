@@ -0,0 +1,124 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/consumer"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) getDeadLetters() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		var limit int
+
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		query, ok := ctx.Value(queryKey).(url.Values)
+		if ok && query.Get("limit") != "" {
+			l, err := strconv.Atoi(query.Get("limit"))
+			if err != nil || l < 1 {
+				return nil, http.StatusBadRequest, errors.New("limit must be a positive integer.")
+			}
+			limit = l
+		}
+
+		deadLetters, err := s.db.GetDeadLetters(user, limit)
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getDeadLetters", "error": err}).Error("Couldn't get dead letters from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return &obj.DeadLetters{DeadLetters: deadLetters}, http.StatusOK, nil
+	}
+}
+
+func (s *Service) getDeadLetter() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		id, err := idParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		deadLetter, err := s.db.GetDeadLetter(user, id)
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.New("Dead letter not found.")
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getDeadLetter", "error": err}).Error("Couldn't get dead letter from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return deadLetter, http.StatusOK, nil
+	}
+}
+
+// Sends the parked CheckResult through the notification pipeline again.
+func (s *Service) postDeadLetterReplay() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		id, err := idParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		deadLetter, err := s.db.GetDeadLetter(user, id)
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.New("Dead letter not found.")
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"service": "postDeadLetterReplay", "error": err}).Error("Couldn't get dead letter from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		var force bool
+		query, ok := ctx.Value(queryKey).(url.Values)
+		if ok && query.Get("force") != "" {
+			f, err := strconv.ParseBool(query.Get("force"))
+			if err != nil {
+				return nil, http.StatusBadRequest, errors.New("force must be a boolean.")
+			}
+			force = f
+		}
+
+		// only marked replayed once its notifications went out
+		err = s.pipeline.Replay(deadLetter.CheckResult, force)
+		switch err.(type) {
+		case nil:
+		case *consumer.ReplaySkippedError:
+			return nil, http.StatusConflict, err
+		case *consumer.UndeliveredError:
+			log.WithFields(log.Fields{"service": "postDeadLetterReplay", "error": err}).Error("Couldn't deliver dead letter.")
+			return nil, http.StatusBadGateway, err
+		default:
+			log.WithFields(log.Fields{"service": "postDeadLetterReplay", "error": err}).Error("Couldn't replay dead letter.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		if err := s.db.MarkDeadLetterReplayed(deadLetter); err != nil {
+			log.WithFields(log.Fields{"service": "postDeadLetterReplay", "error": err}).Error("Couldn't mark dead letter replayed.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+// Lists the most recent notification deliveries for a check, newest first.
+func (s *Service) getDeliveriesByCheckId() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		var (
+			checkId string
+			limit   int
+		)
+
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get user from request context.")
+		}
+
+		params, ok := ctx.Value(paramsKey).(httprouter.Params)
+		if ok && params.ByName("check_id") != "" {
+			checkId = params.ByName("check_id")
+		}
+
+		if checkId == "" {
+			return nil, http.StatusBadRequest, errors.New("Must specify check-id in request.")
+		}
+
+		query, ok := ctx.Value(queryKey).(url.Values)
+		if ok && query.Get("limit") != "" {
+			l, err := strconv.Atoi(query.Get("limit"))
+			if err != nil || l < 1 {
+				return nil, http.StatusBadRequest, errors.New("limit must be a positive integer.")
+			}
+			limit = l
+		}
+
+		deliveries, err := s.db.GetDeliveriesByCheckId(user, checkId, limit)
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getDeliveriesByCheckId", "error": err}).Error("Couldn't get deliveries from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return &obj.Deliveries{CheckId: checkId, Deliveries: deliveries}, http.StatusOK, nil
+	}
+}
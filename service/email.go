@@ -7,7 +7,6 @@ import (
 
 	"github.com/opsee/basic/schema"
 	"github.com/opsee/basic/tp"
-	"github.com/opsee/hugs/config"
 	"github.com/opsee/hugs/notifier"
 	"github.com/opsee/hugs/obj"
 	log "github.com/opsee/logrus"
@@ -21,7 +20,13 @@ func (s *Service) postEmailTest() tp.HandleFunc {
 			return ctx, http.StatusUnauthorized, errors.New("Unable to get User from request context")
 		}
 
-		emailSender, err := notifier.NewEmailSender(config.GetConfig().OpseeHost, config.GetConfig().MandrillApiKey)
+		mailer, err := notifier.NewMailer(s.config)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"service": "postEmailTest"}).Error("Couldn't get mailer.")
+			return ctx, http.StatusBadRequest, errUnknown
+		}
+
+		emailSender, err := notifier.NewEmailSender(s.senders.Cats, s.config.OpseeHost, mailer)
 		if err != nil {
 			log.WithFields(log.Fields{"service": "postEmailTest"}).Error("Couldn't get email sender.")
 			return ctx, http.StatusBadRequest, errUnknown
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) getEscalationPolicy() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		checkId, err := checkIdParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		policy, err := s.db.GetEscalationPolicy(user, checkId)
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getEscalationPolicy", "error": err}).Error("Couldn't get escalation policy from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		if policy == nil {
+			return nil, http.StatusNotFound, errors.New("Check has no escalation policy.")
+		}
+
+		return redactEscalationPolicy(policy), http.StatusOK, nil
+	}
+}
+
+// Creates or replaces the escalation policy for a check.
+func (s *Service) putEscalationPolicy() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		checkId, err := checkIdParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		request, ok := ctx.Value(requestKey).(*obj.EscalationPolicy)
+		if !ok {
+			return nil, http.StatusBadRequest, errUnknown
+		}
+
+		request.CheckId = checkId
+		stored, err := s.db.GetEscalationPolicy(user, checkId)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		storedNotifications := []*obj.Notification{}
+		if stored != nil {
+			for _, step := range stored.Steps {
+				storedNotifications = append(storedNotifications, step.Notifications...)
+			}
+		}
+
+		for _, step := range request.Steps {
+			if err := s.checkWebhookURLs(step.Notifications); err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			if err := restoreWebhookHeaders(step.Notifications, storedNotifications); err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+		}
+
+		if err := s.db.PutEscalationPolicy(user, request); err != nil {
+			log.WithFields(log.Fields{"service": "putEscalationPolicy", "error": err}).Error("Couldn't put escalation policy in database.")
+			return nil, http.StatusBadRequest, err
+		}
+
+		policy, err := s.db.GetEscalationPolicy(user, checkId)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return redactEscalationPolicy(policy), http.StatusOK, nil
+	}
+}
+
+func (s *Service) deleteEscalationPolicy() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		checkId, err := checkIdParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		if err := s.db.DeleteEscalationPolicy(user, checkId); err != nil {
+			log.WithFields(log.Fields{"service": "deleteEscalationPolicy", "error": err}).Error("Couldn't delete escalation policy from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+}
+
+// redactEscalationPolicy returns a copy of a policy with the webhook header
+// values of its notifications redacted.
+func redactEscalationPolicy(policy *obj.EscalationPolicy) *obj.EscalationPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	redacted := *policy
+	redacted.Steps = obj.EscalationSteps{}
+	for _, step := range policy.Steps {
+		s := *step
+		s.Notifications = redactWebhookHeaders(step.Notifications)
+		redacted.Steps = append(redacted.Steps, &s)
+	}
+	return &redacted
+}
+
+func checkIdParam(ctx context.Context) (string, error) {
+	params, ok := ctx.Value(paramsKey).(httprouter.Params)
+	if !ok || params.ByName("check_id") == "" {
+		return "", errors.New("Must specify check-id in request.")
+	}
+	return params.ByName("check_id"), nil
+}
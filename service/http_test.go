@@ -5,61 +5,23 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	//"golang.org/x/net/context"
 
 	"github.com/opsee/basic/schema"
 	"github.com/opsee/basic/tp"
 	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/hugstest"
 	"github.com/opsee/hugs/obj"
 	"github.com/opsee/hugs/store"
+	"github.com/opsee/hugs/webhook"
 	log "github.com/opsee/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
-func webhooktest(rw http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		log.Error(err)
-	} else {
-		log.Info("Test webhook endpoint got: ", string(body))
-	}
-}
-
-// emulates slack api endpoints, provides endpoint for /services/webhook/test
-func startTestServer() {
-	oaResponse := &obj.SlackOAuthResponse{
-		AccessToken: "test",
-		Scope:       "test",
-		TeamName:    "test",
-		TeamId:      "test",
-		IncomingWebhook: &obj.SlackIncomingWebhook{
-			URL:              "test",
-			Channel:          "test",
-			ConfigurationURL: "test",
-		},
-		Bot: &obj.SlackBotCreds{
-			BotUserId:      "test",
-			BotAccessToken: "test",
-		},
-	}
-
-	oaResponseData, err := json.Marshal(oaResponse)
-	if err != nil {
-		log.Fatal(err)
-	}
-	// emulate slack oauth endpoint
-	http.HandleFunc("/api/oauth.access", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, string(oaResponseData), r.URL.Path)
-	})
-	http.HandleFunc("/hook", webhooktest)
-
-	log.Fatal(http.ListenAndServe(":7766", nil))
-}
-
 func GetUserAuthToken(user *schema.User) string {
 	userstring := fmt.Sprintf(`{"id": %d, "customer_id": "%s", "user_id": "%s", "email": "%s", "verified": %t, "admin": %t, "active": %t}`, user.Id, user.CustomerId, user.Id, user.Email, user.Verified, user.Admin, user.Active)
 	token := base64.StdEncoding.EncodeToString([]byte(userstring))
@@ -85,6 +47,11 @@ type ServiceTest struct {
 	Notifications []*obj.Notification
 	User          *schema.User
 	UserToken     string
+	Slack         *hugstest.Slack
+	PagerDuty     *hugstest.PagerDuty
+	Opsgenie      *hugstest.Opsgenie
+	Mandrill      *hugstest.Mandrill
+	Webhook       *hugstest.Webhook
 }
 
 func NewServiceTest() *ServiceTest {
@@ -113,6 +80,23 @@ func NewServiceTest() *ServiceTest {
 		log.Warn("Warning: Couldn't clear local test obj of notifications")
 	}
 
+	// point every outbound api at the in-process fakes before the service
+	// builds its senders
+	log.Info("Starting api emulators...")
+	slackFake := hugstest.NewSlack()
+	pagerDutyFake := hugstest.NewPagerDuty()
+	opsgenieFake := hugstest.NewOpsgenie()
+	mandrillFake := hugstest.NewMandrill()
+	webhookFake := hugstest.NewWebhook()
+
+	cfg := config.GetConfig()
+	cfg.SlackAPIURL = slackFake.URL
+	cfg.PagerDutyEventsURL = pagerDutyFake.URL
+	cfg.OpsgenieAPIURL = opsgenieFake.URL
+	cfg.MandrillAPIURL = mandrillFake.URL + "/"
+	// the webhook fake listens on localhost
+	cfg.WebhookDeniedNetworks = nil
+
 	service, err := NewService()
 	if err != nil {
 		log.Fatal("Failed to create service: ", err)
@@ -123,6 +107,11 @@ func NewServiceTest() *ServiceTest {
 		Router:    service.NewRouter(),
 		User:      user,
 		UserToken: userAuthToken,
+		Slack:     slackFake,
+		PagerDuty: pagerDutyFake,
+		Opsgenie:  opsgenieFake,
+		Mandrill:  mandrillFake,
+		Webhook:   webhookFake,
 		Notifications: []*obj.Notification{
 			&obj.Notification{
 				Id:         0,
@@ -145,15 +134,13 @@ func NewServiceTest() *ServiceTest {
 				CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
 				UserId:     13,
 				CheckId:    "00000",
-				Value:      "someslackhook.com",
+				Value:      "https://someslackhook.com",
 				Type:       "webhook",
 			},
 		},
 	}
 
 	serviceTest.Service.router = serviceTest.Router
-	log.Info("Starting slack api emulator...")
-	go startTestServer()
 
 	log.Info("Adding initial notifications to obj...")
 	err = serviceTest.Service.db.PutNotifications(user, serviceTest.Notifications)
@@ -163,7 +150,7 @@ func NewServiceTest() *ServiceTest {
 
 	log.Info("Adding initial slack oauth shit to obj...")
 	slackOAuthResponse := &obj.SlackOAuthResponse{
-		AccessToken: config.GetConfig().SlackTestToken,
+		AccessToken: slackFake.Token,
 		Scope:       "bot",
 		TeamName:    "opsee",
 		TeamId:      "opsee",
@@ -175,7 +162,7 @@ func NewServiceTest() *ServiceTest {
 		},
 		Bot: &obj.SlackBotCreds{
 			BotUserId:      "test",
-			BotAccessToken: config.GetConfig().SlackTestToken,
+			BotAccessToken: slackFake.Token,
 		},
 	}
 
@@ -429,6 +416,220 @@ func TestGetNotificationsByCheckId(t *testing.T) {
 	}
 }
 
+func TestWebhookHeadersRedacted(t *testing.T) {
+	put := func(headers map[string]string) *httptest.ResponseRecorder {
+		cn := &obj.Notifications{
+			Notifications: []*obj.Notification{
+				&obj.Notification{
+					Value:   Common.Webhook.URL + "/redacted",
+					Type:    "webhook",
+					Webhook: &obj.WebhookOptions{Headers: headers},
+				}},
+		}
+
+		cnBytes, err := json.Marshal(cn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("PUT", fmt.Sprintf("%s/notifications/redacted", Common.Service.config.PublicHost), bytes.NewReader(cnBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", Common.UserToken)
+
+		rw := httptest.NewRecorder()
+		Common.Service.router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	rw := put(map[string]string{"Authorization": "Bearer secret"})
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	assert.NotContains(t, rw.Body.String(), "Bearer secret")
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/notifications/redacted", Common.Service.config.PublicHost), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", Common.UserToken)
+	rw = httptest.NewRecorder()
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	var resp obj.Notifications
+	if err := json.Unmarshal(rw.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, resp.Notifications, 1) {
+		assert.Equal(t, obj.RedactedHeaderValue, resp.Notifications[0].Webhook.Headers["Authorization"])
+	}
+
+	// sending the redacted value back keeps the stored one
+	rw = put(map[string]string{"Authorization": obj.RedactedHeaderValue})
+	assert.Equal(t, http.StatusCreated, rw.Code)
+
+	stored, err := Common.Service.db.GetNotificationsByCheckId(Common.User, "redacted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, stored, 1) {
+		assert.Equal(t, "Bearer secret", stored[0].Webhook.Headers["Authorization"])
+	}
+
+	rw = put(map[string]string{"X-Unknown": obj.RedactedHeaderValue})
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestGetDeliveriesByCheckId(t *testing.T) {
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CustomerId = Common.User.CustomerId
+	delivery := obj.NewDelivery(Common.Notifications[0], event, obj.DeliveryStatusSent, nil)
+	if err := Common.Service.db.PutDelivery(delivery); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/notifications/%s/deliveries?limit=10", Common.Service.config.PublicHost, event.Result.CheckId), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	var resp obj.Deliveries
+
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, event.Result.CheckId, resp.CheckId)
+	if len(resp.Deliveries) == 0 {
+		t.FailNow()
+	}
+}
+
+func TestGetDeadLetters(t *testing.T) {
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CustomerId = Common.User.CustomerId
+	deadLetter, err := obj.NewDeadLetter(event.Result, 1, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Common.Service.db.PutDeadLetter(deadLetter); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/dead-letters/%d", Common.Service.config.PublicHost, deadLetter.Id), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	var resp obj.DeadLetter
+
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, deadLetter.Id, resp.Id)
+	if resp.CheckResult == nil {
+		t.FailNow()
+	}
+
+	req, err = http.NewRequest("GET", fmt.Sprintf("%s/dead-letters/%d", Common.Service.config.PublicHost, deadLetter.Id+1000), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw = httptest.NewRecorder()
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestPostSilence(t *testing.T) {
+	now := time.Now().UTC()
+	silence := &obj.Silence{
+		CheckId:  "00001",
+		Reason:   "deploy",
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	}
+
+	body, err := json.Marshal(silence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/silences", Common.Service.config.PublicHost), bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusCreated, rw.Code)
+
+	var resp obj.Silence
+
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, 0, resp.Id)
+	assert.Equal(t, Common.User.CustomerId, resp.CustomerId)
+	assert.Equal(t, "00001", resp.CheckId)
+}
+
+func TestPostIncidentAck(t *testing.T) {
+	incident, err := Common.Service.db.OpenIncident(Common.User.CustomerId, "00003")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/incidents/00003/ack", Common.Service.config.PublicHost), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	var resp obj.Incident
+
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, incident.Id, resp.Id)
+	assert.True(t, resp.Acknowledged())
+
+	if err := Common.Service.db.ResolveIncident(Common.User.CustomerId, "00003"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDeleteNotification(t *testing.T) {
 	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/notifications/00002", Common.Service.config.PublicHost), nil)
 	if err != nil {
@@ -454,7 +655,13 @@ func TestGetSlackChannels(t *testing.T) {
 	rw := httptest.NewRecorder()
 	Common.Service.router.ServeHTTP(rw, req)
 	log.WithFields(log.Fields{"TestGetSlackChannels": "Got channel list."}).Info(rw.Body)
+	assert.Equal(t, http.StatusOK, rw.Code)
 
+	var resp obj.SlackChannels
+	if err := json.Unmarshal(rw.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Common.Slack.Channels, resp.Channels)
 }
 
 func TestGetSlackToken(t *testing.T) {
@@ -478,12 +685,13 @@ func TestGetSlackToken(t *testing.T) {
 
 	log.WithFields(log.Fields{"TestGetSlackToken": "Got slack token."}).Info(resp)
 	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, Common.Slack.TeamDomain, resp.TeamDomain)
 }
 
 // Note that this should fail because code will be invalid.
 func TestPostSlackCode(t *testing.T) {
 	oar := &obj.SlackOAuthRequest{
-		Code:        "test",
+		Code:        hugstest.SlackInvalidCode,
 		RedirectURI: "test",
 	}
 
@@ -507,7 +715,6 @@ func TestPostSlackCode(t *testing.T) {
 }
 
 // Test posting a message to slack
-/*
 func TestPostSlackTest(t *testing.T) {
 	cn := &obj.Notifications{
 		Notifications: []*obj.Notification{
@@ -539,6 +746,9 @@ func TestPostSlackTest(t *testing.T) {
 	Common.Service.router.ServeHTTP(rw, req)
 	log.Info(string(rw.Body.Bytes()))
 	assert.Equal(t, http.StatusOK, rw.Code)
+	if messages := Common.Slack.Messages(); assert.NotEmpty(t, messages) {
+		assert.Equal(t, "C0ADACATT", messages[len(messages)-1].Form.Get("channel"))
+	}
 }
 
 func TestPostEmailTest(t *testing.T) {
@@ -572,8 +782,15 @@ func TestPostEmailTest(t *testing.T) {
 	Common.Service.router.ServeHTTP(rw, req)
 	log.Info(string(rw.Body.Bytes()))
 	assert.Equal(t, http.StatusOK, rw.Code)
+
+	messages := Common.Mandrill.Messages()
+	if assert.NotEmpty(t, messages) {
+		msg := messages[len(messages)-1]
+		assert.Equal(t, "check-pass-json", msg.TemplateName)
+		assert.Equal(t, "dan@opsee.com", msg.Message.To[0].Email)
+	}
 }
-*/
+
 func TestPostWebHookTest(t *testing.T) {
 	cn := &obj.Notifications{
 		Notifications: []*obj.Notification{
@@ -582,7 +799,7 @@ func TestPostWebHookTest(t *testing.T) {
 				CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
 				UserId:     13,
 				CheckId:    "00002",
-				Value:      "http://localhost:7766/hook",
+				Value:      Common.Webhook.URL + "/hook",
 				Type:       "webhook",
 			}},
 	}
@@ -605,4 +822,131 @@ func TestPostWebHookTest(t *testing.T) {
 	Common.Service.router.ServeHTTP(rw, req)
 	log.Info(string(rw.Body.Bytes()))
 	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NotEmpty(t, Common.Webhook.Requests("/hook"))
+}
+
+func TestWebhookSecret(t *testing.T) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/services/webhook/secret", Common.Service.config.PublicHost), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	secret := &obj.WebhookSecret{}
+	if err := json.Unmarshal(rw.Body.Bytes(), secret); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, secret.Secret, obj.WebhookSecretLength)
+
+	cn := &obj.Notifications{
+		Notifications: []*obj.Notification{
+			&obj.Notification{
+				CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+				UserId:     13,
+				CheckId:    "00002",
+				Value:      Common.Webhook.URL + "/signed",
+				Type:       "webhook",
+			}},
+	}
+
+	notifs, err := json.Marshal(cn)
+	if err != nil {
+		t.FailNow()
+	}
+
+	req, err = http.NewRequest("POST", fmt.Sprintf("%s/services/webhook/test", Common.Service.config.PublicHost), bytes.NewReader(notifs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw = httptest.NewRecorder()
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	requests := Common.Webhook.Requests("/signed")
+	if assert.Len(t, requests, 1) {
+		assert.Nil(t, webhook.Verify(secret.Secret, requests[0].Header, requests[0].Body, webhook.DefaultTolerance))
+	}
+}
+
+func TestPostMSTeamsTestRejectsInsecureURL(t *testing.T) {
+	cn := &obj.Notifications{
+		Notifications: []*obj.Notification{
+			&obj.Notification{
+				CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+				UserId:     13,
+				CheckId:    "00002",
+				Value:      Common.Webhook.URL + "/msteams",
+				Type:       "msteams",
+			}},
+	}
+
+	notifs, err := json.Marshal(cn)
+	if err != nil {
+		t.FailNow()
+	}
+
+	rdr := bytes.NewBufferString(string(notifs))
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/services/msteams/test", Common.Service.config.PublicHost), rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Empty(t, Common.Webhook.Requests("/msteams"))
+}
+
+func TestOpsgenieIntegration(t *testing.T) {
+	integration, err := json.Marshal(&obj.OpsgenieIntegration{APIKey: Common.Opsgenie.Key, Enabled: true})
+	if err != nil {
+		t.FailNow()
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/services/opsgenie", Common.Service.config.PublicHost), bytes.NewReader(integration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw := httptest.NewRecorder()
+	Common.Service.router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	cn := &obj.Notifications{
+		Notifications: []*obj.Notification{
+			&obj.Notification{
+				CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+				UserId:     13,
+				CheckId:    "00002",
+				Value:      "opsgenie",
+				Type:       "opsgenie",
+			}},
+	}
+
+	notifs, err := json.Marshal(cn)
+	if err != nil {
+		t.FailNow()
+	}
+
+	req, err = http.NewRequest("POST", fmt.Sprintf("%s/services/opsgenie/test", Common.Service.config.PublicHost), bytes.NewReader(notifs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", Common.UserToken)
+
+	rw = httptest.NewRecorder()
+	Common.Service.router.ServeHTTP(rw, req)
+	log.Info(string(rw.Body.Bytes()))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NotEmpty(t, Common.Opsgenie.Alerts())
 }
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+// Acknowledges the check's open incident, which stops re-notifications and
+// escalations for it until the check recovers.
+func (s *Service) postIncidentAck() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		checkId, err := checkIdParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		incident, err := s.db.AcknowledgeIncident(user, checkId)
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.New("Check has no open incident.")
+		}
+		if err != nil {
+			log.WithFields(log.Fields{"service": "postIncidentAck", "error": err}).Error("Couldn't acknowledge incident in database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		// the acknowledgement stands even if we can't tell PagerDuty about it
+		if err := s.pipeline.Acknowledge(incident); err != nil {
+			log.WithFields(log.Fields{"service": "postIncidentAck", "error": err}).Error("Couldn't propagate acknowledgement.")
+		}
+
+		return incident, http.StatusOK, nil
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/notifier"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) postMSTeamsTest() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return ctx, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		msTeamsSender, err := notifier.NewMSTeamsSender(s.senders.Cats, s.senders.Webhooks)
+		if err != nil {
+			log.WithError(err).Error("Couldn't get msteams sender")
+			return ctx, http.StatusBadRequest, errUnknown
+		}
+
+		request, ok := ctx.Value(requestKey).(*obj.Notifications)
+		if !ok {
+			return ctx, http.StatusBadRequest, errUnknown
+		}
+
+		if len(request.Notifications) < 1 {
+			log.WithFields(log.Fields{"service": "postMSTeamsTest"}).Error("Invalid notification")
+			return ctx, http.StatusBadRequest, fmt.Errorf("Must have at least one notification")
+		}
+
+		// the webhook url is only validated for msteams notifications
+		if request.Notifications[0].Type != "msteams" {
+			return ctx, http.StatusBadRequest, fmt.Errorf("Notification type must be msteams")
+		}
+
+		event := obj.GenerateTestEvent()
+		request.Notifications[0].CustomerId = user.CustomerId
+
+		err = msTeamsSender.Send(request.Notifications[0], event)
+		if err != nil {
+			log.WithError(err).Error("Error sending notification to teams")
+			return ctx, http.StatusBadRequest, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+}
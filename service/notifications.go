@@ -25,7 +25,7 @@ func (s *Service) getNotifications() tp.HandleFunc {
 			return ctx, http.StatusBadRequest, err
 		}
 
-		response := &obj.Notifications{Notifications: notifications}
+		response := &obj.Notifications{Notifications: redactWebhookHeaders(notifications)}
 
 		return response, http.StatusOK, nil
 	}
@@ -44,7 +44,13 @@ func (s *Service) getNotificationsDefault() tp.HandleFunc {
 			return nil, http.StatusBadRequest, err
 		}
 
-		response := &obj.Notifications{Notifications: notifications}
+		threshold, err := s.db.GetThreshold(user, "")
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getNotificationsDefault", "error": err}).Error("Couldn't get default threshold from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		response := &obj.Notifications{Notifications: redactWebhookHeaders(notifications), Threshold: threshold}
 
 		return response, http.StatusOK, nil
 	}
@@ -69,12 +75,29 @@ func (s *Service) postNotifications() tp.HandleFunc {
 			n.CheckId = request.CheckId
 		}
 
-		err := s.db.PutNotifications(user, request.Notifications)
+		if err := s.checkWebhookURLs(request.Notifications); err != nil {
+			return ctx, http.StatusBadRequest, err
+		}
+
+		stored, err := s.db.GetNotificationsByCheckId(user, request.CheckId)
+		if err != nil {
+			return ctx, http.StatusInternalServerError, err
+		}
+		if err := restoreWebhookHeaders(request.Notifications, stored); err != nil {
+			return ctx, http.StatusBadRequest, err
+		}
+
+		err = s.db.PutNotifications(user, request.Notifications)
 		if err != nil {
 			log.WithFields(log.Fields{"service": "putNotifications", "error": err}).Error("Couldn't put notifications in database.")
 			return ctx, http.StatusBadRequest, err
 		}
 
+		if err := s.putThreshold(user, request.CheckId, request.Threshold); err != nil {
+			log.WithFields(log.Fields{"service": "putNotifications", "error": err}).Error("Couldn't put threshold in database.")
+			return ctx, http.StatusBadRequest, err
+		}
+
 		result, err := s.db.GetNotificationsByCheckId(user, request.CheckId)
 		if err != nil {
 			log.WithFields(log.Fields{"service": "putNotifications", "error": err}).Error("Failed to get notifications.")
@@ -82,7 +105,8 @@ func (s *Service) postNotifications() tp.HandleFunc {
 
 		notifs := &obj.Notifications{
 			CheckId:       request.CheckId,
-			Notifications: result,
+			Notifications: redactWebhookHeaders(result),
+			Threshold:     request.Threshold,
 		}
 
 		return notifs, http.StatusCreated, nil
@@ -106,19 +130,36 @@ func (s *Service) postNotificationsDefault() tp.HandleFunc {
 			notif.CustomerId = user.CustomerId
 		}
 
-		err := s.db.PutDefaultNotifications(user, request.Notifications)
+		if err := s.checkWebhookURLs(request.Notifications); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		stored, err := s.db.GetDefaultNotifications(user)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if err := restoreWebhookHeaders(request.Notifications, stored); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		err = s.db.PutDefaultNotifications(user, request.Notifications)
 		if err != nil {
 			log.WithFields(log.Fields{"service": "putNotificationsDefault", "error": err}).Error("Couldn't put default notifications in database.")
 			return nil, http.StatusInternalServerError, err
 		}
 
+		if err := s.putThreshold(user, "", request.Threshold); err != nil {
+			log.WithFields(log.Fields{"service": "putNotificationsDefault", "error": err}).Error("Couldn't put default threshold in database.")
+			return nil, http.StatusBadRequest, err
+		}
+
 		result, err := s.db.GetDefaultNotifications(user)
 		if err != nil {
 			log.WithFields(log.Fields{"service": "putNotificationsDefault", "error": err}).Error("Failed to get default notifications.")
 			return nil, http.StatusInternalServerError, err
 		}
 
-		response := &obj.Notifications{Notifications: result}
+		response := &obj.Notifications{Notifications: redactWebhookHeaders(result), Threshold: request.Threshold}
 
 		return response, http.StatusCreated, nil
 	}
@@ -146,6 +187,17 @@ func (s *Service) postNotificationsMultiCheck() tp.HandleFunc {
 				notification.UserId = int(user.Id)
 				notification.CheckId = notificationsObj.CheckId
 			}
+			if err := s.checkWebhookURLs(notificationsObj.Notifications); err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+
+			stored, err := s.db.GetNotificationsByCheckId(user, notificationsObj.CheckId)
+			if err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			if err := restoreWebhookHeaders(notificationsObj.Notifications, stored); err != nil {
+				return nil, http.StatusBadRequest, err
+			}
 		}
 
 		err := s.db.PutNotificationsMultiCheck(notificationsObjArray)
@@ -154,6 +206,14 @@ func (s *Service) postNotificationsMultiCheck() tp.HandleFunc {
 			return nil, http.StatusBadRequest, err
 		}
 
+		for _, notificationsObj := range notificationsObjArray {
+			if err := s.putThreshold(user, notificationsObj.CheckId, notificationsObj.Threshold); err != nil {
+				log.WithError(err).Error("Couldn't post threshold in database.")
+				return nil, http.StatusBadRequest, err
+			}
+			updatedNotificationsObjMap[notificationsObj.CheckId].Threshold = notificationsObj.Threshold
+		}
+
 		// return the notifications for each check in the deebee
 		updatedNotificationsObjs := make([]*obj.Notifications, 0, len(updatedNotificationsObjMap))
 		for checkId, updatedNotificationsObj := range updatedNotificationsObjMap {
@@ -162,7 +222,7 @@ func (s *Service) postNotificationsMultiCheck() tp.HandleFunc {
 				log.WithError(err).Error("Couldn't get updated list of notifications")
 			}
 
-			updatedNotificationsObj.Notifications = updatedNotificationsArray
+			updatedNotificationsObj.Notifications = redactWebhookHeaders(updatedNotificationsArray)
 			updatedNotificationsObjs = append(updatedNotificationsObjs, updatedNotificationsObj)
 		}
 
@@ -200,6 +260,11 @@ func (s *Service) deleteNotificationsByCheckId() tp.HandleFunc {
 			return ctx, http.StatusInternalServerError, err
 		}
 
+		if err := s.db.DeleteThreshold(user, checkId); err != nil {
+			log.WithFields(log.Fields{"service": "deleteNotificationsByCheckId", "error": err}).Error("Couldn't delete threshold from database.")
+			return ctx, http.StatusInternalServerError, err
+		}
+
 		return nil, http.StatusOK, nil
 	}
 }
@@ -228,7 +293,13 @@ func (s *Service) getNotificationsByCheckId() tp.HandleFunc {
 			return ctx, http.StatusInternalServerError, err
 		}
 
-		return &obj.Notifications{Notifications: notifications}, http.StatusOK, nil
+		threshold, err := s.db.GetThreshold(user, checkId)
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getNotificationsByCheckId", "error": err}).Error("Couldn't get threshold from database.")
+			return ctx, http.StatusInternalServerError, err
+		}
+
+		return &obj.Notifications{Notifications: redactWebhookHeaders(notifications), Threshold: threshold}, http.StatusOK, nil
 	}
 }
 
@@ -255,8 +326,20 @@ func (s *Service) putNotificationsByCheckId() tp.HandleFunc {
 			return nil, http.StatusBadRequest, errUnknown
 		}
 
+		if err := s.checkWebhookURLs(request.Notifications); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		stored, err := s.db.GetNotificationsByCheckId(user, checkId)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if err := restoreWebhookHeaders(request.Notifications, stored); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
 		// First delete notifications for this check
-		err := s.db.DeleteNotificationsByCheckId(user, checkId)
+		err = s.db.DeleteNotificationsByCheckId(user, checkId)
 		if err != nil {
 			return nil, http.StatusInternalServerError, err
 		}
@@ -272,7 +355,11 @@ func (s *Service) putNotificationsByCheckId() tp.HandleFunc {
 			return nil, http.StatusInternalServerError, err
 		}
 
-		return &obj.Notifications{checkId, request.Notifications}, http.StatusCreated, nil
+		if err := s.putThreshold(user, checkId, request.Threshold); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		return &obj.Notifications{CheckId: checkId, Notifications: redactWebhookHeaders(request.Notifications), Threshold: request.Threshold}, http.StatusCreated, nil
 
 	}
 }
@@ -312,3 +399,13 @@ func (s *Service) deleteNotifications() tp.HandleFunc {
 		return nil, http.StatusOK, nil
 	}
 }
+
+// putThreshold stores the threshold sent along with a check's notifications.
+// Leaving it out of the request keeps the current threshold.
+func (s *Service) putThreshold(user *schema.User, checkId string, threshold *obj.Threshold) error {
+	if threshold == nil {
+		return nil
+	}
+	threshold.CheckId = checkId
+	return s.db.PutThreshold(user, threshold)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/notifier"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) postOpsgenieTest() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		ogSender, err := notifier.NewOpsgenieSender(s.senders.Credentials, s.config.OpsgenieAPIURL)
+		if err != nil {
+			log.WithError(err).Error("Couldn't get opsgenie sender")
+			return nil, http.StatusInternalServerError, errUnknown
+		}
+
+		request, ok := ctx.Value(requestKey).(*obj.Notifications)
+		if !ok {
+			return nil, http.StatusBadRequest, errUnknown
+		}
+
+		if len(request.Notifications) < 1 {
+			log.Error("Invalid opsgenie test notification received from emissary")
+			return nil, http.StatusBadRequest, fmt.Errorf("Must have at least one notification")
+		}
+
+		event := obj.GenerateFailingTestEvent()
+		request.Notifications[0].CustomerId = user.CustomerId
+
+		err = ogSender.Send(request.Notifications[0], event)
+		if err != nil {
+			log.WithError(err).Error("Error sending opsgenie notification")
+			return nil, http.StatusBadRequest, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+}
+
+// Fetch the customer's opsgenie integration from the database
+func (s *Service) getOpsgenieIntegration() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		integration, err := s.db.GetOpsgenieIntegration(user)
+		if err != nil {
+			log.WithError(err).Error("Didn't get opsgenie integration from database.")
+			return nil, http.StatusOK, fmt.Errorf("integration_inactive")
+		}
+		if integration == nil {
+			return nil, http.StatusOK, fmt.Errorf("integration_inactive")
+		}
+
+		return integration, http.StatusOK, nil
+	}
+}
+
+func (s *Service) postOpsgenieIntegration() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		integration, ok := ctx.Value(requestKey).(*obj.OpsgenieIntegration)
+		if !ok {
+			return nil, http.StatusBadRequest, errUnknown
+		}
+
+		if err := integration.Validate(); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		err := s.db.PutOpsgenieIntegration(user, integration)
+		if err != nil {
+			log.WithError(err).Error("Couldn't write opsgenie integration to database")
+			return nil, http.StatusInternalServerError, err
+		}
+		s.senders.Credentials.Invalidate(user.CustomerId)
+
+		return integration, http.StatusOK, nil
+	}
+}
@@ -20,7 +20,7 @@ func (s *Service) postPagerDutyTest() tp.HandleFunc {
 			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
 		}
 
-		pdSender, err := notifier.NewPagerDutySender()
+		pdSender, err := notifier.NewPagerDutySender(s.senders.Credentials, s.config.PagerDutyEventsURL)
 		if err != nil {
 			log.WithError(err).Error("Couldn't get pagerduty sender")
 			return nil, http.StatusInternalServerError, errUnknown
@@ -96,6 +96,7 @@ func (s *Service) postPagerDutyCode() tp.HandleFunc {
 			log.WithError(err).Error("Couldn't write pagerduty oauth response to database")
 			return nil, http.StatusInternalServerError, err
 		}
+		s.senders.Credentials.Invalidate(user.CustomerId)
 
 		return oaResponse, http.StatusOK, nil
 	}
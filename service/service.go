@@ -3,15 +3,21 @@ package service
 import (
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"golang.org/x/net/context"
 
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlopes/slack"
 	"github.com/opsee/basic/schema"
 	"github.com/opsee/basic/tp"
 	"github.com/opsee/hugs/config"
+	"github.com/opsee/hugs/consumer"
+	"github.com/opsee/hugs/notifier"
 	"github.com/opsee/hugs/obj"
 	"github.com/opsee/hugs/store"
+	log "github.com/opsee/logrus"
 )
 
 const (
@@ -28,9 +34,11 @@ var (
 )
 
 type Service struct {
-	db     *store.Postgres
-	router *tp.Router
-	config *config.Config
+	db       store.Store
+	router   *tp.Router
+	config   *config.Config
+	pipeline *consumer.Pipeline
+	senders  *notifier.Dependencies
 }
 
 func (s *Service) Start() error {
@@ -60,11 +68,24 @@ func (s *Service) NewRouter() *tp.Router {
 	rtr.Handle("GET", "/services/pagerduty", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{})}, s.getPagerDutyToken())
 	rtr.Handle("POST", "/services/pagerduty/test", decoders(schema.User{}, obj.Notifications{}), s.postPagerDutyTest())
 
+	// opsgenie
+	rtr.Handle("POST", "/services/opsgenie", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.RequestDecodeFunc(requestKey, obj.OpsgenieIntegration{})}, s.postOpsgenieIntegration())
+	rtr.Handle("GET", "/services/opsgenie", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{})}, s.getOpsgenieIntegration())
+	rtr.Handle("POST", "/services/opsgenie/test", decoders(schema.User{}, obj.Notifications{}), s.postOpsgenieTest())
+
 	// email
 	rtr.Handle("POST", "/services/email/test", decoders(schema.User{}, obj.Notifications{}), s.postEmailTest())
 
 	// webhooks
 	rtr.Handle("POST", "/services/webhook/test", decoders(schema.User{}, obj.Notifications{}), s.postWebHookTest())
+	rtr.Handle("POST", "/services/webhook/secret", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{})}, s.postWebhookSecret())
+	rtr.Handle("GET", "/services/webhook/secret", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{})}, s.getWebhookSecret())
+
+	// sns
+	rtr.Handle("POST", "/services/sns/test", decoders(schema.User{}, obj.Notifications{}), s.postSNSTest())
+
+	// microsoft teams
+	rtr.Handle("POST", "/services/msteams/test", decoders(schema.User{}, obj.Notifications{}), s.postMSTeamsTest())
 
 	// notifications
 	rtr.Handle("GET", "/notifications", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.getNotifications())
@@ -76,6 +97,25 @@ func (s *Service) NewRouter() *tp.Router {
 	rtr.Handle("DELETE", "/notifications/:check_id", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.deleteNotificationsByCheckId())
 	rtr.Handle("GET", "/notifications/:check_id", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.getNotificationsByCheckId())
 	rtr.Handle("PUT", "/notifications/:check_id", decoders(schema.User{}, obj.Notifications{}), s.putNotificationsByCheckId())
+	rtr.Handle("GET", "/notifications/:check_id/deliveries", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey), tp.QueryDecoder(queryKey)}, s.getDeliveriesByCheckId())
+	rtr.Handle("GET", "/notifications/:check_id/escalation", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.getEscalationPolicy())
+	rtr.Handle("PUT", "/notifications/:check_id/escalation", decoders(schema.User{}, obj.EscalationPolicy{}), s.putEscalationPolicy())
+	rtr.Handle("DELETE", "/notifications/:check_id/escalation", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.deleteEscalationPolicy())
+
+	// dead letters
+	rtr.Handle("GET", "/dead-letters", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.QueryDecoder(queryKey)}, s.getDeadLetters())
+	rtr.Handle("GET", "/dead-letters/:id", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.getDeadLetter())
+	rtr.Handle("POST", "/dead-letters/:id/replay", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey), tp.QueryDecoder(queryKey)}, s.postDeadLetterReplay())
+
+	// incidents
+	rtr.Handle("POST", "/incidents/:check_id/ack", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.postIncidentAck())
+
+	// silences
+	rtr.Handle("GET", "/silences", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{})}, s.getSilences())
+	rtr.Handle("POST", "/silences", decoders(schema.User{}, obj.Silence{}), s.postSilence())
+	rtr.Handle("GET", "/silences/:id", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.getSilence())
+	rtr.Handle("PUT", "/silences/:id", decoders(schema.User{}, obj.Silence{}), s.putSilence())
+	rtr.Handle("DELETE", "/silences/:id", []tp.DecodeFunc{tp.AuthorizationDecodeFunc(userKey, schema.User{}), tp.ParamsDecoder(paramsKey)}, s.deleteSilence())
 	rtr.Timeout(5 * time.Minute)
 
 	return rtr
@@ -100,8 +140,50 @@ func NewService() (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	return NewServiceWithStore(dbmaybe)
+}
+
+// NewServiceWithStore returns a Service backed by the given store, e.g. a
+// store.Memory for tests and local development.
+func NewServiceWithStore(db store.Store) (*Service, error) {
+	// replays go through the same pipeline as the workers, so we need every
+	// sender we can get, but a missing one shouldn't keep the API down.
+	deps, err := notifier.NewDependencies(db)
+	if err != nil {
+		return nil, err
+	}
+
+	n, errMap := notifier.NewNotifier(deps)
+	for k, v := range errMap {
+		if v != nil {
+			log.WithFields(log.Fields{"service": "NewService", "error": v}).Warn("Couldn't initialize notifier: ", k)
+		}
+	}
+
+	cfg := config.GetConfig()
+
+	// the slack client library only reads its endpoint from a package var
+	slack.SLACK_API = cfg.SlackAPIURL + "/"
+
 	return &Service{
-		db:     dbmaybe,
-		config: config.GetConfig(),
+		db:       db,
+		config:   cfg,
+		pipeline: consumer.NewPipeline(db, n),
+		senders:  deps,
 	}, nil
 }
+
+// idParam reads the integer :id route parameter.
+func idParam(ctx context.Context) (int, error) {
+	params, ok := ctx.Value(paramsKey).(httprouter.Params)
+	if !ok || params.ByName("id") == "" {
+		return 0, errors.New("Must specify id in request.")
+	}
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		return 0, errors.New("id must be an integer.")
+	}
+	return id, nil
+}
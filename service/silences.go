@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) getSilences() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		silences, err := s.db.GetSilences(user)
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getSilences", "error": err}).Error("Couldn't get silences from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return &obj.Silences{Silences: silences}, http.StatusOK, nil
+	}
+}
+
+func (s *Service) getSilence() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		id, err := idParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		silence, err := s.db.GetSilence(user, id)
+		if err != nil {
+			log.WithFields(log.Fields{"service": "getSilence", "error": err}).Error("Couldn't get silence from database.")
+			return nil, http.StatusNotFound, err
+		}
+
+		return silence, http.StatusOK, nil
+	}
+}
+
+func (s *Service) postSilence() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		request, ok := ctx.Value(requestKey).(*obj.Silence)
+		if !ok {
+			return nil, http.StatusBadRequest, errUnknown
+		}
+
+		if err := s.db.PutSilence(user, request); err != nil {
+			log.WithFields(log.Fields{"service": "postSilence", "error": err}).Error("Couldn't put silence in database.")
+			return nil, http.StatusBadRequest, err
+		}
+
+		return request, http.StatusCreated, nil
+	}
+}
+
+func (s *Service) putSilence() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		id, err := idParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		request, ok := ctx.Value(requestKey).(*obj.Silence)
+		if !ok {
+			return nil, http.StatusBadRequest, errUnknown
+		}
+
+		if _, err := s.db.GetSilence(user, id); err != nil {
+			return nil, http.StatusNotFound, err
+		}
+
+		request.Id = id
+		if err := s.db.UpdateSilence(user, request); err != nil {
+			log.WithFields(log.Fields{"service": "putSilence", "error": err}).Error("Couldn't update silence in database.")
+			return nil, http.StatusBadRequest, err
+		}
+
+		silence, err := s.db.GetSilence(user, id)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return silence, http.StatusOK, nil
+	}
+}
+
+func (s *Service) deleteSilence() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		id, err := idParam(ctx)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		if err := s.db.DeleteSilence(user, id); err != nil {
+			log.WithFields(log.Fields{"service": "deleteSilence", "error": err}).Error("Couldn't delete silence from database.")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+}
@@ -35,7 +35,7 @@ func (s *Service) getSlackCode() tp.HandleFunc {
 			RedirectURI:  request.RedirectURI,
 		}
 
-		oaResponse, err := oaRequest.Do("https://slack.com/api/oauth.access")
+		oaResponse, err := oaRequest.Do(s.config.SlackAPIURL + "/oauth.access")
 		if err != nil {
 			log.WithFields(log.Fields{"service": "getSlackCode", "error": err}).Error("Didn't get oauth response from slack.")
 			return oaResponse, http.StatusBadRequest, err
@@ -48,6 +48,7 @@ func (s *Service) getSlackCode() tp.HandleFunc {
 				log.WithFields(log.Fields{"service": "getSlackCode", "error": err}).Error("Couldn't put oauth response received from slack.")
 				return ctx, http.StatusInternalServerError, err
 			}
+			s.senders.Credentials.Invalidate(user.CustomerId)
 		}
 
 		return oaResponse, http.StatusOK, nil
@@ -61,7 +62,7 @@ func (s *Service) postSlackTest() tp.HandleFunc {
 			return ctx, http.StatusUnauthorized, errors.New("Unable to get User from request context")
 		}
 
-		slackSender, err := notifier.NewSlackBotSender()
+		slackSender, err := notifier.NewSlackBotSender(s.senders.Credentials, s.senders.SlackThreads, s.senders.Cats, s.config.SlackAPIURL)
 		if err != nil {
 			log.WithFields(log.Fields{"service": "postSlackTest"}).Error("Couldn't get slack sender.")
 			return ctx, http.StatusBadRequest, errUnknown
@@ -198,7 +199,7 @@ func (s *Service) postSlackCode() tp.HandleFunc {
 			RedirectURI:  request.RedirectURI,
 		}
 
-		oaResponse, err := oaRequest.Do("https://slack.com/api/oauth.access")
+		oaResponse, err := oaRequest.Do(s.config.SlackAPIURL + "/oauth.access")
 		if err != nil {
 			log.WithFields(log.Fields{"service": "postSlackCode", "error": err}).Error("Couldn't get oauth response from slack.")
 			return nil, http.StatusBadRequest, err
@@ -222,6 +223,7 @@ func (s *Service) postSlackCode() tp.HandleFunc {
 			log.WithFields(log.Fields{"service": "postSlackCode", "error": err}).Error("Couldn't write slack oauth response to database.")
 			return nil, http.StatusBadRequest, err
 		}
+		s.senders.Credentials.Invalidate(user.CustomerId)
 
 		return oaResponse, http.StatusOK, nil
 	}
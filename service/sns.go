@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/basic/tp"
+	"github.com/opsee/hugs/notifier"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+	"golang.org/x/net/context"
+)
+
+func (s *Service) postSNSTest() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return ctx, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		snsSender, err := notifier.NewSNSSender(s.config.AWSSession, s.config.SNSEndpoint)
+		if err != nil {
+			log.WithError(err).Error("Couldn't get sns sender")
+			return ctx, http.StatusBadRequest, errUnknown
+		}
+
+		request, ok := ctx.Value(requestKey).(*obj.Notifications)
+		if !ok {
+			return ctx, http.StatusBadRequest, errUnknown
+		}
+
+		if len(request.Notifications) < 1 {
+			log.WithFields(log.Fields{"service": "postSNSTest"}).Error("Invalid notification")
+			return ctx, http.StatusBadRequest, fmt.Errorf("Must have at least one notification")
+		}
+
+		event := obj.GenerateTestEvent()
+		request.Notifications[0].CustomerId = user.CustomerId
+
+		err = snsSender.Send(request.Notifications[0], event)
+		if err != nil {
+			log.WithError(err).Error("Error publishing notification to sns")
+			return ctx, http.StatusBadRequest, err
+		}
+
+		return nil, http.StatusOK, nil
+	}
+}
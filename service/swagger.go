@@ -94,6 +94,68 @@ var swaggerMap = j{
 			},
 		},
 
+		"/services/opsgenie": j{
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "OpsgenieIntegration",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/OpsgenieIntegration",
+						},
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/OpsgenieIntegration",
+						},
+					},
+				},
+				"summary": "Saves an opsgenie integration.",
+				"tags":    k{"token"},
+			},
+			"get": j{
+				"parameters": []j{},
+				"responses": j{
+					"200": j{
+						"description": "Retrieves user's opsgenie integration.",
+						"schema": j{
+							"$ref": "#/definitions/OpsgenieIntegration",
+						},
+					},
+				},
+				"summary": "Get the opsgenie integration.",
+				"tags":    k{"getopsgenieintegration"},
+			},
+		},
+		"/services/opsgenie/test": j{
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "Notifications",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/Notifications",
+						},
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema":      j{},
+					},
+				},
+				"summary": "Test alert on a notification",
+				"tags":    k{"notifications"},
+			},
+		},
+
 		"/services/email/test": j{
 			"post": j{
 				"parameters": []j{
@@ -142,6 +204,83 @@ var swaggerMap = j{
 			},
 		},
 
+		"/services/webhook/secret": j{
+			"post": j{
+				"parameters": []j{},
+				"responses": j{
+					"200": j{
+						"description": "The new secret. Webhooks are signed with it from now on.",
+						"schema": j{
+							"$ref": "#/definitions/WebhookSecret",
+						},
+					},
+				},
+				"summary": "Generates or rotates the secret webhooks are signed with.",
+				"tags":    k{"token"},
+			},
+			"get": j{
+				"parameters": []j{},
+				"responses": j{
+					"200": j{
+						"description": "Retrieves user's webhook signing secret.",
+						"schema": j{
+							"$ref": "#/definitions/WebhookSecret",
+						},
+					},
+				},
+				"summary": "Get the webhook signing secret.",
+				"tags":    k{"getwebhooksecret"},
+			},
+		},
+
+		"/services/sns/test": j{
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "Notifications",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/Notifications",
+						},
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema":      j{},
+					},
+				},
+				"summary": "Test alert on an SNS topic notification",
+				"tags":    k{"notifications"},
+			},
+		},
+
+		"/services/msteams/test": j{
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "Notifications",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/Notifications",
+						},
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema":      j{},
+					},
+				},
+				"summary": "Test alert on a Microsoft Teams notification",
+				"tags":    k{"notifications"},
+			},
+		},
+
 		"/services/slack/channels": j{
 			"get": j{
 				"parameters": []j{},
@@ -421,58 +560,653 @@ var swaggerMap = j{
 				"tags":    k{"notifications"},
 			},
 		},
-	},
-
-	"definitions": j{
-		"Notifications": j{
-			"properties": j{
-				"check_id": j{
-					"type": "string",
+		"/notifications/{check_id}/deliveries": j{
+			"get": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "check_id",
+						"required":    true,
+						"type":        "string",
+					},
+					j{
+						"description": "Maximum number of deliveries to return, defaults to 100.",
+						"in":          "query",
+						"name":        "limit",
+						"required":    false,
+						"type":        "integer",
+					},
 				},
-				"notifications": j{
-					"items": j{
-						"$ref": "#/definitions/notification",
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/Deliveries",
+						},
 					},
-					"type": "array",
 				},
+				"summary": "Lists the most recent notification deliveries for a check.",
+				"tags":    k{"notifications"},
 			},
-			"required": k{
-				"check_id",
-				"notifications",
-			},
-			"type": "object",
 		},
-		"Notification": j{
-			"properties": j{
-				"type": j{
-					"type": "string",
+		"/notifications/{check_id}/escalation": j{
+			"get": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "check_id",
+						"required":    true,
+						"type":        "string",
+					},
 				},
-				"value": j{
-					"type": "string",
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/EscalationPolicy",
+						},
+					},
 				},
+				"summary": "Retrieves the escalation policy for a check.",
+				"tags":    k{"notifications"},
 			},
-			"required": k{
-				"type",
-				"value",
+			"put": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "check_id",
+						"required":    true,
+						"type":        "string",
+					},
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "EscalationPolicy",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/EscalationPolicy",
+						},
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/EscalationPolicy",
+						},
+					},
+				},
+				"summary": "Creates or replaces the escalation policy for a check. Steps are notified in order while the check keeps failing.",
+				"tags":    k{"notifications"},
 			},
-			"type": "object",
-		},
-		"PagerDutyOAuthResponse": j{
-			"properties": j{
-				"account": j{
-					"type": "string",
+			"delete": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "check_id",
+						"required":    true,
+						"type":        "string",
+					},
 				},
-				"service_key": j{
-					"type": "string",
+				"responses": j{
+					"200": j{
+						"description": "",
+					},
 				},
-				"service_name": j{
-					"type": "string",
+				"summary": "Deletes the escalation policy for a check.",
+				"tags":    k{"notifications"},
+			},
+		},
+		"/dead-letters": j{
+			"get": j{
+				"parameters": []j{
+					j{
+						"description": "Maximum number of dead letters to return, defaults to 100.",
+						"in":          "query",
+						"name":        "limit",
+						"required":    false,
+						"type":        "integer",
+					},
 				},
-				"enabled": j{
-					"type": "boolean",
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/DeadLetters",
+						},
+					},
 				},
-				"error": j{
-					"type": "string",
+				"summary": "Lists CheckResults we gave up delivering notifications for.",
+				"tags":    k{"deadletters"},
+			},
+		},
+		"/dead-letters/{id}": j{
+			"get": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "id",
+						"required":    true,
+						"type":        "integer",
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/DeadLetter",
+						},
+					},
+					"404": j{
+						"description": "The dead letter doesn't exist.",
+					},
+				},
+				"summary": "Retrieves a dead letter and its CheckResult.",
+				"tags":    k{"deadletters"},
+			},
+		},
+		"/dead-letters/{id}/replay": j{
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "id",
+						"required":    true,
+						"type":        "integer",
+					},
+					j{
+						"description": "Send the CheckResult even if the check's state has changed since.",
+						"in":          "query",
+						"name":        "force",
+						"required":    false,
+						"type":        "boolean",
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+					},
+					"404": j{
+						"description": "The dead letter doesn't exist.",
+					},
+					"409": j{
+						"description": "Nothing was sent, because the check has no notifications, is silenced or its state has changed since the CheckResult.",
+					},
+					"500": j{
+						"description": "The replay failed before anything was sent.",
+					},
+					"502": j{
+						"description": "None of the check's notifications could be delivered. The dead letter isn't marked replayed.",
+					},
+				},
+				"summary": "Sends a dead letter's CheckResult through the notification pipeline again.",
+				"tags":    k{"deadletters"},
+			},
+		},
+		"/incidents/{check_id}/ack": j{
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "check_id",
+						"required":    true,
+						"type":        "string",
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/Incident",
+						},
+					},
+					"404": j{
+						"description": "The check has no open incident.",
+					},
+				},
+				"summary": "Acknowledges a failing check's incident, which stops re-notifications and escalations until it recovers.",
+				"tags":    k{"incidents"},
+			},
+		},
+		"/silences": j{
+			"get": j{
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/Silences",
+						},
+					},
+				},
+				"summary": "Lists a customer's silences.",
+				"tags":    k{"silences"},
+			},
+			"post": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "Silence",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/Silence",
+						},
+					},
+				},
+				"responses": j{
+					"201": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/Silence",
+						},
+					},
+				},
+				"summary": "Mutes notifications for a check, a target or the whole customer during a time window.",
+				"tags":    k{"silences"},
+			},
+		},
+		"/silences/{id}": j{
+			"get": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "id",
+						"required":    true,
+						"type":        "integer",
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/Silence",
+						},
+					},
+				},
+				"summary": "Retrieves a silence.",
+				"tags":    k{"silences"},
+			},
+			"put": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "id",
+						"required":    true,
+						"type":        "integer",
+					},
+					j{
+						"description": "",
+						"in":          "body",
+						"name":        "Silence",
+						"required":    true,
+						"schema": j{
+							"$ref": "#/definitions/Silence",
+						},
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+						"schema": j{
+							"$ref": "#/definitions/Silence",
+						},
+					},
+				},
+				"summary": "Updates a silence.",
+				"tags":    k{"silences"},
+			},
+			"delete": j{
+				"parameters": []j{
+					j{
+						"description": "",
+						"in":          "path",
+						"name":        "id",
+						"required":    true,
+						"type":        "integer",
+					},
+				},
+				"responses": j{
+					"200": j{
+						"description": "",
+					},
+				},
+				"summary": "Deletes a silence.",
+				"tags":    k{"silences"},
+			},
+		},
+	},
+
+	"definitions": j{
+		"Notifications": j{
+			"properties": j{
+				"check_id": j{
+					"type": "string",
+				},
+				"notifications": j{
+					"items": j{
+						"$ref": "#/definitions/notification",
+					},
+					"type": "array",
+				},
+				"threshold": j{
+					"$ref": "#/definitions/Threshold",
+				},
+			},
+			"required": k{
+				"check_id",
+				"notifications",
+			},
+			"type": "object",
+		},
+		"Notification": j{
+			"properties": j{
+				"type": j{
+					"type": "string",
+				},
+				"value": j{
+					"type": "string",
+				},
+				"webhook": j{
+					"$ref": "#/definitions/WebhookOptions",
+				},
+			},
+			"required": k{
+				"type",
+				"value",
+			},
+			"type": "object",
+		},
+		"Threshold": j{
+			"properties": j{
+				"consecutive": j{
+					"description": "Number of results in a row a new state must be seen in before notifying.",
+					"type":        "integer",
+				},
+				"duration": j{
+					"description": "Seconds a new state must have lasted before notifying.",
+					"type":        "integer",
+				},
+			},
+			"type": "object",
+		},
+		"Deliveries": j{
+			"properties": j{
+				"check_id": j{
+					"type": "string",
+				},
+				"deliveries": j{
+					"items": j{
+						"$ref": "#/definitions/Delivery",
+					},
+					"type": "array",
+				},
+			},
+			"type": "object",
+		},
+		"Delivery": j{
+			"properties": j{
+				"id": j{
+					"type": "integer",
+				},
+				"notification_id": j{
+					"type": "integer",
+				},
+				"check_id": j{
+					"type": "string",
+				},
+				"type": j{
+					"type": "string",
+				},
+				"passing": j{
+					"type": "boolean",
+				},
+				"status": j{
+					"type": "string",
+				},
+				"error": j{
+					"type": "string",
+				},
+				"attempt": j{
+					"type": "integer",
+				},
+				"latency_ms": j{
+					"type": "integer",
+				},
+				"created_at": j{
+					"type": "string",
+				},
+			},
+			"type": "object",
+		},
+		"EscalationPolicy": j{
+			"properties": j{
+				"check_id": j{
+					"type": "string",
+				},
+				"steps": j{
+					"items": j{
+						"$ref": "#/definitions/EscalationStep",
+					},
+					"type": "array",
+				},
+			},
+			"required": k{
+				"steps",
+			},
+			"type": "object",
+		},
+		"EscalationStep": j{
+			"properties": j{
+				"delay": j{
+					"description": "Seconds the check must have been failing for before this step is notified.",
+					"type":        "integer",
+				},
+				"notifications": j{
+					"items": j{
+						"$ref": "#/definitions/Notification",
+					},
+					"type": "array",
+				},
+			},
+			"required": k{
+				"notifications",
+			},
+			"type": "object",
+		},
+		"DeadLetters": j{
+			"properties": j{
+				"dead_letters": j{
+					"items": j{
+						"$ref": "#/definitions/DeadLetter",
+					},
+					"type": "array",
+				},
+			},
+			"type": "object",
+		},
+		"DeadLetter": j{
+			"properties": j{
+				"id": j{
+					"type": "integer",
+				},
+				"customer_id": j{
+					"type": "string",
+				},
+				"check_id": j{
+					"type": "string",
+				},
+				"reasons": j{
+					"items": j{
+						"type": "string",
+					},
+					"type": "array",
+				},
+				"attempts": j{
+					"type": "integer",
+				},
+				"created_at": j{
+					"type": "string",
+				},
+				"replayed_at": j{
+					"type": "string",
+				},
+				"check_result": j{
+					"type": "object",
+				},
+			},
+			"type": "object",
+		},
+		"Incident": j{
+			"properties": j{
+				"id": j{
+					"type": "integer",
+				},
+				"check_id": j{
+					"type": "string",
+				},
+				"opened_at": j{
+					"type": "string",
+				},
+				"acknowledged_at": j{
+					"type": "string",
+				},
+				"acknowledged_by": j{
+					"type": "integer",
+				},
+				"resolved_at": j{
+					"type": "string",
+				},
+			},
+			"type": "object",
+		},
+		"Silences": j{
+			"properties": j{
+				"silences": j{
+					"items": j{
+						"$ref": "#/definitions/Silence",
+					},
+					"type": "array",
+				},
+			},
+			"type": "object",
+		},
+		"Silence": j{
+			"properties": j{
+				"id": j{
+					"type": "integer",
+				},
+				"check_id": j{
+					"description": "Only silence this check, leave empty to match every check.",
+					"type":        "string",
+				},
+				"target_id": j{
+					"description": "Only silence this target, leave empty to match every target.",
+					"type":        "string",
+				},
+				"reason": j{
+					"type": "string",
+				},
+				"starts_at": j{
+					"type": "string",
+				},
+				"ends_at": j{
+					"type": "string",
+				},
+				"created_at": j{
+					"type": "string",
+				},
+			},
+			"required": k{
+				"starts_at",
+				"ends_at",
+			},
+			"type": "object",
+		},
+		"PagerDutyOAuthResponse": j{
+			"properties": j{
+				"account": j{
+					"type": "string",
+				},
+				"service_key": j{
+					"type": "string",
+				},
+				"service_name": j{
+					"type": "string",
+				},
+				"enabled": j{
+					"type": "boolean",
+				},
+				"api_version": j{
+					"description": "PagerDuty Events API version, v1 or v2. Defaults to v1.",
+					"type":        "string",
+				},
+				"error": j{
+					"type": "string",
+				},
+			},
+		},
+
+		"OpsgenieIntegration": j{
+			"properties": j{
+				"api_key": j{
+					"description": "Opsgenie API integration key.",
+					"type":        "string",
+				},
+				"enabled": j{
+					"type": "boolean",
+				},
+			},
+			"required": k{
+				"api_key",
+			},
+		},
+
+		"WebhookOptions": j{
+			"properties": j{
+				"method": j{
+					"description": "POST, PUT or PATCH. Defaults to POST.",
+					"type":        "string",
+				},
+				"content_type": j{
+					"description": "Defaults to application/json.",
+					"type":        "string",
+				},
+				"headers": j{
+					"additionalProperties": j{
+						"type": "string",
+					},
+					"description": "Header values are returned as [redacted]. Sending [redacted] back keeps the stored value.",
+					"type":        "object",
+				},
+				"template": j{
+					"description": "Mustache template for the request body. Defaults to the full check result JSON. Insert values with triple mustaches, they are escaped for the content type, and {{{result}}} is the check result JSON.",
+					"type":        "string",
+				},
+			},
+			"type": "object",
+		},
+
+		"WebhookSecret": j{
+			"properties": j{
+				"customer_id": j{
+					"type": "string",
+				},
+				"secret": j{
+					"description": "Key for the HMAC-SHA256 X-Hugs-Signature header on webhook deliveries.",
+					"type":        "string",
+				},
+				"created_at": j{
+					"type":   "string",
+					"format": "date-time",
 				},
 			},
 		},
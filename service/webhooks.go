@@ -20,7 +20,7 @@ func (s *Service) postWebHookTest() tp.HandleFunc {
 			return ctx, http.StatusUnauthorized, errors.New("Unable to get User from request context")
 		}
 
-		webHookSender, err := notifier.NewWebHookSender()
+		webHookSender, err := notifier.NewWebHookSender(s.senders.Credentials, s.senders.Webhooks)
 		if err != nil {
 			log.WithError(err).Error("Couldn't get web hook sender")
 			return ctx, http.StatusBadRequest, errUnknown
@@ -48,3 +48,105 @@ func (s *Service) postWebHookTest() tp.HandleFunc {
 		return nil, http.StatusOK, nil
 	}
 }
+
+// checkWebhookURLs rejects webhook and msteams notifications whose hosts we
+// won't deliver to, so customers find out when saving them rather than from
+// dead letters.
+func (s *Service) checkWebhookURLs(notifications []*obj.Notification) error {
+	for _, notification := range notifications {
+		if notification.Type != "webhook" && notification.Type != "msteams" {
+			continue
+		}
+		if err := s.senders.Webhooks.CheckURL(notification.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactWebhookHeaders returns copies of notifications with their webhook
+// header values redacted, for API responses.
+func redactWebhookHeaders(notifications []*obj.Notification) []*obj.Notification {
+	redacted := make([]*obj.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		if notification.Webhook != nil {
+			n := *notification
+			n.Webhook = notification.Webhook.Redacted()
+			notification = &n
+		}
+		redacted = append(redacted, notification)
+	}
+	return redacted
+}
+
+// restoreWebhookHeaders puts the stored values back into webhook headers
+// that were sent back redacted, matching notifications by type and URL.
+func restoreWebhookHeaders(notifications, stored []*obj.Notification) error {
+	for _, notification := range notifications {
+		if notification.Webhook == nil {
+			continue
+		}
+
+		for name, value := range notification.Webhook.Headers {
+			if value != obj.RedactedHeaderValue {
+				continue
+			}
+
+			restored := false
+			for _, s := range stored {
+				if s.Type != notification.Type || s.Value != notification.Value || s.Webhook == nil {
+					continue
+				}
+				if storedValue, ok := s.Webhook.Headers[name]; ok {
+					notification.Webhook.Headers[name] = storedValue
+					restored = true
+					break
+				}
+			}
+			if !restored {
+				return fmt.Errorf("webhook header %s is redacted, send its value", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Fetch the customer's webhook signing secret from the database
+func (s *Service) getWebhookSecret() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		secret, err := s.db.GetWebhookSecret(user)
+		if err != nil {
+			log.WithError(err).Error("Didn't get webhook secret from database.")
+			return nil, http.StatusInternalServerError, errUnknown
+		}
+		if secret == nil {
+			return nil, http.StatusOK, fmt.Errorf("secret_inactive")
+		}
+
+		return secret, http.StatusOK, nil
+	}
+}
+
+// Generate a new webhook signing secret, replacing the customer's old one.
+func (s *Service) postWebhookSecret() tp.HandleFunc {
+	return func(ctx context.Context) (interface{}, int, error) {
+		user, ok := ctx.Value(userKey).(*schema.User)
+		if !ok {
+			return nil, http.StatusUnauthorized, errors.New("Unable to get User from request context")
+		}
+
+		secret := obj.NewWebhookSecret(user.CustomerId)
+		err := s.db.PutWebhookSecret(secret)
+		if err != nil {
+			log.WithError(err).Error("Couldn't write webhook secret to database")
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return secret, http.StatusOK, nil
+	}
+}
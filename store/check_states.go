@@ -0,0 +1,68 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+)
+
+// GetCheckState returns the last recorded state for a check's target, or nil if
+// we have never recorded one.
+func (pg *Postgres) GetCheckState(customerId, checkId, targetId string) (*obj.CheckState, error) {
+	state := &obj.CheckState{}
+	err := pg.db.Get(state, "SELECT * FROM check_states WHERE customer_id = $1 AND check_id = $2 AND target_id = $3", customerId, checkId, targetId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// PutCheckState records state in place of previous, the state it was derived
+// from or nil if there was none. If another writer got there first it returns
+// ErrCheckStateChanged and records nothing, so that only one of them can act
+// on a state transition.
+func (pg *Postgres) PutCheckState(previous, state *obj.CheckState) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO check_states (customer_id, check_id, target_id, passing, observed, consecutive, observed_since,
+		transitions, flapping, flap_count, flapping_since, updated_at)
+		VALUES (:customer_id, :check_id, :target_id, :passing, :observed, :consecutive, :observed_since,
+		:transitions, :flapping, :flap_count, :flapping_since, :updated_at)`
+	if previous == nil {
+		query += ` ON CONFLICT (customer_id, check_id, target_id) DO NOTHING`
+	} else {
+		query += ` ON CONFLICT (customer_id, check_id, target_id) DO UPDATE SET passing = EXCLUDED.passing,
+		observed = EXCLUDED.observed, consecutive = EXCLUDED.consecutive, observed_since = EXCLUDED.observed_since,
+		transitions = EXCLUDED.transitions, flapping = EXCLUDED.flapping, flap_count = EXCLUDED.flap_count,
+		flapping_since = EXCLUDED.flapping_since, updated_at = EXCLUDED.updated_at
+		WHERE check_states.updated_at = :previous_updated_at`
+	}
+
+	args := struct {
+		*obj.CheckState
+		PreviousUpdatedAt time.Time `db:"previous_updated_at"`
+	}{CheckState: state}
+	if previous != nil {
+		args.PreviousUpdatedAt = previous.UpdatedAt
+	}
+
+	res, err := pg.db.NamedExec(query, args)
+	if err != nil {
+		return err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrCheckStateChanged
+	}
+	return nil
+}
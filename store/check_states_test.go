@@ -0,0 +1,69 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStorePutCheckState(t *testing.T) {
+	state := &obj.CheckState{
+		CustomerId: Common.User.CustomerId,
+		CheckId:    "00001",
+		TargetId:   "test-target",
+		Passing:    false,
+	}
+
+	if err := Common.DBStore.PutCheckState(nil, state); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	// only one of two writers starting from the same state records theirs
+	if err := Common.DBStore.PutCheckState(nil, state); err != ErrCheckStateChanged {
+		log.Error("TestStorePutCheckState: expected ErrCheckStateChanged, got ", err)
+		t.FailNow()
+	}
+
+	previous, err := Common.DBStore.GetCheckState(state.CustomerId, state.CheckId, state.TargetId)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	next := *previous
+	next.Passing = true
+	next.UpdatedAt = time.Now().UTC()
+	if err := Common.DBStore.PutCheckState(previous, &next); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+	if err := Common.DBStore.PutCheckState(previous, &next); err != ErrCheckStateChanged {
+		log.Error("TestStorePutCheckState: expected ErrCheckStateChanged, got ", err)
+		t.FailNow()
+	}
+
+	stored, err := Common.DBStore.GetCheckState(state.CustomerId, state.CheckId, state.TargetId)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+	if stored == nil || !stored.Passing {
+		log.Error("TestStorePutCheckState: expected stored passing state, got ", stored)
+		t.FailNow()
+	}
+}
+
+func TestStoreGetCheckStateMissing(t *testing.T) {
+	state, err := Common.DBStore.GetCheckState(Common.User.CustomerId, "no-such-check", "")
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+	if state != nil {
+		log.Error("TestStoreGetCheckStateMissing: expected nil state, got ", state)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// DefaultDeadLettersLimit is the number of dead letters returned when no limit
+// is given.
+const DefaultDeadLettersLimit = 100
+
+func (pg *Postgres) PutDeadLetter(deadLetter *obj.DeadLetter) error {
+	if err := deadLetter.Validate(); err != nil {
+		return err
+	}
+
+	rows, err := pg.db.NamedQuery(
+		`INSERT INTO dead_letters (customer_id, check_id, result, reasons, attempts, created_at)
+		VALUES (:customer_id, :check_id, :result, :reasons, :attempts, :created_at)
+		RETURNING id`, deadLetter)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return rows.Scan(&deadLetter.Id)
+	}
+	return rows.Err()
+}
+
+func (pg *Postgres) GetDeadLetters(user *schema.User, limit int) ([]*obj.DeadLetter, error) {
+	if limit <= 0 {
+		limit = DefaultDeadLettersLimit
+	}
+
+	deadLetters := []*obj.DeadLetter{}
+	err := pg.db.Select(&deadLetters, "SELECT * FROM dead_letters WHERE customer_id = $1 ORDER BY created_at DESC LIMIT $2", user.CustomerId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalDeadLetters(deadLetters), nil
+}
+
+func (pg *Postgres) GetDeadLetter(user *schema.User, id int) (*obj.DeadLetter, error) {
+	deadLetter := &obj.DeadLetter{}
+	err := pg.db.Get(deadLetter, "SELECT * FROM dead_letters WHERE customer_id = $1 AND id = $2", user.CustomerId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return deadLetter, deadLetter.UnmarshalResult()
+}
+
+// UnsafeGetDeadLetters returns dead letters for every customer. It is meant for
+// operators only.
+func (pg *Postgres) UnsafeGetDeadLetters(limit int) ([]*obj.DeadLetter, error) {
+	if limit <= 0 {
+		limit = DefaultDeadLettersLimit
+	}
+
+	deadLetters := []*obj.DeadLetter{}
+	err := pg.db.Select(&deadLetters, "SELECT * FROM dead_letters ORDER BY created_at DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalDeadLetters(deadLetters), nil
+}
+
+// UnsafeGetDeadLetter returns a dead letter regardless of customer. It is meant
+// for operators only.
+func (pg *Postgres) UnsafeGetDeadLetter(id int) (*obj.DeadLetter, error) {
+	deadLetter := &obj.DeadLetter{}
+	err := pg.db.Get(deadLetter, "SELECT * FROM dead_letters WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return deadLetter, deadLetter.UnmarshalResult()
+}
+
+func (pg *Postgres) MarkDeadLetterReplayed(deadLetter *obj.DeadLetter) error {
+	_, err := pg.db.Exec("UPDATE dead_letters SET replayed_at = now() WHERE id = $1", deadLetter.Id)
+	return err
+}
+
+func unmarshalDeadLetters(deadLetters []*obj.DeadLetter) []*obj.DeadLetter {
+	for _, deadLetter := range deadLetters {
+		if err := deadLetter.UnmarshalResult(); err != nil {
+			log.WithError(err).WithFields(log.Fields{"dead_letter": deadLetter.Id}).Error("Couldn't unmarshal dead letter CheckResult.")
+		}
+	}
+	return deadLetters
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStorePutDeadLetter(t *testing.T) {
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CustomerId = Common.User.CustomerId
+
+	deadLetter, err := obj.NewDeadLetter(event.Result, 3, []string{"webhook notification 1: remote unavailable"})
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	if err := Common.DBStore.PutDeadLetter(deadLetter); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	stored, err := Common.DBStore.GetDeadLetter(Common.User, deadLetter.Id)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	if stored.CheckResult == nil || stored.CheckResult.CheckId != event.Result.CheckId {
+		log.Error("TestStorePutDeadLetter: Got ", stored, ".")
+		t.FailNow()
+	}
+
+	if err := Common.DBStore.MarkDeadLetterReplayed(stored); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+}
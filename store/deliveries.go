@@ -0,0 +1,37 @@
+package store
+
+import (
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+// DefaultDeliveriesLimit is the number of deliveries returned when no limit is
+// given.
+const DefaultDeliveriesLimit = 100
+
+func (pg *Postgres) PutDelivery(delivery *obj.Delivery) error {
+	if err := delivery.Validate(); err != nil {
+		return err
+	}
+
+	_, err := pg.db.NamedExec(
+		`INSERT INTO notification_deliveries (customer_id, notification_id, check_id, type, passing, status, error, attempt, latency_ms, created_at)
+		VALUES (:customer_id, :notification_id, :check_id, :type, :passing, :status, :error, :attempt, :latency_ms, :created_at)`, delivery)
+	return err
+}
+
+// GetDeliveriesByCheckId returns the most recent deliveries for a check, newest
+// first.
+func (pg *Postgres) GetDeliveriesByCheckId(user *schema.User, checkId string, limit int) ([]*obj.Delivery, error) {
+	if limit <= 0 {
+		limit = DefaultDeliveriesLimit
+	}
+
+	deliveries := []*obj.Delivery{}
+	err := pg.db.Select(&deliveries, "SELECT * FROM notification_deliveries WHERE customer_id = $1 AND check_id = $2 ORDER BY created_at DESC, id DESC LIMIT $3", user.CustomerId, checkId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStorePutDelivery(t *testing.T) {
+	event := obj.GenerateFailingTestEvent()
+	event.Result.CustomerId = Common.User.CustomerId
+
+	delivery := obj.NewDelivery(Common.Notifications[0], event, obj.DeliveryStatusSent, nil)
+	if err := Common.DBStore.PutDelivery(delivery); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	deliveries, err := Common.DBStore.GetDeliveriesByCheckId(Common.User, event.Result.CheckId, 1)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	if len(deliveries) != 1 || deliveries[0].Status != obj.DeliveryStatusSent {
+		log.Error("TestStorePutDelivery: Got ", deliveries, ".")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+func (pg *Postgres) GetEscalationPolicy(user *schema.User, checkId string) (*obj.EscalationPolicy, error) {
+	return pg.UnsafeGetEscalationPolicy(user.CustomerId, checkId)
+}
+
+// UnsafeGetEscalationPolicy returns the escalation policy for a check, or nil
+// if it doesn't have one.
+func (pg *Postgres) UnsafeGetEscalationPolicy(customerId, checkId string) (*obj.EscalationPolicy, error) {
+	policy := &obj.EscalationPolicy{}
+	err := pg.db.Get(policy, "SELECT * FROM escalation_policies WHERE customer_id = $1 AND check_id = $2", customerId, checkId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// PutEscalationPolicy creates or replaces the escalation policy for a check.
+func (pg *Postgres) PutEscalationPolicy(user *schema.User, policy *obj.EscalationPolicy) error {
+	policy.CustomerId = user.CustomerId
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	_, err := pg.db.NamedExec(
+		`INSERT INTO escalation_policies (customer_id, check_id, steps)
+		VALUES (:customer_id, :check_id, :steps)
+		ON CONFLICT (customer_id, check_id) DO UPDATE SET steps = EXCLUDED.steps, updated_at = now()`, policy)
+	return err
+}
+
+func (pg *Postgres) DeleteEscalationPolicy(user *schema.User, checkId string) error {
+	_, err := pg.db.Exec("DELETE FROM escalation_policies WHERE customer_id = $1 AND check_id = $2", user.CustomerId, checkId)
+	return err
+}
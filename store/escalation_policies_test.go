@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStorePutEscalationPolicy(t *testing.T) {
+	policy := &obj.EscalationPolicy{
+		CheckId: "00001",
+		Steps: obj.EscalationSteps{
+			{Delay: 0, Notifications: []*obj.Notification{{Type: "slack_bot", Value: "#ops"}}},
+		},
+	}
+
+	if err := Common.DBStore.PutEscalationPolicy(Common.User, policy); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	// replacing an existing policy must not fail on the primary key
+	policy.Steps = append(policy.Steps, &obj.EscalationStep{Delay: 600, Notifications: []*obj.Notification{{Type: "email", Value: "ops@example.com"}}})
+	if err := Common.DBStore.PutEscalationPolicy(Common.User, policy); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	stored, err := Common.DBStore.GetEscalationPolicy(Common.User, "00001")
+	if err != nil || stored == nil {
+		log.Error("TestStorePutEscalationPolicy: couldn't get escalation policy.", err)
+		t.FailNow()
+	}
+	if len(stored.Steps) != 2 || stored.Steps[1].Delay != 600 {
+		log.Error("TestStorePutEscalationPolicy: escalation policy wasn't replaced.")
+		t.FailNow()
+	}
+
+	if err := Common.DBStore.DeleteEscalationPolicy(Common.User, "00001"); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	stored, err = Common.DBStore.GetEscalationPolicy(Common.User, "00001")
+	if err != nil || stored != nil {
+		log.Error("TestStorePutEscalationPolicy: escalation policy wasn't deleted.")
+		t.FailNow()
+	}
+}
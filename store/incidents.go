@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+// GetOpenIncident returns the unresolved incident for a check, or nil if the
+// check has none.
+func (pg *Postgres) GetOpenIncident(customerId, checkId string) (*obj.Incident, error) {
+	incident := &obj.Incident{}
+	err := pg.db.Get(incident, "SELECT * FROM incidents WHERE customer_id = $1 AND check_id = $2 AND resolved_at IS NULL", customerId, checkId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// OpenIncident returns the unresolved incident for a check, opening one if
+// there is none.
+func (pg *Postgres) OpenIncident(customerId, checkId string) (*obj.Incident, error) {
+	incident, err := pg.GetOpenIncident(customerId, checkId)
+	if err != nil || incident != nil {
+		return incident, err
+	}
+
+	incident = &obj.Incident{
+		CustomerId: customerId,
+		CheckId:    checkId,
+	}
+	if err := incident.Validate(); err != nil {
+		return nil, err
+	}
+
+	err = pg.db.QueryRowx(
+		"INSERT INTO incidents (customer_id, check_id) VALUES ($1, $2) RETURNING *",
+		customerId, checkId).StructScan(incident)
+	if err != nil {
+		// another worker may have opened it first
+		if existing, getErr := pg.GetOpenIncident(customerId, checkId); getErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return incident, nil
+}
+
+// AcknowledgeIncident marks a check's unresolved incident as acknowledged by
+// the user. It returns sql.ErrNoRows if the check has no unresolved incident.
+func (pg *Postgres) AcknowledgeIncident(user *schema.User, checkId string) (*obj.Incident, error) {
+	incident := &obj.Incident{}
+	err := pg.db.QueryRowx(
+		`UPDATE incidents SET acknowledged_at = coalesce(acknowledged_at, now()), acknowledged_by = coalesce(acknowledged_by, $3)
+		WHERE customer_id = $1 AND check_id = $2 AND resolved_at IS NULL RETURNING *`,
+		user.CustomerId, checkId, user.Id).StructScan(incident)
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// ResolveIncident closes a check's unresolved incident, if it has one.
+func (pg *Postgres) ResolveIncident(customerId, checkId string) error {
+	_, err := pg.db.Exec("UPDATE incidents SET resolved_at = now() WHERE customer_id = $1 AND check_id = $2 AND resolved_at IS NULL", customerId, checkId)
+	return err
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"testing"
+
+	log "github.com/opsee/logrus"
+)
+
+func TestStoreIncidents(t *testing.T) {
+	incident, err := Common.DBStore.OpenIncident(Common.User.CustomerId, "00001")
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	// opening an incident for a check that has one returns the same incident
+	again, err := Common.DBStore.OpenIncident(Common.User.CustomerId, "00001")
+	if err != nil || again.Id != incident.Id {
+		log.Error("TestStoreIncidents: opened a second incident.", err)
+		t.FailNow()
+	}
+
+	acked, err := Common.DBStore.AcknowledgeIncident(Common.User, "00001")
+	if err != nil || !acked.Acknowledged() {
+		log.Error("TestStoreIncidents: couldn't acknowledge incident.", err)
+		t.FailNow()
+	}
+
+	if err := Common.DBStore.ResolveIncident(Common.User.CustomerId, "00001"); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	open, err := Common.DBStore.GetOpenIncident(Common.User.CustomerId, "00001")
+	if err != nil || open != nil {
+		log.Error("TestStoreIncidents: incident wasn't resolved.")
+		t.FailNow()
+	}
+
+	if _, err := Common.DBStore.AcknowledgeIncident(Common.User, "00001"); err == nil {
+		log.Error("TestStoreIncidents: acknowledged a resolved incident.")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,976 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+// Memory is an in-memory Store for tests and local development. It mirrors
+// the behaviour of Postgres, including which lookups return sql.ErrNoRows and
+// which return nil, but nothing survives a restart.
+type Memory struct {
+	sync.Mutex
+
+	nextId               int
+	notifications        map[int]*obj.Notification
+	defaultNotifications map[string][]*obj.Notification
+	thresholds           map[string]*obj.Threshold
+	slackOAuthResponses  map[string][]byte
+	slackThreads         map[string]*obj.SlackThread
+	pagerDutyResponses   map[string][]byte
+	opsgenieIntegrations map[string][]byte
+	webhookSecrets       map[string]*obj.WebhookSecret
+	deliveries           []*obj.Delivery
+	deadLetters          map[int]*obj.DeadLetter
+	checkStates          map[string]*obj.CheckState
+	silences             map[int]*obj.Silence
+	escalationPolicies   map[string]*obj.EscalationPolicy
+	incidents            []*obj.Incident
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		notifications:        map[int]*obj.Notification{},
+		defaultNotifications: map[string][]*obj.Notification{},
+		thresholds:           map[string]*obj.Threshold{},
+		slackOAuthResponses:  map[string][]byte{},
+		slackThreads:         map[string]*obj.SlackThread{},
+		pagerDutyResponses:   map[string][]byte{},
+		opsgenieIntegrations: map[string][]byte{},
+		webhookSecrets:       map[string]*obj.WebhookSecret{},
+		deadLetters:          map[int]*obj.DeadLetter{},
+		checkStates:          map[string]*obj.CheckState{},
+		silences:             map[int]*obj.Silence{},
+		escalationPolicies:   map[string]*obj.EscalationPolicy{},
+	}
+}
+
+// id hands out serial ids shared by every table, callers must hold the lock.
+func (m *Memory) id() int {
+	m.nextId++
+	return m.nextId
+}
+
+func memoryKey(parts ...string) string {
+	key := ""
+	for _, part := range parts {
+		key += part + "/"
+	}
+	return key
+}
+
+func (m *Memory) GetNotifications(user *schema.User, oldNotifications []*obj.Notification) ([]*obj.Notification, error) {
+	notifications := []*obj.Notification{}
+	for _, oldNotification := range oldNotifications {
+		newNotification, err := m.GetNotification(user, oldNotification.Id)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to get notification %d, for customerId %s", oldNotification.Id, user.CustomerId)
+		}
+		notifications = append(notifications, newNotification)
+	}
+
+	return notifications, nil
+}
+
+func (m *Memory) GetNotification(user *schema.User, id int) (*obj.Notification, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	notification, ok := m.notifications[id]
+	if !ok || notification.CustomerId != user.CustomerId {
+		return &obj.Notification{}, sql.ErrNoRows
+	}
+
+	n := *notification
+	return &n, nil
+}
+
+func (m *Memory) GetNotificationsByUser(user *schema.User) ([]*obj.Notification, error) {
+	return m.findNotifications(func(n *obj.Notification) bool {
+		return n.CustomerId == user.CustomerId
+	}), nil
+}
+
+func (m *Memory) GetNotificationsByCheckId(user *schema.User, checkId string) ([]*obj.Notification, error) {
+	notifications := m.findNotifications(func(n *obj.Notification) bool {
+		return n.CustomerId == user.CustomerId && n.CheckId == checkId
+	})
+	if notifications == nil {
+		notifications = []*obj.Notification{}
+	}
+	return notifications, nil
+}
+
+func (m *Memory) UnsafeGetNotificationsByCheckId(checkId string) ([]*obj.Notification, error) {
+	notifications := m.findNotifications(func(n *obj.Notification) bool {
+		return n.CheckId == checkId
+	})
+	if notifications == nil {
+		notifications = []*obj.Notification{}
+	}
+	return notifications, nil
+}
+
+// findNotifications returns copies of matching notifications in insertion
+// order.
+func (m *Memory) findNotifications(match func(*obj.Notification) bool) []*obj.Notification {
+	m.Lock()
+	defer m.Unlock()
+
+	var notifications []*obj.Notification
+	for _, notification := range m.notifications {
+		if match(notification) {
+			n := *notification
+			notifications = append(notifications, &n)
+		}
+	}
+
+	sort.Sort(notificationsById(notifications))
+	return notifications
+}
+
+type notificationsById []*obj.Notification
+
+func (s notificationsById) Len() int           { return len(s) }
+func (s notificationsById) Less(i, j int) bool { return s[i].Id < s[j].Id }
+func (s notificationsById) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// putNotification stores a copy of the notification, callers must hold the lock.
+func (m *Memory) putNotification(notification *obj.Notification) {
+	n := *notification
+	n.Id = m.id()
+	m.notifications[n.Id] = &n
+}
+
+// deleteNotificationsByCheckId callers must hold the lock.
+func (m *Memory) deleteNotificationsByCheckId(customerId, checkId string) {
+	for id, n := range m.notifications {
+		if n.CustomerId == customerId && n.CheckId == checkId {
+			delete(m.notifications, id)
+		}
+	}
+}
+
+func (m *Memory) PutNotifications(user *schema.User, notifications []*obj.Notification) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, notification := range notifications {
+		m.putNotification(notification)
+	}
+
+	return nil
+}
+
+func (m *Memory) PutNotificationsMultiCheck(notificationsObjs []*obj.Notifications) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, notificationsObj := range notificationsObjs {
+		// delete all notifications associated with this checkId
+		if len(notificationsObj.Notifications) > 0 {
+			notification := notificationsObj.Notifications[0]
+			m.deleteNotificationsByCheckId(notification.CustomerId, notification.CheckId)
+		}
+
+		for _, notification := range notificationsObj.Notifications {
+			m.putNotification(notification)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) DeleteNotification(user *schema.User, notification *obj.Notification) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if n, ok := m.notifications[notification.Id]; ok && n.CustomerId == user.CustomerId {
+		delete(m.notifications, notification.Id)
+	}
+
+	return nil
+}
+
+func (m *Memory) DeleteNotifications(notifications []*obj.Notification) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, notification := range notifications {
+		if n, ok := m.notifications[notification.Id]; ok && n.CustomerId == notification.CustomerId {
+			delete(m.notifications, notification.Id)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) DeleteNotificationsByUser(user *schema.User) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for id, n := range m.notifications {
+		if n.CustomerId == user.CustomerId {
+			delete(m.notifications, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) DeleteNotificationsByCheckId(user *schema.User, checkId string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.deleteNotificationsByCheckId(user.CustomerId, checkId)
+	return nil
+}
+
+// GetDefaultNotifications only returns type, value and webhook options, like
+// Postgres.
+func (m *Memory) GetDefaultNotifications(user *schema.User) ([]*obj.Notification, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var notifications []*obj.Notification
+	for _, notification := range m.defaultNotifications[user.CustomerId] {
+		notifications = append(notifications, &obj.Notification{
+			Type:    notification.Type,
+			Value:   notification.Value,
+			Webhook: notification.Webhook,
+		})
+	}
+
+	return notifications, nil
+}
+
+func (m *Memory) PutDefaultNotifications(user *schema.User, notifications []*obj.Notification) error {
+	m.Lock()
+	defer m.Unlock()
+
+	defaults := []*obj.Notification{}
+	for _, notification := range notifications {
+		n := *notification
+		n.Id = m.id()
+		defaults = append(defaults, &n)
+	}
+	m.defaultNotifications[user.CustomerId] = defaults
+
+	return nil
+}
+
+func (m *Memory) GetThreshold(user *schema.User, checkId string) (*obj.Threshold, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	threshold, ok := m.thresholds[memoryKey(user.CustomerId, checkId)]
+	if !ok {
+		return nil, nil
+	}
+
+	t := *threshold
+	return &t, nil
+}
+
+func (m *Memory) UnsafeGetThreshold(customerId, checkId string) (*obj.Threshold, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	threshold, ok := m.thresholds[memoryKey(customerId, checkId)]
+	if !ok {
+		threshold, ok = m.thresholds[memoryKey(customerId, "")]
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	t := *threshold
+	return &t, nil
+}
+
+func (m *Memory) PutThreshold(user *schema.User, threshold *obj.Threshold) error {
+	threshold.CustomerId = user.CustomerId
+	if err := threshold.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	t := *threshold
+	m.thresholds[memoryKey(t.CustomerId, t.CheckId)] = &t
+	return nil
+}
+
+func (m *Memory) DeleteThreshold(user *schema.User, checkId string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.thresholds, memoryKey(user.CustomerId, checkId))
+	return nil
+}
+
+// oauth responses are kept as json, the way Postgres keeps them in jsonb, so
+// callers never share them.
+
+func (m *Memory) GetSlackOAuthResponse(user *schema.User) (*obj.SlackOAuthResponse, error) {
+	oaResponses, err := m.GetSlackOAuthResponses(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(oaResponses) > 0 {
+		return oaResponses[0], nil
+	}
+
+	return nil, nil
+}
+
+func (m *Memory) GetSlackOAuthResponses(user *schema.User) ([]*obj.SlackOAuthResponse, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	oaResponses := []*obj.SlackOAuthResponse{}
+	if data, ok := m.slackOAuthResponses[user.CustomerId]; ok {
+		oaResponse := &obj.SlackOAuthResponse{}
+		if err := json.Unmarshal(data, oaResponse); err == nil {
+			oaResponses = append(oaResponses, oaResponse)
+		}
+	}
+
+	return oaResponses, nil
+}
+
+func (m *Memory) PutSlackOAuthResponse(user *schema.User, s *obj.SlackOAuthResponse) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.slackOAuthResponses[user.CustomerId] = data
+	return nil
+}
+
+func (m *Memory) UpdateSlackOAuthResponse(user *schema.User, s *obj.SlackOAuthResponse) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.slackOAuthResponses[user.CustomerId]; ok {
+		m.slackOAuthResponses[user.CustomerId] = data
+	}
+	return nil
+}
+
+func (m *Memory) DeleteSlackOAuthResponsesByUser(user *schema.User) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.slackOAuthResponses, user.CustomerId)
+	return nil
+}
+
+func (m *Memory) GetPagerDutyOAuthResponse(user *schema.User) (*obj.PagerDutyOAuthResponse, error) {
+	oaResponses, err := m.GetPagerDutyOAuthResponses(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(oaResponses) > 0 {
+		return oaResponses[0], nil
+	}
+
+	return nil, nil
+}
+
+func (m *Memory) GetPagerDutyOAuthResponses(user *schema.User) ([]*obj.PagerDutyOAuthResponse, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	oaResponses := []*obj.PagerDutyOAuthResponse{}
+	if data, ok := m.pagerDutyResponses[user.CustomerId]; ok {
+		oaResponse := &obj.PagerDutyOAuthResponse{}
+		if err := json.Unmarshal(data, oaResponse); err == nil {
+			oaResponses = append(oaResponses, oaResponse)
+		}
+	}
+
+	return oaResponses, nil
+}
+
+func (m *Memory) PutPagerDutyOAuthResponse(user *schema.User, s *obj.PagerDutyOAuthResponse) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.pagerDutyResponses[user.CustomerId] = data
+	return nil
+}
+
+func (m *Memory) UpdatePagerDutyOAuthResponse(user *schema.User, s *obj.PagerDutyOAuthResponse) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.pagerDutyResponses[user.CustomerId]; ok {
+		m.pagerDutyResponses[user.CustomerId] = data
+	}
+	return nil
+}
+
+func (m *Memory) DeletePagerDutyOAuthResponsesByUser(user *schema.User) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.pagerDutyResponses, user.CustomerId)
+	return nil
+}
+
+func (m *Memory) GetSlackThread(customerId, checkId, channel string) (*obj.SlackThread, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	t, ok := m.slackThreads[memoryKey(customerId, checkId, channel)]
+	if !ok {
+		return nil, nil
+	}
+
+	thread := *t
+	return &thread, nil
+}
+
+func (m *Memory) PutSlackThread(thread *obj.SlackThread) error {
+	if err := thread.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	t := *thread
+	t.CreatedAt = time.Now().UTC()
+	m.slackThreads[memoryKey(t.CustomerId, t.CheckId, t.Channel)] = &t
+	return nil
+}
+
+func (m *Memory) DeleteSlackThread(customerId, checkId, channel string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.slackThreads, memoryKey(customerId, checkId, channel))
+	return nil
+}
+
+func (m *Memory) GetOpsgenieIntegration(user *schema.User) (*obj.OpsgenieIntegration, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	data, ok := m.opsgenieIntegrations[user.CustomerId]
+	if !ok {
+		return nil, nil
+	}
+
+	integration := &obj.OpsgenieIntegration{}
+	if err := json.Unmarshal(data, integration); err != nil {
+		return nil, err
+	}
+
+	return integration, nil
+}
+
+func (m *Memory) PutOpsgenieIntegration(user *schema.User, integration *obj.OpsgenieIntegration) error {
+	data, err := json.Marshal(integration)
+	if err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.opsgenieIntegrations[user.CustomerId] = data
+	return nil
+}
+
+func (m *Memory) DeleteOpsgenieIntegrationsByUser(user *schema.User) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.opsgenieIntegrations, user.CustomerId)
+	return nil
+}
+
+func (m *Memory) GetWebhookSecret(user *schema.User) (*obj.WebhookSecret, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	secret, ok := m.webhookSecrets[user.CustomerId]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *secret
+	return &copied, nil
+}
+
+func (m *Memory) PutWebhookSecret(secret *obj.WebhookSecret) error {
+	if err := secret.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	stored := *secret
+	m.webhookSecrets[secret.CustomerId] = &stored
+	return nil
+}
+
+func (m *Memory) PutDelivery(delivery *obj.Delivery) error {
+	if err := delivery.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	d := *delivery
+	d.Id = m.id()
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	m.deliveries = append(m.deliveries, &d)
+	return nil
+}
+
+// GetDeliveriesByCheckId returns the most recent deliveries for a check, newest
+// first.
+func (m *Memory) GetDeliveriesByCheckId(user *schema.User, checkId string, limit int) ([]*obj.Delivery, error) {
+	if limit <= 0 {
+		limit = DefaultDeliveriesLimit
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	deliveries := []*obj.Delivery{}
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		d := m.deliveries[i]
+		if d.CustomerId == user.CustomerId && d.CheckId == checkId {
+			delivery := *d
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+
+	sort.Stable(deliveriesByCreatedAt(deliveries))
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+type deliveriesByCreatedAt []*obj.Delivery
+
+func (s deliveriesByCreatedAt) Len() int           { return len(s) }
+func (s deliveriesByCreatedAt) Less(i, j int) bool { return s[i].CreatedAt.After(s[j].CreatedAt) }
+func (s deliveriesByCreatedAt) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (m *Memory) PutDeadLetter(deadLetter *obj.DeadLetter) error {
+	if err := deadLetter.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	deadLetter.Id = m.id()
+	d := *deadLetter
+	d.CheckResult = nil
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	m.deadLetters[d.Id] = &d
+	return nil
+}
+
+func (m *Memory) GetDeadLetters(user *schema.User, limit int) ([]*obj.DeadLetter, error) {
+	return m.findDeadLetters(limit, func(d *obj.DeadLetter) bool {
+		return d.CustomerId == user.CustomerId
+	}), nil
+}
+
+func (m *Memory) GetDeadLetter(user *schema.User, id int) (*obj.DeadLetter, error) {
+	deadLetter, err := m.UnsafeGetDeadLetter(id)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetter.CustomerId != user.CustomerId {
+		return nil, sql.ErrNoRows
+	}
+	return deadLetter, nil
+}
+
+// UnsafeGetDeadLetters returns dead letters for every customer. It is meant for
+// operators only.
+func (m *Memory) UnsafeGetDeadLetters(limit int) ([]*obj.DeadLetter, error) {
+	return m.findDeadLetters(limit, func(d *obj.DeadLetter) bool {
+		return true
+	}), nil
+}
+
+// UnsafeGetDeadLetter returns a dead letter regardless of customer. It is meant
+// for operators only.
+func (m *Memory) UnsafeGetDeadLetter(id int) (*obj.DeadLetter, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	d, ok := m.deadLetters[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	deadLetter := *d
+	return &deadLetter, deadLetter.UnmarshalResult()
+}
+
+func (m *Memory) findDeadLetters(limit int, match func(*obj.DeadLetter) bool) []*obj.DeadLetter {
+	if limit <= 0 {
+		limit = DefaultDeadLettersLimit
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	deadLetters := []*obj.DeadLetter{}
+	for _, d := range m.deadLetters {
+		if match(d) {
+			deadLetter := *d
+			deadLetters = append(deadLetters, &deadLetter)
+		}
+	}
+
+	sort.Sort(deadLettersByCreatedAt(deadLetters))
+	if len(deadLetters) > limit {
+		deadLetters = deadLetters[:limit]
+	}
+	return unmarshalDeadLetters(deadLetters)
+}
+
+type deadLettersByCreatedAt []*obj.DeadLetter
+
+func (s deadLettersByCreatedAt) Len() int { return len(s) }
+func (s deadLettersByCreatedAt) Less(i, j int) bool {
+	if s[i].CreatedAt.Equal(s[j].CreatedAt) {
+		return s[i].Id > s[j].Id
+	}
+	return s[i].CreatedAt.After(s[j].CreatedAt)
+}
+func (s deadLettersByCreatedAt) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (m *Memory) MarkDeadLetterReplayed(deadLetter *obj.DeadLetter) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if d, ok := m.deadLetters[deadLetter.Id]; ok {
+		now := time.Now()
+		d.ReplayedAt = &now
+	}
+	return nil
+}
+
+// GetCheckState returns the last recorded state for a check's target, or nil if
+// we have never recorded one.
+func (m *Memory) GetCheckState(customerId, checkId, targetId string) (*obj.CheckState, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.checkStates[memoryKey(customerId, checkId, targetId)]
+	if !ok {
+		return nil, nil
+	}
+
+	state := *s
+	state.Transitions = append(obj.Timestamps{}, s.Transitions...)
+	return &state, nil
+}
+
+// PutCheckState records state in place of previous, the state it was derived
+// from or nil if there was none, returning ErrCheckStateChanged if it was
+// replaced in the meantime.
+func (m *Memory) PutCheckState(previous, state *obj.CheckState) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	key := memoryKey(state.CustomerId, state.CheckId, state.TargetId)
+	if current, ok := m.checkStates[key]; ok {
+		if previous == nil || !current.UpdatedAt.Equal(previous.UpdatedAt) {
+			return ErrCheckStateChanged
+		}
+	}
+
+	s := *state
+	s.Transitions = append(obj.Timestamps{}, state.Transitions...)
+	m.checkStates[key] = &s
+	return nil
+}
+
+func (m *Memory) GetSilences(user *schema.User) ([]*obj.Silence, error) {
+	return m.findSilences(func(s *obj.Silence) bool {
+		return s.CustomerId == user.CustomerId
+	}), nil
+}
+
+func (m *Memory) GetSilence(user *schema.User, id int) (*obj.Silence, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.silences[id]
+	if !ok || s.CustomerId != user.CustomerId {
+		return &obj.Silence{}, sql.ErrNoRows
+	}
+
+	silence := *s
+	return &silence, nil
+}
+
+// GetActiveSilences returns every silence for a customer that is in effect at
+// the given time.
+func (m *Memory) GetActiveSilences(customerId string, at time.Time) ([]*obj.Silence, error) {
+	return m.findSilences(func(s *obj.Silence) bool {
+		return s.CustomerId == customerId && s.Active(at)
+	}), nil
+}
+
+func (m *Memory) findSilences(match func(*obj.Silence) bool) []*obj.Silence {
+	m.Lock()
+	defer m.Unlock()
+
+	silences := []*obj.Silence{}
+	for _, s := range m.silences {
+		if match(s) {
+			silence := *s
+			silences = append(silences, &silence)
+		}
+	}
+
+	sort.Sort(silencesByStartsAt(silences))
+	return silences
+}
+
+type silencesByStartsAt []*obj.Silence
+
+func (s silencesByStartsAt) Len() int           { return len(s) }
+func (s silencesByStartsAt) Less(i, j int) bool { return s[i].StartsAt.After(s[j].StartsAt) }
+func (s silencesByStartsAt) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (m *Memory) PutSilence(user *schema.User, silence *obj.Silence) error {
+	silence.CustomerId = user.CustomerId
+	silence.UserId = int(user.Id)
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	silence.Id = m.id()
+	silence.CreatedAt = time.Now()
+	s := *silence
+	m.silences[s.Id] = &s
+	return nil
+}
+
+func (m *Memory) UpdateSilence(user *schema.User, silence *obj.Silence) error {
+	silence.CustomerId = user.CustomerId
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.silences[silence.Id]
+	if !ok || s.CustomerId != user.CustomerId {
+		return nil
+	}
+
+	s.CheckId = silence.CheckId
+	s.TargetId = silence.TargetId
+	s.Reason = silence.Reason
+	s.StartsAt = silence.StartsAt
+	s.EndsAt = silence.EndsAt
+	return nil
+}
+
+func (m *Memory) DeleteSilence(user *schema.User, id int) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if s, ok := m.silences[id]; ok && s.CustomerId == user.CustomerId {
+		delete(m.silences, id)
+	}
+	return nil
+}
+
+func (m *Memory) GetEscalationPolicy(user *schema.User, checkId string) (*obj.EscalationPolicy, error) {
+	return m.UnsafeGetEscalationPolicy(user.CustomerId, checkId)
+}
+
+// UnsafeGetEscalationPolicy returns the escalation policy for a check, or nil
+// if it doesn't have one.
+func (m *Memory) UnsafeGetEscalationPolicy(customerId, checkId string) (*obj.EscalationPolicy, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	p, ok := m.escalationPolicies[memoryKey(customerId, checkId)]
+	if !ok {
+		return nil, nil
+	}
+
+	policy := *p
+	policy.Steps = append(obj.EscalationSteps{}, p.Steps...)
+	return &policy, nil
+}
+
+// PutEscalationPolicy creates or replaces the escalation policy for a check.
+func (m *Memory) PutEscalationPolicy(user *schema.User, policy *obj.EscalationPolicy) error {
+	policy.CustomerId = user.CustomerId
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	key := memoryKey(policy.CustomerId, policy.CheckId)
+	now := time.Now()
+
+	p := *policy
+	p.Steps = append(obj.EscalationSteps{}, policy.Steps...)
+	p.CreatedAt = now
+	if existing, ok := m.escalationPolicies[key]; ok {
+		p.CreatedAt = existing.CreatedAt
+	}
+	p.UpdatedAt = now
+	m.escalationPolicies[key] = &p
+	return nil
+}
+
+func (m *Memory) DeleteEscalationPolicy(user *schema.User, checkId string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.escalationPolicies, memoryKey(user.CustomerId, checkId))
+	return nil
+}
+
+// openIncident callers must hold the lock.
+func (m *Memory) openIncident(customerId, checkId string) *obj.Incident {
+	for _, incident := range m.incidents {
+		if incident.CustomerId == customerId && incident.CheckId == checkId && incident.ResolvedAt == nil {
+			return incident
+		}
+	}
+	return nil
+}
+
+// GetOpenIncident returns the unresolved incident for a check, or nil if the
+// check has none.
+func (m *Memory) GetOpenIncident(customerId, checkId string) (*obj.Incident, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	incident := m.openIncident(customerId, checkId)
+	if incident == nil {
+		return nil, nil
+	}
+
+	i := *incident
+	return &i, nil
+}
+
+// OpenIncident returns the unresolved incident for a check, opening one if
+// there is none.
+func (m *Memory) OpenIncident(customerId, checkId string) (*obj.Incident, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	incident := m.openIncident(customerId, checkId)
+	if incident == nil {
+		incident = &obj.Incident{
+			CustomerId: customerId,
+			CheckId:    checkId,
+		}
+		if err := incident.Validate(); err != nil {
+			return nil, err
+		}
+
+		incident.Id = m.id()
+		incident.OpenedAt = time.Now()
+		m.incidents = append(m.incidents, incident)
+	}
+
+	i := *incident
+	return &i, nil
+}
+
+// AcknowledgeIncident marks a check's unresolved incident as acknowledged by
+// the user. It returns sql.ErrNoRows if the check has no unresolved incident.
+func (m *Memory) AcknowledgeIncident(user *schema.User, checkId string) (*obj.Incident, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	incident := m.openIncident(user.CustomerId, checkId)
+	if incident == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if incident.AcknowledgedAt == nil {
+		now := time.Now()
+		incident.AcknowledgedAt = &now
+	}
+	if incident.AcknowledgedBy == nil {
+		userId := int(user.Id)
+		incident.AcknowledgedBy = &userId
+	}
+
+	i := *incident
+	return &i, nil
+}
+
+// ResolveIncident closes a check's unresolved incident, if it has one.
+func (m *Memory) ResolveIncident(customerId, checkId string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if incident := m.openIncident(customerId, checkId); incident != nil {
+		now := time.Now()
+		incident.ResolvedAt = &now
+	}
+	return nil
+}
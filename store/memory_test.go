@@ -0,0 +1,233 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+	"github.com/stretchr/testify/assert"
+)
+
+var memoryTestUser = &schema.User{
+	Id:         13,
+	CustomerId: "5963d7bc-6ba2-11e5-8603-6ba085b2f5b5",
+}
+
+func TestMemoryNotifications(t *testing.T) {
+	m := NewMemory()
+	notifications := []*obj.Notification{
+		&obj.Notification{CustomerId: memoryTestUser.CustomerId, CheckId: "00001", Value: "off", Type: "slack_bot"},
+		&obj.Notification{CustomerId: memoryTestUser.CustomerId, CheckId: "00001", Value: "you", Type: "email"},
+		&obj.Notification{CustomerId: memoryTestUser.CustomerId, CheckId: "00002", Value: "go", Type: "webhook"},
+	}
+	assert.Nil(t, m.PutNotifications(memoryTestUser, notifications))
+
+	byCheck, err := m.GetNotificationsByCheckId(memoryTestUser, "00001")
+	assert.Nil(t, err)
+	if assert.Len(t, byCheck, 2) {
+		assert.Equal(t, "off", byCheck[0].Value)
+		assert.NotEqual(t, 0, byCheck[0].Id)
+	}
+
+	// stored notifications are copies
+	byCheck[0].Value = "changed"
+	notification, err := m.GetNotification(memoryTestUser, byCheck[0].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "off", notification.Value)
+
+	_, err = m.GetNotification(&schema.User{CustomerId: "someone-else"}, byCheck[0].Id)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	err = m.PutNotificationsMultiCheck([]*obj.Notifications{
+		&obj.Notifications{Notifications: []*obj.Notification{
+			&obj.Notification{CustomerId: memoryTestUser.CustomerId, CheckId: "00001", Value: "pd", Type: "pagerduty"},
+		}},
+	})
+	assert.Nil(t, err)
+
+	all, err := m.GetNotificationsByUser(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Len(t, all, 2)
+
+	assert.Nil(t, m.DeleteNotificationsByCheckId(memoryTestUser, "00001"))
+	unsafe, err := m.UnsafeGetNotificationsByCheckId("00001")
+	assert.Nil(t, err)
+	assert.Empty(t, unsafe)
+
+	assert.Nil(t, m.PutDefaultNotifications(memoryTestUser, notifications[:1]))
+	defaults, err := m.GetDefaultNotifications(memoryTestUser)
+	assert.Nil(t, err)
+	if assert.Len(t, defaults, 1) {
+		assert.Equal(t, "slack_bot", defaults[0].Type)
+		assert.Equal(t, "", defaults[0].CustomerId)
+	}
+}
+
+func TestMemoryThresholds(t *testing.T) {
+	m := NewMemory()
+
+	threshold, err := m.UnsafeGetThreshold(memoryTestUser.CustomerId, "00001")
+	assert.Nil(t, err)
+	assert.Nil(t, threshold)
+
+	assert.Nil(t, m.PutThreshold(memoryTestUser, &obj.Threshold{Consecutive: 2}))
+	assert.Nil(t, m.PutThreshold(memoryTestUser, &obj.Threshold{CheckId: "00001", Consecutive: 5}))
+
+	threshold, err = m.UnsafeGetThreshold(memoryTestUser.CustomerId, "00001")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, threshold.Consecutive)
+
+	threshold, err = m.UnsafeGetThreshold(memoryTestUser.CustomerId, "00002")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, threshold.Consecutive)
+
+	assert.NotNil(t, m.PutThreshold(memoryTestUser, &obj.Threshold{Consecutive: -1}))
+}
+
+func TestMemoryOAuthResponses(t *testing.T) {
+	m := NewMemory()
+
+	slack, err := m.GetSlackOAuthResponse(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Nil(t, slack)
+
+	assert.Nil(t, m.PutSlackOAuthResponse(memoryTestUser, &obj.SlackOAuthResponse{
+		AccessToken: "token",
+		Bot:         &obj.SlackBotCreds{BotAccessToken: "bot-token"},
+	}))
+	slack, err = m.GetSlackOAuthResponse(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Equal(t, "bot-token", slack.Bot.BotAccessToken)
+
+	assert.Nil(t, m.PutPagerDutyOAuthResponse(memoryTestUser, &obj.PagerDutyOAuthResponse{ServiceKey: "key", Enabled: true}))
+	assert.Nil(t, m.UpdatePagerDutyOAuthResponse(memoryTestUser, &obj.PagerDutyOAuthResponse{ServiceKey: "new-key", Enabled: true}))
+	pd, err := m.GetPagerDutyOAuthResponse(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Equal(t, "new-key", pd.ServiceKey)
+
+	assert.Nil(t, m.DeletePagerDutyOAuthResponsesByUser(memoryTestUser))
+	pd, err = m.GetPagerDutyOAuthResponse(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Nil(t, pd)
+
+	thread, err := m.GetSlackThread(memoryTestUser.CustomerId, "00001", "#alerts")
+	assert.Nil(t, err)
+	assert.Nil(t, thread)
+
+	assert.NotNil(t, m.PutSlackThread(&obj.SlackThread{CustomerId: memoryTestUser.CustomerId, CheckId: "00001"}))
+	assert.Nil(t, m.PutSlackThread(&obj.SlackThread{CustomerId: memoryTestUser.CustomerId, CheckId: "00001", Channel: "#alerts", ChannelId: "C00000001", Ts: "1.000001"}))
+	thread, err = m.GetSlackThread(memoryTestUser.CustomerId, "00001", "#alerts")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.000001", thread.Ts)
+	assert.False(t, thread.CreatedAt.IsZero())
+
+	assert.Nil(t, m.DeleteSlackThread(memoryTestUser.CustomerId, "00001", "#alerts"))
+	thread, err = m.GetSlackThread(memoryTestUser.CustomerId, "00001", "#alerts")
+	assert.Nil(t, err)
+	assert.Nil(t, thread)
+
+	og, err := m.GetOpsgenieIntegration(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Nil(t, og)
+
+	assert.Nil(t, m.PutOpsgenieIntegration(memoryTestUser, &obj.OpsgenieIntegration{APIKey: "key", Enabled: true}))
+	assert.Nil(t, m.PutOpsgenieIntegration(memoryTestUser, &obj.OpsgenieIntegration{APIKey: "new-key", Enabled: true}))
+	og, err = m.GetOpsgenieIntegration(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Equal(t, "new-key", og.APIKey)
+
+	assert.Nil(t, m.DeleteOpsgenieIntegrationsByUser(memoryTestUser))
+	og, err = m.GetOpsgenieIntegration(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Nil(t, og)
+
+	ws, err := m.GetWebhookSecret(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Nil(t, ws)
+
+	assert.Nil(t, m.PutWebhookSecret(obj.NewWebhookSecret(memoryTestUser.CustomerId)))
+	rotated := obj.NewWebhookSecret(memoryTestUser.CustomerId)
+	assert.Nil(t, m.PutWebhookSecret(rotated))
+	ws, err = m.GetWebhookSecret(memoryTestUser)
+	assert.Nil(t, err)
+	assert.Equal(t, rotated.Secret, ws.Secret)
+}
+
+func TestMemorySilencesAndIncidents(t *testing.T) {
+	m := NewMemory()
+	now := time.Now()
+
+	silence := &obj.Silence{CheckId: "00001", StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)}
+	assert.Nil(t, m.PutSilence(memoryTestUser, silence))
+	assert.NotEqual(t, 0, silence.Id)
+
+	active, err := m.GetActiveSilences(memoryTestUser.CustomerId, now)
+	assert.Nil(t, err)
+	assert.Len(t, active, 1)
+
+	silence.EndsAt = now.Add(-time.Second)
+	assert.Nil(t, m.UpdateSilence(memoryTestUser, silence))
+	active, err = m.GetActiveSilences(memoryTestUser.CustomerId, now)
+	assert.Nil(t, err)
+	assert.Empty(t, active)
+
+	_, err = m.AcknowledgeIncident(memoryTestUser, "00001")
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	opened, err := m.OpenIncident(memoryTestUser.CustomerId, "00001")
+	assert.Nil(t, err)
+	again, err := m.OpenIncident(memoryTestUser.CustomerId, "00001")
+	assert.Nil(t, err)
+	assert.Equal(t, opened.Id, again.Id)
+
+	acked, err := m.AcknowledgeIncident(memoryTestUser, "00001")
+	assert.Nil(t, err)
+	assert.True(t, acked.Acknowledged())
+	assert.Equal(t, 13, *acked.AcknowledgedBy)
+
+	assert.Nil(t, m.ResolveIncident(memoryTestUser.CustomerId, "00001"))
+	incident, err := m.GetOpenIncident(memoryTestUser.CustomerId, "00001")
+	assert.Nil(t, err)
+	assert.Nil(t, incident)
+}
+
+func TestMemoryDeadLettersAndDeliveries(t *testing.T) {
+	m := NewMemory()
+	result := obj.GenerateFailingTestEvent().Result
+	result.CustomerId = memoryTestUser.CustomerId
+
+	deadLetter, err := obj.NewDeadLetter(result, 3, []string{"remote unavailable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, m.PutDeadLetter(deadLetter))
+
+	fetched, err := m.GetDeadLetter(memoryTestUser, deadLetter.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, result.CheckId, fetched.CheckResult.CheckId)
+
+	_, err = m.GetDeadLetter(&schema.User{CustomerId: "someone-else"}, deadLetter.Id)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	assert.Nil(t, m.MarkDeadLetterReplayed(deadLetter))
+	fetched, err = m.UnsafeGetDeadLetter(deadLetter.Id)
+	assert.Nil(t, err)
+	assert.NotNil(t, fetched.ReplayedAt)
+
+	for _, status := range []string{obj.DeliveryStatusRetrying, obj.DeliveryStatusSent} {
+		assert.Nil(t, m.PutDelivery(&obj.Delivery{
+			CustomerId: memoryTestUser.CustomerId,
+			CheckId:    result.CheckId,
+			Type:       "webhook",
+			Status:     status,
+		}))
+	}
+
+	deliveries, err := m.GetDeliveriesByCheckId(memoryTestUser, result.CheckId, 1)
+	assert.Nil(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, obj.DeliveryStatusSent, deliveries[0].Status)
+	}
+}
@@ -30,7 +30,7 @@ func (pg *Postgres) GetNotification(user *schema.User, id int) (*obj.Notificatio
 
 func (pg *Postgres) GetDefaultNotifications(user *schema.User) ([]*obj.Notification, error) {
 	var notifications []*obj.Notification
-	err := pg.db.Select(&notifications, "SELECT type, value FROM default_notifications WHERE customer_id = $1 limit 100", user.CustomerId)
+	err := pg.db.Select(&notifications, "SELECT type, value, webhook FROM default_notifications WHERE customer_id = $1 limit 100", user.CustomerId)
 	return notifications, err
 }
 
@@ -68,16 +68,16 @@ func (pg *Postgres) deleteNotificationsByCheckId(x sqlx.Ext, notification *obj.N
 
 func (pg *Postgres) putNotification(x sqlx.Ext, notification *obj.Notification) error {
 	_, err := sqlx.NamedExec(x,
-		`INSERT INTO notifications (customer_id, user_id, check_id, value, type)
-		VALUES (:customer_id, :user_id, :check_id, :value, :type)
+		`INSERT INTO notifications (customer_id, user_id, check_id, value, type, webhook)
+		VALUES (:customer_id, :user_id, :check_id, :value, :type, :webhook)
 		RETURNING id`, notification)
 	return err
 }
 
 func (pg *Postgres) putDefaultNotification(x sqlx.Ext, notification *obj.Notification) error {
 	_, err := sqlx.NamedExec(x,
-		`INSERT INTO default_notifications (customer_id, value, type)
-		VALUES (:customer_id, :value, :type)
+		`INSERT INTO default_notifications (customer_id, value, type, webhook)
+		VALUES (:customer_id, :value, :type, :webhook)
 		RETURNING id`, notification)
 	return err
 }
@@ -113,8 +113,8 @@ func (pg *Postgres) PutNotifications(user *schema.User, notifications []*obj.Not
 
 	for _, notification := range notifications {
 		_, err := tx.NamedExec(
-			`insert into notifications (customer_id, user_id, check_id, value, type)
-														 values (:customer_id, :user_id, :check_id, :value, :type)
+			`insert into notifications (customer_id, user_id, check_id, value, type, webhook)
+														 values (:customer_id, :user_id, :check_id, :value, :type, :webhook)
 														 			 returning id`, notification)
 
 		if err != nil {
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+// GetOpsgenieIntegration returns the customer's Opsgenie integration, or nil
+// if they haven't set one up.
+func (pg *Postgres) GetOpsgenieIntegration(user *schema.User) (*obj.OpsgenieIntegration, error) {
+	wrapper := &obj.OpsgenieIntegrationDBWrapper{}
+	err := pg.db.Get(wrapper, "SELECT id, customer_id, data FROM opsgenie_integrations WHERE customer_id = $1", user.CustomerId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	integration := &obj.OpsgenieIntegration{}
+	if err := wrapper.Data.Unmarshal(integration); err != nil {
+		return nil, err
+	}
+
+	return integration, nil
+}
+
+// PutOpsgenieIntegration creates or replaces the customer's Opsgenie integration.
+func (pg *Postgres) PutOpsgenieIntegration(user *schema.User, integration *obj.OpsgenieIntegration) error {
+	datjson, err := json.Marshal(integration)
+	if err != nil {
+		return err
+	}
+
+	wrapper := &obj.OpsgenieIntegrationDBWrapper{
+		CustomerId: user.CustomerId,
+		Data:       types.JSONText(string(datjson)),
+	}
+	if err := wrapper.Validate(); err != nil {
+		return err
+	}
+
+	_, err = pg.db.NamedExec(
+		`INSERT INTO opsgenie_integrations (customer_id, data) VALUES (:customer_id, :data)
+		ON CONFLICT (customer_id) DO UPDATE SET data = EXCLUDED.data`, wrapper)
+	return err
+}
+
+func (pg *Postgres) DeleteOpsgenieIntegrationsByUser(user *schema.User) error {
+	_, err := pg.db.Exec(`DELETE FROM opsgenie_integrations WHERE customer_id = $1`, user.CustomerId)
+	return err
+}
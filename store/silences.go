@@ -0,0 +1,73 @@
+package store
+
+import (
+	"time"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+func (pg *Postgres) GetSilences(user *schema.User) ([]*obj.Silence, error) {
+	silences := []*obj.Silence{}
+	err := pg.db.Select(&silences, "SELECT * FROM silences WHERE customer_id = $1 ORDER BY starts_at DESC", user.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+func (pg *Postgres) GetSilence(user *schema.User, id int) (*obj.Silence, error) {
+	silence := &obj.Silence{}
+	err := pg.db.Get(silence, "SELECT * FROM silences WHERE customer_id = $1 AND id = $2", user.CustomerId, id)
+	return silence, err
+}
+
+// GetActiveSilences returns every silence for a customer that is in effect at
+// the given time.
+func (pg *Postgres) GetActiveSilences(customerId string, at time.Time) ([]*obj.Silence, error) {
+	silences := []*obj.Silence{}
+	err := pg.db.Select(&silences, "SELECT * FROM silences WHERE customer_id = $1 AND starts_at <= $2 AND ends_at > $2", customerId, at)
+	if err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+func (pg *Postgres) PutSilence(user *schema.User, silence *obj.Silence) error {
+	silence.CustomerId = user.CustomerId
+	silence.UserId = int(user.Id)
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	rows, err := pg.db.NamedQuery(
+		`INSERT INTO silences (customer_id, user_id, check_id, target_id, reason, starts_at, ends_at)
+		VALUES (:customer_id, :user_id, :check_id, :target_id, :reason, :starts_at, :ends_at)
+		RETURNING id, created_at`, silence)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return rows.Scan(&silence.Id, &silence.CreatedAt)
+	}
+	return rows.Err()
+}
+
+func (pg *Postgres) UpdateSilence(user *schema.User, silence *obj.Silence) error {
+	silence.CustomerId = user.CustomerId
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	_, err := pg.db.NamedExec(
+		`UPDATE silences SET check_id = :check_id, target_id = :target_id, reason = :reason, starts_at = :starts_at, ends_at = :ends_at
+		WHERE id = :id AND customer_id = :customer_id`, silence)
+	return err
+}
+
+func (pg *Postgres) DeleteSilence(user *schema.User, id int) error {
+	_, err := pg.db.Exec("DELETE FROM silences WHERE customer_id = $1 AND id = $2", user.CustomerId, id)
+	return err
+}
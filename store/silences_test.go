@@ -0,0 +1,57 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStoreSilences(t *testing.T) {
+	now := time.Now()
+	silence := &obj.Silence{
+		CheckId:  "00001",
+		Reason:   "deploy",
+		StartsAt: now.Add(-time.Minute),
+		EndsAt:   now.Add(time.Hour),
+	}
+
+	if err := Common.DBStore.PutSilence(Common.User, silence); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	active, err := Common.DBStore.GetActiveSilences(Common.User.CustomerId, now)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+	if len(active) == 0 {
+		log.Error("TestStoreSilences: expected an active silence.")
+		t.FailNow()
+	}
+
+	silence.EndsAt = now.Add(-time.Second)
+	if err := Common.DBStore.UpdateSilence(Common.User, silence); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	active, err = Common.DBStore.GetActiveSilences(Common.User.CustomerId, now)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+	for _, s := range active {
+		if s.Id == silence.Id {
+			log.Error("TestStoreSilences: expired silence is still active.")
+			t.FailNow()
+		}
+	}
+
+	if err := Common.DBStore.DeleteSilence(Common.User, silence.Id); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/opsee/hugs/obj"
+)
+
+// GetSlackThread returns the thread a check's open failure was posted as in a
+// channel, or nil if there isn't one.
+func (pg *Postgres) GetSlackThread(customerId, checkId, channel string) (*obj.SlackThread, error) {
+	thread := &obj.SlackThread{}
+	err := pg.db.Get(thread, "SELECT * FROM slack_threads WHERE customer_id = $1 AND check_id = $2 AND channel = $3", customerId, checkId, channel)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return thread, nil
+}
+
+// PutSlackThread creates or replaces the thread for a check in a channel. Its
+// CreatedAt is always the time it is put.
+func (pg *Postgres) PutSlackThread(thread *obj.SlackThread) error {
+	if err := thread.Validate(); err != nil {
+		return err
+	}
+
+	_, err := pg.db.NamedExec(`INSERT INTO slack_threads (customer_id, check_id, channel, channel_id, ts)
+		VALUES (:customer_id, :check_id, :channel, :channel_id, :ts)
+		ON CONFLICT (customer_id, check_id, channel) DO UPDATE SET channel_id = EXCLUDED.channel_id,
+		ts = EXCLUDED.ts, created_at = now()`, thread)
+	return err
+}
+
+func (pg *Postgres) DeleteSlackThread(customerId, checkId, channel string) error {
+	_, err := pg.db.Exec(`DELETE FROM slack_threads WHERE customer_id = $1 AND check_id = $2 AND channel = $3`, customerId, checkId, channel)
+	return err
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStoreSlackThread(t *testing.T) {
+	thread := &obj.SlackThread{
+		CustomerId: Common.User.CustomerId,
+		CheckId:    "00001",
+		Channel:    "#alerts",
+		ChannelId:  "C00000001",
+		Ts:         "1466000000.000001",
+	}
+
+	if err := Common.DBStore.PutSlackThread(thread); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	// a new failure replaces the old thread
+	thread.Ts = "1466000000.000002"
+	if err := Common.DBStore.PutSlackThread(thread); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	stored, err := Common.DBStore.GetSlackThread(thread.CustomerId, thread.CheckId, thread.Channel)
+	if err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+	if stored == nil || stored.Ts != thread.Ts || stored.CreatedAt.IsZero() {
+		log.Error("TestStoreSlackThread: expected stored thread, got ", stored)
+		t.FailNow()
+	}
+
+	if err := Common.DBStore.DeleteSlackThread(thread.CustomerId, thread.CheckId, thread.Channel); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	stored, err = Common.DBStore.GetSlackThread(thread.CustomerId, thread.CheckId, thread.Channel)
+	if err != nil || stored != nil {
+		log.Error("TestStoreSlackThread: expected deleted thread, got ", stored, err)
+		t.FailNow()
+	}
+}
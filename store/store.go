@@ -1,20 +1,105 @@
 package store
 
 import (
+	"errors"
+	"time"
+
 	"github.com/opsee/basic/schema"
 	"github.com/opsee/hugs/obj"
 )
 
+// Store is everything hugs persists. Postgres is the production
+// implementation, Memory is meant for tests and local development.
 type Store interface {
-	GetNotifications(*schema.User) ([]*obj.Notification, error)
+	// notifications
+	GetNotifications(*schema.User, []*obj.Notification) ([]*obj.Notification, error)
+	GetNotification(*schema.User, int) (*obj.Notification, error)
+	GetNotificationsByUser(*schema.User) ([]*obj.Notification, error)
 	GetNotificationsByCheckId(*schema.User, string) ([]*obj.Notification, error)
 	UnsafeGetNotificationsByCheckId(string) ([]*obj.Notification, error)
-	PutNotification(*schema.User, *obj.Notification) error
 	PutNotifications(*schema.User, []*obj.Notification) error
-	UpdateNotification(*schema.User, *obj.Notification) error
+	PutNotificationsMultiCheck([]*obj.Notifications) error
 	DeleteNotification(*schema.User, *obj.Notification) error
-	DeleteNotifications(*schema.User, []*obj.Notification) error
+	DeleteNotifications([]*obj.Notification) error
+	DeleteNotificationsByUser(*schema.User) error
+	DeleteNotificationsByCheckId(*schema.User, string) error
+	GetDefaultNotifications(*schema.User) ([]*obj.Notification, error)
+	PutDefaultNotifications(*schema.User, []*obj.Notification) error
+
+	// thresholds
+	GetThreshold(*schema.User, string) (*obj.Threshold, error)
+	UnsafeGetThreshold(string, string) (*obj.Threshold, error)
+	PutThreshold(*schema.User, *obj.Threshold) error
+	DeleteThreshold(*schema.User, string) error
+
+	// slack
 	GetSlackOAuthResponse(*schema.User) (*obj.SlackOAuthResponse, error)
-	UpdateSlackOAuthResponse(*schema.User) error
+	GetSlackOAuthResponses(*schema.User) ([]*obj.SlackOAuthResponse, error)
 	PutSlackOAuthResponse(*schema.User, *obj.SlackOAuthResponse) error
+	UpdateSlackOAuthResponse(*schema.User, *obj.SlackOAuthResponse) error
+	DeleteSlackOAuthResponsesByUser(*schema.User) error
+	GetSlackThread(customerId, checkId, channel string) (*obj.SlackThread, error)
+	PutSlackThread(*obj.SlackThread) error
+	DeleteSlackThread(customerId, checkId, channel string) error
+
+	// pagerduty
+	GetPagerDutyOAuthResponse(*schema.User) (*obj.PagerDutyOAuthResponse, error)
+	GetPagerDutyOAuthResponses(*schema.User) ([]*obj.PagerDutyOAuthResponse, error)
+	PutPagerDutyOAuthResponse(*schema.User, *obj.PagerDutyOAuthResponse) error
+	UpdatePagerDutyOAuthResponse(*schema.User, *obj.PagerDutyOAuthResponse) error
+	DeletePagerDutyOAuthResponsesByUser(*schema.User) error
+
+	// opsgenie
+	GetOpsgenieIntegration(*schema.User) (*obj.OpsgenieIntegration, error)
+	PutOpsgenieIntegration(*schema.User, *obj.OpsgenieIntegration) error
+	DeleteOpsgenieIntegrationsByUser(*schema.User) error
+
+	// webhook signing secrets
+	GetWebhookSecret(*schema.User) (*obj.WebhookSecret, error)
+	PutWebhookSecret(*obj.WebhookSecret) error
+
+	// deliveries
+	PutDelivery(*obj.Delivery) error
+	GetDeliveriesByCheckId(*schema.User, string, int) ([]*obj.Delivery, error)
+
+	// dead letters
+	PutDeadLetter(*obj.DeadLetter) error
+	GetDeadLetters(*schema.User, int) ([]*obj.DeadLetter, error)
+	GetDeadLetter(*schema.User, int) (*obj.DeadLetter, error)
+	UnsafeGetDeadLetters(int) ([]*obj.DeadLetter, error)
+	UnsafeGetDeadLetter(int) (*obj.DeadLetter, error)
+	MarkDeadLetterReplayed(*obj.DeadLetter) error
+
+	// check states
+	GetCheckState(string, string, string) (*obj.CheckState, error)
+	PutCheckState(previous *obj.CheckState, state *obj.CheckState) error
+
+	// silences
+	GetSilences(*schema.User) ([]*obj.Silence, error)
+	GetSilence(*schema.User, int) (*obj.Silence, error)
+	GetActiveSilences(string, time.Time) ([]*obj.Silence, error)
+	PutSilence(*schema.User, *obj.Silence) error
+	UpdateSilence(*schema.User, *obj.Silence) error
+	DeleteSilence(*schema.User, int) error
+
+	// escalation policies
+	GetEscalationPolicy(*schema.User, string) (*obj.EscalationPolicy, error)
+	UnsafeGetEscalationPolicy(string, string) (*obj.EscalationPolicy, error)
+	PutEscalationPolicy(*schema.User, *obj.EscalationPolicy) error
+	DeleteEscalationPolicy(*schema.User, string) error
+
+	// incidents
+	GetOpenIncident(string, string) (*obj.Incident, error)
+	OpenIncident(string, string) (*obj.Incident, error)
+	AcknowledgeIncident(*schema.User, string) (*obj.Incident, error)
+	ResolveIncident(string, string) error
 }
+
+// ErrCheckStateChanged is returned by PutCheckState when the recorded state is
+// no longer the one the new state was derived from.
+var ErrCheckStateChanged = errors.New("check state changed concurrently")
+
+var (
+	_ Store = &Postgres{}
+	_ Store = &Memory{}
+)
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+// GetThreshold returns the threshold stored for a check, or the customer's
+// default threshold when checkId is empty. It returns nil if there is none.
+func (pg *Postgres) GetThreshold(user *schema.User, checkId string) (*obj.Threshold, error) {
+	threshold := &obj.Threshold{}
+	err := pg.db.Get(threshold, "SELECT * FROM notification_thresholds WHERE customer_id = $1 AND check_id = $2", user.CustomerId, checkId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return threshold, nil
+}
+
+// UnsafeGetThreshold returns the threshold that applies to a check: its own if
+// it has one, otherwise the customer's default. It returns nil if neither
+// exists.
+func (pg *Postgres) UnsafeGetThreshold(customerId, checkId string) (*obj.Threshold, error) {
+	thresholds := []*obj.Threshold{}
+	err := pg.db.Select(&thresholds, "SELECT * FROM notification_thresholds WHERE customer_id = $1 AND check_id IN ($2, '') ORDER BY check_id DESC LIMIT 1", customerId, checkId)
+	if err != nil {
+		return nil, err
+	}
+	if len(thresholds) == 0 {
+		return nil, nil
+	}
+	return thresholds[0], nil
+}
+
+// PutThreshold creates or replaces the threshold for a check, or the
+// customer's default threshold when its CheckId is empty.
+func (pg *Postgres) PutThreshold(user *schema.User, threshold *obj.Threshold) error {
+	threshold.CustomerId = user.CustomerId
+	if err := threshold.Validate(); err != nil {
+		return err
+	}
+
+	_, err := pg.db.NamedExec(
+		`INSERT INTO notification_thresholds (customer_id, check_id, consecutive, duration)
+		VALUES (:customer_id, :check_id, :consecutive, :duration)
+		ON CONFLICT (customer_id, check_id) DO UPDATE SET consecutive = EXCLUDED.consecutive,
+		duration = EXCLUDED.duration`, threshold)
+	return err
+}
+
+func (pg *Postgres) DeleteThreshold(user *schema.User, checkId string) error {
+	_, err := pg.db.Exec("DELETE FROM notification_thresholds WHERE customer_id = $1 AND check_id = $2", user.CustomerId, checkId)
+	return err
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/opsee/hugs/obj"
+	log "github.com/opsee/logrus"
+)
+
+func TestStoreThresholds(t *testing.T) {
+	if err := Common.DBStore.PutThreshold(Common.User, &obj.Threshold{Consecutive: 2}); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	// checks without a threshold of their own use the default
+	threshold, err := Common.DBStore.UnsafeGetThreshold(Common.User.CustomerId, "00001")
+	if err != nil || threshold == nil || threshold.Consecutive != 2 {
+		log.Error("TestStoreThresholds: expected the default threshold.", err)
+		t.FailNow()
+	}
+
+	if err := Common.DBStore.PutThreshold(Common.User, &obj.Threshold{CheckId: "00001", Consecutive: 5}); err != nil {
+		log.Error(err)
+		t.FailNow()
+	}
+
+	threshold, err = Common.DBStore.UnsafeGetThreshold(Common.User.CustomerId, "00001")
+	if err != nil || threshold == nil || threshold.Consecutive != 5 {
+		log.Error("TestStoreThresholds: expected the check's threshold.", err)
+		t.FailNow()
+	}
+
+	for _, checkId := range []string{"00001", ""} {
+		if err := Common.DBStore.DeleteThreshold(Common.User, checkId); err != nil {
+			log.Error(err)
+			t.FailNow()
+		}
+	}
+
+	threshold, err = Common.DBStore.UnsafeGetThreshold(Common.User.CustomerId, "00001")
+	if err != nil || threshold != nil {
+		log.Error("TestStoreThresholds: thresholds weren't deleted.")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/opsee/basic/schema"
+	"github.com/opsee/hugs/obj"
+)
+
+// GetWebhookSecret returns the secret the customer's webhooks are signed
+// with, or nil if they haven't generated one.
+func (pg *Postgres) GetWebhookSecret(user *schema.User) (*obj.WebhookSecret, error) {
+	secret := &obj.WebhookSecret{}
+	err := pg.db.Get(secret, "SELECT customer_id, secret, created_at FROM webhook_secrets WHERE customer_id = $1", user.CustomerId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// PutWebhookSecret creates or rotates the customer's webhook signing secret.
+func (pg *Postgres) PutWebhookSecret(secret *obj.WebhookSecret) error {
+	if err := secret.Validate(); err != nil {
+		return err
+	}
+
+	_, err := pg.db.NamedExec(
+		`INSERT INTO webhook_secrets (customer_id, secret, created_at) VALUES (:customer_id, :secret, :created_at)
+		ON CONFLICT (customer_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at`, secret)
+	return err
+}
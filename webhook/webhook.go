@@ -0,0 +1,123 @@
+// Package webhook signs webhook deliveries and lets receivers verify them.
+//
+// Every webhook hugs posts for a customer with a signing secret carries two
+// headers: X-Hugs-Timestamp, the unix time the delivery was signed, and
+// X-Hugs-Signature, "sha256=" followed by the hex HMAC-SHA256 of the
+// timestamp, a period and the request body, keyed by the secret. Receivers
+// should reject deliveries whose signature doesn't match or whose timestamp
+// is too old, so a captured delivery can't be replayed later.
+//
+// Rotating the secret takes effect with the next delivery, so a receiver
+// should switch to the new secret as soon as it is generated.
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		body, err := webhook.VerifyRequest(r, secret, webhook.DefaultTolerance)
+//		if err != nil {
+//			http.Error(w, err.Error(), http.StatusUnauthorized)
+//			return
+//		}
+//		...
+//	}
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Hugs-Signature"
+	TimestampHeader = "X-Hugs-Timestamp"
+
+	// DefaultTolerance is how far a delivery's timestamp may be from the
+	// receiver's clock.
+	DefaultTolerance = 5 * time.Minute
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature or timestamp header")
+	ErrInvalidSignature = errors.New("webhook: signature doesn't match")
+	ErrInvalidTimestamp = errors.New("webhook: invalid timestamp")
+	ErrExpired          = errors.New("webhook: timestamp outside of tolerance")
+)
+
+// Sign returns the signature header value for a body signed at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+// SignRequest sets the timestamp and signature headers on a request for body.
+func SignRequest(r *http.Request, secret string, timestamp time.Time, body []byte) {
+	r.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	r.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+}
+
+// Verify checks the signature headers of a delivery against its body, and
+// that it was signed within tolerance of now.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	return verify(secret, header, body, tolerance, time.Now())
+}
+
+// VerifyRequest reads and verifies a delivery, returning its body. The
+// request body is replaced so handlers can read it again.
+func VerifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(secret, r.Header, body, tolerance); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	signature := header.Get(SignatureHeader)
+	ts := header.Get(TimestampHeader)
+	if signature == "" || ts == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil || !hmac.Equal(sum, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+
+	// only trust the timestamp once we know it was signed
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.", timestamp)
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signedHeader(secret string, timestamp time.Time, body []byte) http.Header {
+	r, _ := http.NewRequest("POST", "http://example.com/hook", nil)
+	SignRequest(r, secret, timestamp, body)
+	return r.Header
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"check_id":"00000","passing":false}`)
+	header := signedHeader("secret", now, body)
+
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), header.Get(TimestampHeader))
+	assert.Nil(t, verify("secret", header, body, DefaultTolerance, now))
+
+	assert.Equal(t, ErrInvalidSignature, verify("other-secret", header, body, DefaultTolerance, now))
+	assert.Equal(t, ErrInvalidSignature, verify("secret", header, []byte(`{"check_id":"00000","passing":true}`), DefaultTolerance, now))
+	assert.Equal(t, ErrMissingSignature, verify("secret", http.Header{}, body, DefaultTolerance, now))
+
+	// a replayed delivery is rejected once it's outside the tolerance
+	assert.Equal(t, ErrExpired, verify("secret", header, body, DefaultTolerance, now.Add(DefaultTolerance+time.Minute)))
+
+	// the timestamp can't be changed without breaking the signature
+	header.Set(TimestampHeader, strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+	assert.Equal(t, ErrInvalidSignature, verify("secret", header, body, DefaultTolerance, now.Add(time.Hour)))
+
+	header.Set(TimestampHeader, "yesterday")
+	assert.Equal(t, ErrInvalidTimestamp, verify("secret", header, body, DefaultTolerance, now))
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := []byte(`{"check_id":"00000"}`)
+	r, err := http.NewRequest("POST", "http://example.com/hook", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SignRequest(r, "secret", time.Now(), body)
+
+	verified, err := VerifyRequest(r, "secret", DefaultTolerance)
+	assert.Nil(t, err)
+	assert.Equal(t, body, verified)
+
+	// the body can still be read by the handler
+	again, err := ioutil.ReadAll(r.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, body, again)
+}